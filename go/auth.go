@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/valkey-io/valkey-glide/go/api"
+)
+
+// AuthToken is a short-lived credential supplied by a TokenProvider
+type AuthToken struct {
+	Username string
+	Password string
+	Expires  time.Time
+}
+
+// TokenProvider supplies short-lived authentication tokens. Token is called
+// once before connecting, again before each token expires and on SIGHUP.
+// Tokens without an expiry are only refreshed on SIGHUP.
+type TokenProvider interface {
+	Token() (AuthToken, error)
+}
+
+// tokenProviders maps the values of --auth-provider to their constructors
+var tokenProviders = map[string]func(config *Config) (TokenProvider, error){
+	"iam":     newIAMTokenProvider,
+	"command": newCommandTokenProvider,
+	"file":    newFileTokenProvider,
+	"env":     newEnvTokenProvider,
+}
+
+// iamTokenLifetime is the validity of ElastiCache and MemoryDB IAM tokens
+const iamTokenLifetime = 15 * time.Minute
+
+// iamTokenProvider generates IAM authentication tokens for ElastiCache and
+// MemoryDB, which are Signature Version 4 presigned connect requests
+type iamTokenProvider struct {
+	user      string
+	cacheName string
+	region    string
+	service   string
+	creds     awsCredentials
+}
+
+func newIAMTokenProvider(config *Config) (TokenProvider, error) {
+	if config.AuthUser == "" || config.IAMCacheName == "" {
+		return nil, fmt.Errorf("the iam auth provider requires --auth-user and --iam-cache-name")
+	}
+	if config.IAMRegion == "" {
+		return nil, fmt.Errorf("the iam auth provider requires --iam-region or AWS_REGION")
+	}
+	creds, err := awsCredentialsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	service := "elasticache"
+	if managedService(config.Host) == "MemoryDB" {
+		service = "memorydb"
+	}
+	return &iamTokenProvider{
+		user:      config.AuthUser,
+		cacheName: strings.ToLower(config.IAMCacheName),
+		region:    config.IAMRegion,
+		service:   service,
+		creds:     creds,
+	}, nil
+}
+
+func (p *iamTokenProvider) Token() (AuthToken, error) {
+	now := time.Now()
+	params := url.Values{"Action": {"connect"}, "User": {p.user}}
+	signed := presignAWSURL(p.cacheName, params, p.creds, p.region, p.service, iamTokenLifetime, now)
+	return AuthToken{
+		Username: p.user,
+		Password: strings.TrimPrefix(signed, "https://"),
+		Expires:  now.Add(iamTokenLifetime),
+	}, nil
+}
+
+// commandTokenProvider runs a shell command printing the token on stdout
+type commandTokenProvider struct {
+	user    string
+	command string
+	ttl     time.Duration
+}
+
+func newCommandTokenProvider(config *Config) (TokenProvider, error) {
+	if config.AuthCommand == "" {
+		return nil, fmt.Errorf("the command auth provider requires --auth-command")
+	}
+	if config.AuthTokenTTL <= 0 {
+		return nil, fmt.Errorf("--auth-token-ttl must be positive")
+	}
+	return &commandTokenProvider{
+		user:    config.AuthUser,
+		command: config.AuthCommand,
+		ttl:     time.Duration(config.AuthTokenTTL) * time.Second,
+	}, nil
+}
+
+func (p *commandTokenProvider) Token() (AuthToken, error) {
+	now := time.Now()
+	output, err := exec.Command("sh", "-c", p.command).Output()
+	if err != nil {
+		return AuthToken{}, fmt.Errorf("failed to run auth command: %v", err)
+	}
+	token := strings.TrimSpace(string(output))
+	if token == "" {
+		return AuthToken{}, fmt.Errorf("auth command printed no token")
+	}
+	return AuthToken{Username: p.user, Password: token, Expires: now.Add(p.ttl)}, nil
+}
+
+// parseSecret reads a password, or a user and password separated by
+// whitespace, falling back to the given user
+func parseSecret(secret, user string) (AuthToken, error) {
+	fields := strings.Fields(secret)
+	switch len(fields) {
+	case 1:
+		return AuthToken{Username: user, Password: fields[0]}, nil
+	case 2:
+		return AuthToken{Username: fields[0], Password: fields[1]}, nil
+	}
+	return AuthToken{}, fmt.Errorf("expected \"<password>\" or \"<user> <password>\"")
+}
+
+// fileTokenProvider reads the password from a file, which can be rewritten
+// during the run and reloaded with SIGHUP
+type fileTokenProvider struct {
+	user string
+	path string
+}
+
+func newFileTokenProvider(config *Config) (TokenProvider, error) {
+	if config.AuthFile == "" {
+		return nil, fmt.Errorf("the file auth provider requires --auth-file")
+	}
+	return &fileTokenProvider{user: config.AuthUser, path: config.AuthFile}, nil
+}
+
+func (p *fileTokenProvider) Token() (AuthToken, error) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return AuthToken{}, fmt.Errorf("failed to read auth file: %v", err)
+	}
+	token, err := parseSecret(string(data), p.user)
+	if err != nil {
+		return token, fmt.Errorf("invalid auth file %s: %v", p.path, err)
+	}
+	return token, nil
+}
+
+// envTokenProvider reads the password from an environment variable
+type envTokenProvider struct {
+	user     string
+	variable string
+}
+
+func newEnvTokenProvider(config *Config) (TokenProvider, error) {
+	if config.AuthEnv == "" {
+		return nil, fmt.Errorf("the env auth provider requires --auth-env")
+	}
+	return &envTokenProvider{user: config.AuthUser, variable: config.AuthEnv}, nil
+}
+
+func (p *envTokenProvider) Token() (AuthToken, error) {
+	token, err := parseSecret(os.Getenv(p.variable), p.user)
+	if err != nil {
+		return token, fmt.Errorf("invalid $%s: %v", p.variable, err)
+	}
+	return token, nil
+}
+
+// TokenAuth holds the current token of a provider. New connections
+// authenticate with the current token.
+type TokenAuth struct {
+	provider TokenProvider
+	mu       sync.Mutex
+	token    AuthToken
+}
+
+// tokenAuth is the token authentication of --auth-provider (nil if disabled)
+var tokenAuth *TokenAuth
+
+// startTokenAuth creates the configured provider and fetches the first token
+func startTokenAuth(config *Config) error {
+	newProvider, ok := tokenProviders[config.AuthProvider]
+	if !ok {
+		return fmt.Errorf("unknown auth provider %q, expected iam, command, file or env", config.AuthProvider)
+	}
+	provider, err := newProvider(config)
+	if err != nil {
+		return err
+	}
+	auth := &TokenAuth{provider: provider}
+	if _, err := auth.Refresh(); err != nil {
+		return err
+	}
+	tokenAuth = auth
+	return nil
+}
+
+// staticTokenProvider supplies the fixed password of -a
+type staticTokenProvider struct {
+	token AuthToken
+}
+
+func (p *staticTokenProvider) Token() (AuthToken, error) {
+	return p.token, nil
+}
+
+// startPasswordAuth authenticates new connections with the password of -a
+// and the user of --user
+func startPasswordAuth(config *Config) {
+	token := AuthToken{Username: config.User, Password: config.Password}
+	tokenAuth = &TokenAuth{provider: &staticTokenProvider{token: token}, token: token}
+}
+
+// Current returns the current token
+func (a *TokenAuth) Current() AuthToken {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.token
+}
+
+// Refresh fetches a new token from the provider and makes it current
+func (a *TokenAuth) Refresh() (AuthToken, error) {
+	token, err := a.provider.Token()
+	if err != nil {
+		return token, err
+	}
+	a.mu.Lock()
+	a.token = token
+	a.mu.Unlock()
+	return token, nil
+}
+
+// serverCredentials returns the credentials new connections authenticate with (nil if none)
+func serverCredentials() *api.ServerCredentials {
+	if tokenAuth == nil {
+		return nil
+	}
+	token := tokenAuth.Current()
+	if token.Username == "" {
+		return api.NewServerCredentialsWithDefaultUsername(token.Password)
+	}
+	return api.NewServerCredentials(token.Username, token.Password)
+}
+
+// refreshTimer returns a timer firing at 80% of the remaining lifetime of a
+// token, so connections are re-authenticated well before expiry, or nil if
+// the token does not expire
+func refreshTimer(token AuthToken) *time.Timer {
+	if token.Expires.IsZero() {
+		return nil
+	}
+	delay := time.Until(token.Expires) * 4 / 5
+	if delay < time.Second {
+		delay = time.Second
+	}
+	return time.NewTimer(delay)
+}
+
+// watchTokenRefresh refreshes the token before it expires or on SIGHUP and
+// re-authenticates every open connection with it until the returned function
+// is called
+func (b *Benchmark) watchTokenRefresh() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		timer := refreshTimer(tokenAuth.Current())
+		for {
+			var expiring <-chan time.Time
+			if timer != nil {
+				expiring = timer.C
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-expiring:
+			case <-hangup:
+				slog.Info("reloading credentials on SIGHUP")
+				if timer != nil {
+					timer.Stop()
+				}
+			}
+			token, err := tokenAuth.Refresh()
+			if err != nil {
+				// Retry well before the current token expires
+				slog.Warn("auth token refresh failed", "error", err)
+				timer = time.NewTimer(5 * time.Second)
+				continue
+			}
+			if err := b.reauthenticate(token); err != nil {
+				slog.Warn("re-authentication failed", "error", err)
+			} else if token.Expires.IsZero() {
+				slog.Info("connections re-authenticated", "user", token.Username)
+			} else {
+				slog.Info("connections re-authenticated", "user", token.Username, "expires", token.Expires.Format(time.RFC3339))
+			}
+			timer = refreshTimer(token)
+		}
+	}()
+	return func() {
+		signal.Stop(hangup)
+		cancel()
+	}
+}
+
+// reauthenticate sends AUTH with the token on every connection of the pools
+func (b *Benchmark) reauthenticate(token AuthToken) error {
+	args := []string{"AUTH", token.Password}
+	if token.Username != "" {
+		args = []string{"AUTH", token.Username, token.Password}
+	}
+
+	b.poolMu.RLock()
+	clients := append(append([]interface{}{}, b.clientPool...), b.replicaPool...)
+	b.poolMu.RUnlock()
+	if b.stallClient != nil {
+		clients = append(clients, b.stallClient)
+	}
+
+	var failed int
+	var lastErr error
+	for _, client := range clients {
+		if _, err := executeOnAllNodes(client, args); err != nil {
+			failed++
+			lastErr = err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d clients failed AUTH: %v", failed, len(clients), lastErr)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+)
+
+// CustomCommandSizer can be implemented by custom commands to report the
+// bytes of their last successful request for the network bandwidth report.
+type CustomCommandSizer interface {
+	TransferredBytes() (sent, received int64)
+}
+
+// Approximate RESP reply sizes of the built-in commands
+const (
+	respOKSize  = int64(len("+OK\r\n"))
+	respNilSize = int64(len("$-1\r\n"))
+)
+
+// respCommandSize returns the size of a command encoded as a RESP array of bulk strings
+func respCommandSize(args ...string) int64 {
+	size := respHeaderSize(len(args))
+	for _, arg := range args {
+		size += respBulkSize(len(arg))
+	}
+	return size
+}
+
+// respHeaderSize returns the size of an aggregate header such as "*3\r\n"
+func respHeaderSize(n int) int64 {
+	return int64(1 + len(strconv.Itoa(n)) + 2)
+}
+
+// respBulkSize returns the size of a bulk string with n bytes of payload
+func respBulkSize(n int) int64 {
+	return respHeaderSize(n) + int64(n) + 2
+}
+
+// respReplySize approximates the encoded size of a decoded reply
+func respReplySize(reply interface{}) int64 {
+	switch v := reply.(type) {
+	case nil:
+		return respNilSize
+	case string:
+		return respBulkSize(len(v))
+	case int64:
+		return int64(1 + len(strconv.FormatInt(v, 10)) + 2)
+	case float64:
+		return int64(1 + len(strconv.FormatFloat(v, 'g', -1, 64)) + 2)
+	case bool:
+		return 4
+	case []interface{}:
+		size := respHeaderSize(len(v))
+		for _, item := range v {
+			size += respReplySize(item)
+		}
+		return size
+	case map[string]interface{}:
+		size := respHeaderSize(len(v))
+		for key, item := range v {
+			size += respBulkSize(len(key)) + respReplySize(item)
+		}
+		return size
+	}
+	return int64(len(fmt.Sprint(reply))) + 3
+}
+
+// AddTransfer records the bytes sent and received by a request
+func (s *BenchmarkStats) AddTransfer(sent, received int64) {
+	atomic.AddInt64(&s.bytesSent, sent)
+	atomic.AddInt64(&s.bytesReceived, received)
+	s.requestSizes.Record(sent)
+	s.responseSizes.Record(received)
+}
+
+// printBandwidth outputs the approximate egress and ingress of the run
+func (s *BenchmarkStats) printBandwidth(totalTime float64) {
+	sent := atomic.LoadInt64(&s.bytesSent)
+	received := atomic.LoadInt64(&s.bytesReceived)
+	if sent == 0 && received == 0 {
+		return
+	}
+	const mb = 1024 * 1024
+	fmt.Printf("\nNetwork (approximate RESP payload):\n")
+	fmt.Printf("=====================\n")
+	fmt.Printf("Sent: %.2f MB (%.2f MB/s)\n", float64(sent)/mb, float64(sent)/mb/totalTime)
+	fmt.Printf("Received: %.2f MB (%.2f MB/s)\n", float64(received)/mb, float64(received)/mb/totalTime)
+
+	s.requestSizes.Print("Request Sizes")
+	s.responseSizes.Print("Response Sizes")
+	s.printPayloadLatency()
+}
+
+// AddPayloadLatency records the latency of a successful request under the
+// size bucket of its payload (request plus reply)
+func (s *BenchmarkStats) AddPayloadLatency(size int64, latency float64) {
+	bucket := sizeBucket(size)
+	s.mu.Lock()
+	if s.payloadLatencies == nil {
+		s.payloadLatencies = make([]*LatencyHistogram, sizeHistogramBuckets)
+	}
+	if s.payloadLatencies[bucket] == nil {
+		s.payloadLatencies[bucket] = NewLatencyHistogram()
+	}
+	s.payloadLatencies[bucket].Record(latency)
+	s.mu.Unlock()
+}
+
+// printPayloadLatency prints latency percentiles per payload size bucket and
+// each bucket's share of the requests slower than the overall p99, showing how
+// much of the tail latency is due to large values. It is skipped when all
+// payloads fall into one bucket.
+func (s *BenchmarkStats) printPayloadLatency() {
+	s.mu.Lock()
+	var buckets []int
+	var latencies []*LatencyHistogram
+	for i, bucket := range s.payloadLatencies {
+		if bucket != nil {
+			buckets = append(buckets, i)
+			latencies = append(latencies, bucket.Copy())
+		}
+	}
+	overall := s.latencies.Stats()
+	s.mu.Unlock()
+	if len(buckets) < 2 || overall == nil {
+		return
+	}
+
+	tails := make([]int64, len(buckets))
+	var tail int64
+	for i, bucket := range latencies {
+		tails[i] = bucket.CountAbove(overall.p99)
+		tail += tails[i]
+	}
+
+	fmt.Printf("\nLatency by Payload Size (ms, request + reply):\n")
+	fmt.Printf("=======================\n")
+	fmt.Printf("%-14s %10s %10s %10s %10s %10s %14s\n", "Payload", "Requests", "p50", "p95", "p99", "Max", "Share of tail")
+	for i, bucket := range buckets {
+		stats := latencies[i].Stats()
+		share := 0.0
+		if tail > 0 {
+			share = 100 * float64(tails[i]) / float64(tail)
+		}
+		fmt.Printf("<= %-11s %10d %10.3f %10.3f %10.3f %10.3f %13.1f%%\n", formatSize(int64(1)<<bucket),
+			latencies[i].Count(), stats.p50, stats.p95, stats.p99, stats.max, share)
+	}
+	fmt.Printf("Share of tail: fraction of the requests slower than the overall p99 (%.3f ms)\n", overall.p99)
+}
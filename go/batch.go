@@ -0,0 +1,140 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// statsBatch accumulates the results of one worker and flushes them to the
+// shared stats every --stats-batch requests or --stats-flush milliseconds,
+// so workers don't contend on the shared counters and lock per request
+type statsBatch struct {
+	stats         *BenchmarkStats
+	size          int
+	interval      time.Duration
+	latencies     []float64
+	paths         []string // Label of every latency ("" if unlabelled)
+	commands      []string // Command name of every latency ("" if unknown)
+	errorPaths    []string // Label of every error ("" if unlabelled)
+	errorCommands []string // Command name of every error ("" if unknown)
+	lastFlush     time.Time
+}
+
+// newStatsBatch creates the batch of a worker. With a request count, the
+// batch is kept small enough that requests other workers have not flushed
+// yet overshoot -n by at most 1%.
+func newStatsBatch(stats *BenchmarkStats, config *Config) *statsBatch {
+	size := config.StatsBatch
+	if config.TotalRequests > 0 {
+		if limit := config.TotalRequests / int64(config.NumThreads*100); int64(size) > limit {
+			size = int(limit)
+		}
+	}
+	if size < 1 {
+		size = 1
+	}
+	return &statsBatch{
+		stats:     stats,
+		size:      size,
+		interval:  time.Duration(config.StatsFlush) * time.Millisecond,
+		lastFlush: time.Now(),
+	}
+}
+
+// AddLatency records a successful request with an optional path label and command name
+func (w *statsBatch) AddLatency(path, command string, latency float64) {
+	w.latencies = append(w.latencies, latency)
+	w.paths = append(w.paths, path)
+	w.commands = append(w.commands, command)
+	w.flushIfDue()
+}
+
+// AddError records a failed request with an optional path label and command name
+func (w *statsBatch) AddError(path, command string) {
+	w.errorPaths = append(w.errorPaths, path)
+	w.errorCommands = append(w.errorCommands, command)
+	w.flushIfDue()
+}
+
+// Pending returns the successful requests not flushed yet
+func (w *statsBatch) Pending() int64 {
+	return int64(len(w.latencies))
+}
+
+func (w *statsBatch) flushIfDue() {
+	if len(w.latencies)+len(w.errorPaths) >= w.size || time.Since(w.lastFlush) >= w.interval {
+		w.Flush()
+	}
+}
+
+// Flush adds the accumulated results to the shared stats
+func (w *statsBatch) Flush() {
+	w.lastFlush = time.Now()
+	if len(w.latencies) == 0 && len(w.errorPaths) == 0 {
+		return
+	}
+	w.stats.addBatch(w.latencies, w.paths, w.commands, w.errorPaths, w.errorCommands)
+	w.latencies = w.latencies[:0]
+	w.paths = w.paths[:0]
+	w.commands = w.commands[:0]
+	w.errorPaths = w.errorPaths[:0]
+	w.errorCommands = w.errorCommands[:0]
+}
+
+// addBatch records latencies of successful requests and failed requests,
+// each with the path label and command name at the same index ("" if
+// unlabelled or unknown)
+func (s *BenchmarkStats) addBatch(latencies []float64, paths, commands, errorPaths, errorCommands []string) {
+	for _, latency := range latencies {
+		if s.sloBuckets != nil {
+			s.sloBuckets.Record(latency)
+		}
+		if s.apdex != nil {
+			s.apdex.Record(latency)
+		}
+		if s.dataset != nil {
+			s.dataset.Record(latency)
+		}
+	}
+	if s.apdex != nil {
+		for range errorPaths {
+			s.apdex.RecordError()
+		}
+	}
+
+	s.mu.Lock()
+	var stage *RampStage
+	if n := len(s.rampStages); n > 0 {
+		stage = s.rampStages[n-1]
+		stage.errors += int64(len(errorPaths))
+	}
+	for i, latency := range latencies {
+		s.latencies.Record(latency)
+		s.window.Add(latency)
+		if stage != nil {
+			stage.latencies.Record(latency)
+		}
+		if paths[i] != "" {
+			s.recordPath(paths[i], latency)
+		}
+		if commands[i] != "" {
+			s.recordCommand(commands[i], latency)
+		}
+	}
+	for _, path := range errorPaths {
+		if path != "" {
+			s.registerPath(path)
+			s.pathErrors[path]++
+		}
+	}
+	for _, command := range errorCommands {
+		if command != "" {
+			s.registerCommand(command)
+			s.commandErrors[command]++
+		}
+	}
+	s.mu.Unlock()
+
+	atomic.AddInt64(&s.requestsCompleted, int64(len(latencies)))
+	atomic.AddInt64(&s.errors, int64(len(errorPaths)))
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// busMessage is the JSON document published for every interval and event
+type busMessage struct {
+	Type     string                 `json:"type"` // "interval" or the event type
+	RunID    string                 `json:"run_id"`
+	Agent    string                 `json:"agent"`
+	Time     time.Time              `json:"time"`
+	Interval *IntervalStats         `json:"interval,omitempty"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+}
+
+// busPublisher delivers a payload to a message bus
+type busPublisher interface {
+	Publish(payload []byte) error
+	Close() error
+}
+
+// BusExporter publishes interval statistics and lifecycle events as JSON to
+// NATS or to Kafka through a REST proxy, so a central collector can track a
+// fleet of benchmark agents
+type BusExporter struct {
+	runID      string
+	agent      string
+	publishers []busPublisher
+}
+
+// NewBusExporter connects to the configured message buses
+func NewBusExporter(config *Config) (*BusExporter, error) {
+	agent, _ := os.Hostname()
+	e := &BusExporter{runID: config.RunID, agent: agent}
+	if config.EventsNATS != "" {
+		publisher, err := newNATSPublisher(config.EventsNATS, config.EventsSubject)
+		if err != nil {
+			return nil, err
+		}
+		e.publishers = append(e.publishers, publisher)
+	}
+	if config.EventsKafkaREST != "" {
+		e.publishers = append(e.publishers, newKafkaRESTPublisher(config.EventsKafkaREST, config.EventsSubject, config.RunID))
+	}
+	return e, nil
+}
+
+func (e *BusExporter) publish(message busMessage) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	for _, publisher := range e.publishers {
+		if err := publisher.Publish(payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportInterval publishes an "interval" message
+func (e *BusExporter) ExportInterval(interval IntervalStats) error {
+	return e.publish(busMessage{Type: "interval", RunID: e.runID, Agent: e.agent, Time: interval.Time, Interval: &interval})
+}
+
+// ExportEvent publishes a lifecycle event message
+func (e *BusExporter) ExportEvent(event BenchmarkEvent) error {
+	return e.publish(busMessage{Type: event.Type, RunID: e.runID, Agent: e.agent, Time: event.Time, Data: event.Data})
+}
+
+// Close closes all bus connections
+func (e *BusExporter) Close() error {
+	var firstErr error
+	for _, publisher := range e.publishers {
+		if err := publisher.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// natsPublisher speaks the plain-text NATS client protocol over TCP
+type natsPublisher struct {
+	subject string
+	conn    net.Conn
+	mu      sync.Mutex // Serializes writes to conn
+}
+
+// newNATSPublisher connects to a nats://host:port URL. User and password in
+// the URL are sent with CONNECT.
+func newNATSPublisher(rawURL, subject string) (*natsPublisher, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid NATS URL %q", rawURL)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	conn, err := net.DialTimeout("tcp", host, 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %v", err)
+	}
+
+	// The server greets with INFO before accepting CONNECT
+	reader := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	info, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(info, "INFO ") {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected NATS greeting %q: %v", strings.TrimSpace(info), err)
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	options := map[string]interface{}{"verbose": false, "pedantic": false, "name": "valkey-benchmark", "lang": "go"}
+	if u.User != nil {
+		options["user"] = u.User.Username()
+		options["pass"], _ = u.User.Password()
+	}
+	connect, _ := json.Marshal(options)
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\n", connect); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to connect to NATS: %v", err)
+	}
+
+	p := &natsPublisher{subject: subject, conn: conn}
+	go p.readLoop(reader)
+	return p, nil
+}
+
+// readLoop answers server PINGs and reports protocol errors
+func (p *natsPublisher) readLoop(reader *bufio.Reader) {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch {
+		case strings.HasPrefix(line, "PING"):
+			p.mu.Lock()
+			p.conn.Write([]byte("PONG\r\n"))
+			p.mu.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			slog.Warn("NATS error", "error", strings.TrimSpace(line))
+		}
+	}
+}
+
+func (p *natsPublisher) Publish(payload []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := fmt.Fprintf(p.conn, "PUB %s %d\r\n%s\r\n", p.subject, len(payload), payload); err != nil {
+		return fmt.Errorf("NATS publish failed: %v", err)
+	}
+	return nil
+}
+
+func (p *natsPublisher) Close() error {
+	return p.conn.Close()
+}
+
+// kafkaRESTPublisher produces records through a Kafka REST proxy (v2 API)
+type kafkaRESTPublisher struct {
+	url    string
+	key    string
+	client *http.Client
+}
+
+func newKafkaRESTPublisher(baseURL, topic, key string) *kafkaRESTPublisher {
+	return &kafkaRESTPublisher{
+		url:    strings.TrimRight(baseURL, "/") + "/topics/" + url.PathEscape(topic),
+		key:    key,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (p *kafkaRESTPublisher) Publish(payload []byte) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"records": []map[string]interface{}{{"key": p.key, "value": json.RawMessage(payload)}},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("kafka REST publish failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("kafka REST publish failed: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+func (p *kafkaRESTPublisher) Close() error {
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// spinCheckEvery is the number of spin iterations between context checks
+const spinCheckEvery = 64
+
+// spinContext busy-waits for d and returns false if ctx was done first. It
+// never yields to the scheduler, so the wait ends within nanoseconds of the
+// deadline instead of after a timer wakeup, at the cost of a full core.
+func spinContext(ctx context.Context, d time.Duration) bool {
+	deadline := time.Now().Add(d)
+	for i := 0; time.Now().Before(deadline); i++ {
+		if i%spinCheckEvery == 0 && ctx.Err() != nil {
+			return false
+		}
+	}
+	return ctx.Err() == nil
+}
+
+// wait pauses the pacing path for d, spinning with --busy-poll and sleeping otherwise
+func (qps *QPSController) wait(ctx context.Context, d time.Duration) bool {
+	if qps.config.BusyPoll {
+		return spinContext(ctx, d)
+	}
+	return sleepContext(ctx, d)
+}
+
+// pinWorker locks the calling worker goroutine to its own OS thread with
+// --busy-poll, so it isn't migrated or descheduled between requests. It
+// returns the function undoing the lock.
+func pinWorker(config *Config) func() {
+	if !config.BusyPoll {
+		return func() {}
+	}
+	runtime.LockOSThread()
+	return runtime.UnlockOSThread
+}
+
+// checkBusyPoll warns when spinning workers outnumber the available CPUs,
+// which brings back the scheduler jitter the mode is meant to avoid
+func checkBusyPoll(config *Config) {
+	if config.BusyPoll && config.NumThreads >= runtime.GOMAXPROCS(0) {
+		slog.Warn("busy-poll workers leave no CPU for the client runtime; use fewer threads than CPUs",
+			"threads", config.NumThreads, "cpus", runtime.GOMAXPROCS(0))
+	}
+}
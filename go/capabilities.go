@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// ServerCapabilities describes the server the benchmark runs against
+type ServerCapabilities struct {
+	Server   string          // Server name, "valkey" or "redis"
+	Version  string          // Server version
+	Modules  []string        // Names of the loaded modules
+	commands map[string]bool // Known support of the commands checked so far
+}
+
+// String renders the server name, version and modules
+func (c *ServerCapabilities) String() string {
+	s := fmt.Sprintf("%s %s", c.Server, c.Version)
+	if len(c.Modules) > 0 {
+		s += " (modules: " + strings.Join(c.Modules, ", ") + ")"
+	}
+	return s
+}
+
+// Supports reports whether a command was found on the server
+func (c *ServerCapabilities) Supports(command string) bool {
+	return c.commands[strings.ToUpper(command)]
+}
+
+// detectCapabilities queries the server version, the loaded modules and the
+// support of the given commands
+func detectCapabilities(client interface{}, commands []string) (*ServerCapabilities, error) {
+	info, err := executeCommand(client, []string{"INFO", "server"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query server info: %v", err)
+	}
+	caps := &ServerCapabilities{Server: "redis", commands: make(map[string]bool)}
+	fields := infoFields(firstString(info))
+	if fields["server_name"] != "" {
+		caps.Server = fields["server_name"]
+	}
+	caps.Version = fields["valkey_version"]
+	if caps.Version == "" {
+		caps.Version = fields["redis_version"]
+	}
+
+	// MODULE LIST may be denied by ACLs; treat that as no modules
+	if modules, err := executeCommand(client, []string{"MODULE", "LIST"}); err == nil {
+		caps.Modules = moduleNames(modules)
+	}
+
+	if len(commands) > 0 {
+		reply, err := executeCommand(client, append([]string{"COMMAND", "INFO"}, commands...))
+		if err != nil {
+			return nil, fmt.Errorf("failed to query command info: %v", err)
+		}
+		entries, _ := reply.([]interface{})
+		for i, command := range commands {
+			caps.commands[strings.ToUpper(command)] = i < len(entries) && entries[i] != nil
+		}
+	}
+	return caps, nil
+}
+
+// firstString returns a string reply, or the first node's reply of a multi-node reply
+func firstString(reply interface{}) string {
+	switch v := reply.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		nodes := make([]string, 0, len(v))
+		for node := range v {
+			nodes = append(nodes, node)
+		}
+		sort.Strings(nodes)
+		if len(nodes) > 0 {
+			return firstString(v[nodes[0]])
+		}
+	}
+	return ""
+}
+
+// moduleNames extracts the names from a MODULE LIST reply, which holds one
+// map (RESP3) or flat key/value list (RESP2) per module
+func moduleNames(reply interface{}) []string {
+	var names []string
+	modules, _ := reply.([]interface{})
+	for _, module := range modules {
+		switch m := module.(type) {
+		case map[string]interface{}:
+			if name, ok := m["name"].(string); ok {
+				names = append(names, name)
+			}
+		case []interface{}:
+			for i := 0; i+1 < len(m); i += 2 {
+				if key, _ := m[i].(string); key == "name" {
+					if name, ok := m[i+1].(string); ok {
+						names = append(names, name)
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// workloadCommands returns the names of the commands the configured workload
+// issues. Plugins and workload subprocesses are opaque and not included.
+func (b *Benchmark) workloadCommands() []string {
+	var commands []string
+	switch b.config.Command {
+	case "set":
+		commands = append(commands, "SET")
+	case "get":
+		commands = append(commands, "GET")
+	default:
+		commands = append(commands, b.customCommandNames...)
+	}
+	// Commands issued while preparing module workloads
+	switch b.config.Command {
+	case "bf.add", "bf.exists":
+		commands = append(commands, "BF.RESERVE")
+	case "ft.search":
+		commands = append(commands, "FT.CREATE")
+		if b.config.SearchDocuments > 0 {
+			commands = append(commands, "HSET")
+		}
+	case "lrange", "zrange":
+		if b.config.PageFill && b.config.Command == "lrange" {
+			commands = append(commands, "DEL", "RPUSH")
+		} else if b.config.PageFill {
+			commands = append(commands, "DEL", "ZADD")
+		}
+	case "get":
+		if b.config.ScanSample > 0 {
+			commands = append(commands, "SCAN")
+		}
+	case "ft.knn":
+		commands = append(commands, "FT.CREATE")
+		if b.config.VectorDocuments > 0 {
+			commands = append(commands, "HSET")
+		}
+	}
+	for _, stage := range b.config.WorkflowStages {
+		switch stage {
+		case stagePrefill:
+			commands = append(commands, "SET")
+		case stageVerify:
+			commands = append(commands, "GET")
+		case stageCleanup:
+			commands = append(commands, "DEL")
+		}
+	}
+
+	seen := make(map[string]bool)
+	unique := commands[:0]
+	for _, command := range commands {
+		if !seen[command] {
+			seen[command] = true
+			unique = append(unique, command)
+		}
+	}
+	return unique
+}
+
+// checkCapabilities detects the server capabilities and fails if a command of
+// the workload is not available on the server
+func (b *Benchmark) checkCapabilities() error {
+	commands := b.workloadCommands()
+	caps, err := detectCapabilities(b.poolClient(false, 0), commands)
+	if err != nil {
+		return err
+	}
+	b.capabilities = caps
+	fmt.Printf("Server: %s\n", caps)
+
+	// A command mix is a suite: unsupported entries are skipped as long as
+	// something is left to run
+	if b.mix != nil {
+		mix, removed := b.mix.Filter(func(entry *CommandMixEntry) bool {
+			return caps.Supports(entry.Template.name)
+		})
+		if len(mix.Entries) > 0 {
+			for _, entry := range removed {
+				b.skippedCommands = append(b.skippedCommands, entry.Label)
+			}
+			if len(removed) > 0 {
+				slog.Warn("skipping unsupported commands", "commands", strings.Join(b.skippedCommands, ", "))
+			}
+			b.mix = mix
+			b.newCustomCommand = newMixCommandFactory(mix)
+			b.customCommandNames = nil
+			for _, entry := range mix.Entries {
+				b.customCommandNames = append(b.customCommandNames, entry.Template.name)
+			}
+			commands = b.workloadCommands()
+		}
+	}
+
+	var missing []string
+	for _, command := range commands {
+		if !caps.Supports(command) {
+			missing = append(missing, command)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("server %s:%d (%s %s) does not support %s; load the required module or use --skip-capability-check",
+			b.config.Host, b.config.Port, caps.Server, caps.Version, strings.Join(missing, ", "))
+	}
+	return nil
+}
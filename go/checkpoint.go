@@ -0,0 +1,268 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// checkpointVersion is bumped whenever checkpointState changes incompatibly
+const checkpointVersion = 3
+
+// checkpointState is the cumulative state of a run persisted for --resume
+type checkpointState struct {
+	Version          int
+	RunID            string
+	Command          string
+	Saved            time.Time
+	Elapsed          float64 // Seconds of benchmark time before the checkpoint
+	Requests         int64
+	Errors           int64
+	BytesSent        int64
+	BytesReceived    int64
+	Hits             int64
+	Misses           int64
+	Latencies        HistogramState
+	PathOrder        []string
+	PathLatencies    map[string]HistogramState
+	PathErrors       map[string]int64
+	CommandOrder     []string
+	CommandLatencies map[string]HistogramState
+	CommandErrors    map[string]int64
+	Timeline         []IntervalStats
+	TemplateCounter  int64 // Position of {{gcounter}}
+	Deterministic    bool  // Saved by a --deterministic run
+	Seed             int64 // Seed of the --deterministic request sequence
+	WorkloadNext     int64 // Sequence number of the next --deterministic request
+}
+
+// checkpoint captures the cumulative state of the stats. Histograms and slices
+// are copied under the lock so encoding doesn't block the workers.
+func (s *BenchmarkStats) checkpoint() *checkpointState {
+	elapsed := s.elapsed()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := &checkpointState{
+		Version:          checkpointVersion,
+		RunID:            s.config.RunID,
+		Command:          s.config.Command,
+		Saved:            time.Now(),
+		Elapsed:          elapsed,
+		Requests:         atomic.LoadInt64(&s.requestsCompleted),
+		Errors:           atomic.LoadInt64(&s.errors),
+		BytesSent:        atomic.LoadInt64(&s.bytesSent),
+		BytesReceived:    atomic.LoadInt64(&s.bytesReceived),
+		Hits:             atomic.LoadInt64(&s.hits),
+		Misses:           atomic.LoadInt64(&s.misses),
+		Latencies:        s.latencies.State(),
+		PathOrder:        append([]string(nil), s.pathOrder...),
+		PathLatencies:    make(map[string]HistogramState, len(s.pathLatencies)),
+		PathErrors:       make(map[string]int64, len(s.pathErrors)),
+		CommandOrder:     append([]string(nil), s.commandOrder...),
+		CommandLatencies: make(map[string]HistogramState, len(s.commandLatencies)),
+		CommandErrors:    make(map[string]int64, len(s.commandErrors)),
+		Timeline:         append([]IntervalStats(nil), s.timeline...),
+		TemplateCounter:  atomic.LoadInt64(&globalTemplateCounter),
+	}
+	if s.workload != nil {
+		state.Deterministic = true
+		state.Seed = s.config.Seed
+		state.WorkloadNext = atomic.LoadInt64(&s.workload.next)
+	}
+	for path, latencies := range s.pathLatencies {
+		state.PathLatencies[path] = latencies.State()
+	}
+	for path, errors := range s.pathErrors {
+		state.PathErrors[path] = errors
+	}
+	for command, latencies := range s.commandLatencies {
+		state.CommandLatencies[command] = latencies.State()
+	}
+	for command, errors := range s.commandErrors {
+		state.CommandErrors[command] = errors
+	}
+	return state
+}
+
+// restore continues the stats from a checkpoint. The time between the
+// checkpoint and the resume is not counted as benchmark time.
+func (s *BenchmarkStats) restore(state *checkpointState) {
+	latencies := histogramFromState(state.Latencies)
+	s.replayLatencies(latencies, state.Errors)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.startTime = now.Add(-time.Duration(state.Elapsed * float64(time.Second)))
+	s.lastPrint = now
+	s.requestsCompleted = state.Requests
+	s.lastRequests = state.Requests
+	s.errors = state.Errors
+	s.lastErrors = state.Errors
+	s.bytesSent = state.BytesSent
+	s.bytesReceived = state.BytesReceived
+	s.hits = state.Hits
+	s.misses = state.Misses
+	s.latencies.Merge(latencies)
+	s.pathOrder = state.PathOrder
+	for path, latencies := range state.PathLatencies {
+		s.pathLatencies[path] = histogramFromState(latencies)
+	}
+	for path, errors := range state.PathErrors {
+		s.pathErrors[path] = errors
+	}
+	s.commandOrder = state.CommandOrder
+	for command, latencies := range state.CommandLatencies {
+		s.commandLatencies[command] = histogramFromState(latencies)
+	}
+	for command, errors := range state.CommandErrors {
+		s.commandErrors[command] = errors
+	}
+	s.timeline = state.Timeline
+	atomic.StoreInt64(&globalTemplateCounter, state.TemplateCounter)
+	if s.workload != nil {
+		atomic.StoreInt64(&s.workload.next, state.WorkloadNext)
+	}
+}
+
+// replayLatencies adds restored or merged latencies and errors to the SLO
+// buckets, the Apdex counters and the latency dump. Checkpoints and child
+// processes carry histograms rather than every sample, so the buckets and
+// counters take each histogram counter at once, at its midpoint. Only the
+// latency dump of a resumed run needs one sample per request.
+func (s *BenchmarkStats) replayLatencies(latencies *LatencyHistogram, errors int64) {
+	if s.sloBuckets == nil && s.apdex == nil && s.dataset == nil {
+		return
+	}
+	latencies.ForEach(func(latency float64, count int64) {
+		if s.sloBuckets != nil {
+			s.sloBuckets.RecordN(latency, count)
+		}
+		if s.apdex != nil {
+			s.apdex.RecordN(latency, count)
+		}
+		if s.dataset != nil {
+			for i := int64(0); i < count; i++ {
+				s.dataset.Record(latency)
+			}
+		}
+	})
+	if s.apdex != nil {
+		s.apdex.RecordErrors(errors)
+	}
+}
+
+// writeCheckpoint atomically replaces the checkpoint file
+func writeCheckpoint(path string, state *checkpointState) error {
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+	gz := gzip.NewWriter(file)
+	err = gob.NewEncoder(gz).Encode(state)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+	return nil
+}
+
+// loadCheckpoint reads a checkpoint written by writeCheckpoint
+func loadCheckpoint(path string) (*checkpointState, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %v", err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %v", path, err)
+	}
+	var state checkpointState
+	if err := gob.NewDecoder(gz).Decode(&state); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %v", path, err)
+	}
+	if state.Version != checkpointVersion {
+		return nil, fmt.Errorf("checkpoint %s has version %d, expected %d", path, state.Version, checkpointVersion)
+	}
+	return &state, nil
+}
+
+// resumeCheckpoint loads the checkpoint of an interrupted run, if there is
+// one, and adapts the configuration to continue it: the run ID is restored
+// and a test duration is reduced by the time already covered.
+func resumeCheckpoint(config *Config) (*checkpointState, error) {
+	if _, err := os.Stat(config.CheckpointFile); os.IsNotExist(err) {
+		fmt.Printf("No checkpoint found at %s, starting a new run\n", config.CheckpointFile)
+		return nil, nil
+	}
+	state, err := loadCheckpoint(config.CheckpointFile)
+	if err != nil {
+		return nil, err
+	}
+	if state.Command != config.Command {
+		return nil, fmt.Errorf("checkpoint %s was saved by a %q run, not %q", config.CheckpointFile, state.Command, config.Command)
+	}
+	// A --deterministic run continues its request sequence, which needs the seed
+	if state.Deterministic != config.Deterministic {
+		return nil, fmt.Errorf("checkpoint %s and this run must both use --deterministic or neither", config.CheckpointFile)
+	}
+	if state.Deterministic {
+		config.Seed = state.Seed
+		seedRandom(state.Seed)
+	}
+	if config.TestDuration > 0 {
+		remaining := config.TestDuration - int(state.Elapsed)
+		if remaining <= 0 {
+			return nil, fmt.Errorf("checkpoint %s already covers the test duration", config.CheckpointFile)
+		}
+		config.TestDuration = remaining
+	}
+	config.RunID = state.RunID
+	return state, nil
+}
+
+// startCheckpointing saves the stats periodically until the returned function
+// is called, which also writes a last checkpoint
+func startCheckpointing(stats *BenchmarkStats, path string, interval time.Duration) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := writeCheckpoint(path, stats.checkpoint()); err != nil {
+					slog.Warn("checkpoint failed", "error", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+		if err := writeCheckpoint(path, stats.checkpoint()); err != nil {
+			slog.Warn("checkpoint failed", "error", err)
+		}
+	}
+}
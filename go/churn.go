@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// churnCommand writes a new key per request and deletes the key written
+// --churn-keys requests earlier, so the dataset holds a constant number of
+// keys like a session store. Until the first --churn-keys keys exist the
+// requests only insert and are reported as the fill phase.
+type churnCommand struct {
+	next   *int64 // Shared index of the next key
+	live   int64
+	prefix string
+	data   string
+	index  int64
+	set    []string
+	del    []string
+	reply  interface{}
+}
+
+// newChurnFactory returns a factory for commands of the -t churn workload
+func newChurnFactory(config *Config) CustomCommandFactory {
+	next := new(int64)
+	prefix := keyPrefix(config) + ":churn"
+	data := generateRandomData(config.DataSize)
+	return func() CustomCommand {
+		return &churnCommand{next: next, live: config.ChurnKeys, prefix: prefix, data: data}
+	}
+}
+
+func (c *churnCommand) Setup(client interface{}, workerID int, args string) error {
+	return nil
+}
+
+// Prepare claims the next key and the oldest key to delete
+func (c *churnCommand) Prepare() error {
+	c.index = atomic.AddInt64(c.next, 1) - 1
+	c.set = []string{"SET", fmt.Sprintf("%s:%d", c.prefix, c.index), c.data}
+	c.del = nil
+	if c.index >= c.live {
+		c.del = []string{"DEL", fmt.Sprintf("%s:%d", c.prefix, c.index-c.live)}
+	}
+	return nil
+}
+
+// Label separates the fill phase from the steady state of inserts and deletes
+func (c *churnCommand) Label() string {
+	if c.del == nil {
+		return "fill"
+	}
+	return "churn"
+}
+
+// Key returns the inserted key
+func (c *churnCommand) Key() string {
+	return c.set[1]
+}
+
+func (c *churnCommand) Execute(client interface{}) error {
+	if _, err := executeCommand(client, c.set); err != nil {
+		return err
+	}
+	if c.del == nil {
+		return nil
+	}
+	var err error
+	c.reply, err = executeCommand(client, c.del)
+	return err
+}
+
+// TransferredBytes approximates the encoded size of the last insert and delete and their replies
+func (c *churnCommand) TransferredBytes() (sent, received int64) {
+	sent, received = respCommandSize(c.set...), respOKSize
+	if c.del != nil {
+		sent += respCommandSize(c.del...)
+		received += respReplySize(c.reply)
+	}
+	return sent, received
+}
+
+func (c *churnCommand) Teardown(client interface{}) error {
+	return nil
+}
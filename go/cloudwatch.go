@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CloudWatchExporter publishes interval metrics with the CloudWatch
+// PutMetricData API, signed with credentials from the environment
+type CloudWatchExporter struct {
+	namespace  string
+	region     string
+	endpoint   string
+	dimensions [][2]string
+	creds      awsCredentials
+	client     *http.Client
+}
+
+// NewCloudWatchExporter creates an exporter for the configured namespace.
+// The run ID, command and target are always added as dimensions.
+func NewCloudWatchExporter(config *Config) (*CloudWatchExporter, error) {
+	creds, err := awsCredentialsFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("cloudwatch: %v", err)
+	}
+	region := config.CloudWatchRegion
+	if region == "" {
+		region = awsRegionFromEnv()
+	}
+	if region == "" {
+		return nil, fmt.Errorf("cloudwatch: region not set, use --cloudwatch-region or AWS_REGION")
+	}
+
+	dimensions := [][2]string{
+		{"RunId", config.RunID},
+		{"Command", config.Command},
+		{"Target", fmt.Sprintf("%s:%d", config.Host, config.Port)},
+	}
+	if config.CloudWatchDimensions != "" {
+		for _, pair := range strings.Split(config.CloudWatchDimensions, ",") {
+			name, value, ok := strings.Cut(pair, "=")
+			if !ok || name == "" || value == "" {
+				return nil, fmt.Errorf("cloudwatch: invalid dimension %q, expected name=value", pair)
+			}
+			dimensions = append(dimensions, [2]string{strings.TrimSpace(name), strings.TrimSpace(value)})
+		}
+	}
+	// CloudWatch accepts at most 30 dimensions per metric
+	if len(dimensions) > 30 {
+		return nil, fmt.Errorf("cloudwatch: at most 30 dimensions are supported")
+	}
+
+	return &CloudWatchExporter{
+		namespace:  config.CloudWatchNamespace,
+		region:     region,
+		endpoint:   fmt.Sprintf("https://monitoring.%s.amazonaws.com/", region),
+		dimensions: dimensions,
+		creds:      creds,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// ExportInterval publishes the interval as one PutMetricData call
+func (e *CloudWatchExporter) ExportInterval(interval IntervalStats) error {
+	metrics := []struct {
+		name  string
+		unit  string
+		value float64
+	}{
+		{"RequestsPerSecond", "Count/Second", interval.RPS},
+		{"Errors", "Count", float64(interval.Errors)},
+		{"LatencyP50", "Milliseconds", interval.P50},
+		{"LatencyP95", "Milliseconds", interval.P95},
+		{"LatencyP99", "Milliseconds", interval.P99},
+		{"LatencyMax", "Milliseconds", interval.Max},
+	}
+
+	form := url.Values{}
+	form.Set("Action", "PutMetricData")
+	form.Set("Version", "2010-08-01")
+	form.Set("Namespace", e.namespace)
+	timestamp := interval.Time.UTC().Format(time.RFC3339)
+	for i, metric := range metrics {
+		prefix := fmt.Sprintf("MetricData.member.%d.", i+1)
+		form.Set(prefix+"MetricName", metric.name)
+		form.Set(prefix+"Unit", metric.unit)
+		form.Set(prefix+"Value", strconv.FormatFloat(metric.value, 'f', -1, 64))
+		form.Set(prefix+"Timestamp", timestamp)
+		for j, dimension := range e.dimensions {
+			dimPrefix := fmt.Sprintf("%sDimensions.member.%d.", prefix, j+1)
+			form.Set(dimPrefix+"Name", dimension[0])
+			form.Set(dimPrefix+"Value", dimension[1])
+		}
+	}
+
+	body := []byte(form.Encode())
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("cloudwatch: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	signAWSRequest(req, body, e.creds, e.region, "monitoring", time.Now())
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cloudwatch: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("cloudwatch: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// Close is a no-op, every interval is sent synchronously
+func (e *CloudWatchExporter) Close() error {
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CustomCommandNamer can be implemented by custom commands to report the
+// command name of the prepared request (e.g. "SET") for the per-command
+// latency breakdown.
+type CustomCommandNamer interface {
+	CommandName() string
+}
+
+// commandName returns the name of a command in the per-command breakdown
+func commandName(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return strings.ToUpper(args[0])
+}
+
+// registerCommand remembers the order in which commands are first seen. Callers must hold s.mu.
+func (s *BenchmarkStats) registerCommand(command string) {
+	if _, ok := s.commandLatencies[command]; ok {
+		return
+	}
+	if _, ok := s.commandErrors[command]; ok {
+		return
+	}
+	s.commandOrder = append(s.commandOrder, command)
+}
+
+// recordCommand adds a latency to the histogram of a command. Callers must hold s.mu.
+func (s *BenchmarkStats) recordCommand(command string, latency float64) {
+	s.registerCommand(command)
+	h, ok := s.commandLatencies[command]
+	if !ok {
+		h = NewLatencyHistogram()
+		s.commandLatencies[command] = h
+	}
+	h.Record(latency)
+}
+
+// breaksDownCommands reports whether the run issued more than one command, so
+// a per-command breakdown adds information. --ratio runs already break their
+// SETs and GETs down by path.
+func (s *BenchmarkStats) breaksDownCommands() bool {
+	return len(s.commandOrder) > 1 && s.config.Ratio == ""
+}
+
+// printCommandStats prints a latency block per command of mixed and custom
+// workloads, since a single aggregate hides that writes are usually slower
+// than reads
+func (s *BenchmarkStats) printCommandStats(totalTime float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.breaksDownCommands() {
+		return
+	}
+	for _, command := range s.commandOrder {
+		latencies, ok := s.commandLatencies[command]
+		if !ok {
+			latencies = NewLatencyHistogram()
+		}
+		fmt.Printf("\nLatency Statistics for command %s (ms, %d requests, %.2f req/s, %d errors):\n",
+			command, latencies.Count(), float64(latencies.Count())/totalTime, s.commandErrors[command])
+		fmt.Printf("=====================\n")
+		stats := latencies.Stats()
+		if stats == nil {
+			continue
+		}
+		fmt.Printf("Minimum: %.3f\n", stats.min)
+		fmt.Printf("Average: %.3f\n", stats.avg)
+		fmt.Printf("Maximum: %.3f\n", stats.max)
+		fmt.Printf("Median (p50): %.3f\n", stats.p50)
+		fmt.Printf("95th percentile: %.3f\n", stats.p95)
+		fmt.Printf("99th percentile: %.3f\n", stats.p99)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// ConcurrencyLimiter caps the number of requests in flight across all
+// workers. The limit can be changed while requests are running.
+type ConcurrencyLimiter struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	limit    int
+	inflight int
+	signals  chan os.Signal
+}
+
+// NewConcurrencyLimiter creates a limiter allowing limit requests in flight
+func NewConcurrencyLimiter(limit int) *ConcurrencyLimiter {
+	l := &ConcurrencyLimiter{limit: limit}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// Acquire blocks until a request may be sent. It returns false if ctx is
+// done first; wake must be called when ctx is done to interrupt the wait.
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.inflight >= l.limit {
+		if ctx.Err() != nil {
+			return false
+		}
+		l.cond.Wait()
+	}
+	l.inflight++
+	return true
+}
+
+// TryAcquire takes a slot if one is free, without waiting. A worker that
+// already holds slots uses it to grow its -pipeline batch, since blocking
+// while holding them could deadlock the workers.
+func (l *ConcurrencyLimiter) TryAcquire() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inflight >= l.limit {
+		return false
+	}
+	l.inflight++
+	return true
+}
+
+// wake wakes all waiting workers so they can observe a done context
+func (l *ConcurrencyLimiter) wake() {
+	l.mu.Lock()
+	l.cond.Broadcast()
+	l.mu.Unlock()
+}
+
+// Release marks a request as completed
+func (l *ConcurrencyLimiter) Release() {
+	l.mu.Lock()
+	l.inflight--
+	l.mu.Unlock()
+	l.cond.Signal()
+}
+
+// SetLimit changes the in-flight cap. Lowering it takes effect as requests complete.
+func (l *ConcurrencyLimiter) SetLimit(limit int) {
+	if limit < 1 {
+		limit = 1
+	}
+	l.mu.Lock()
+	l.limit = limit
+	l.mu.Unlock()
+	l.cond.Broadcast()
+}
+
+// Limit returns the current in-flight cap
+func (l *ConcurrencyLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// WatchSignals doubles the cap on SIGUSR1 and halves it on SIGUSR2 until StopSignals is called
+func (l *ConcurrencyLimiter) WatchSignals() {
+	l.signals = make(chan os.Signal, 1)
+	signal.Notify(l.signals, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		for sig := range l.signals {
+			limit := l.Limit()
+			if sig == syscall.SIGUSR1 {
+				limit *= 2
+			} else {
+				limit /= 2
+			}
+			l.SetLimit(limit)
+			slog.Info("max in-flight requests changed", "limit", l.Limit())
+		}
+	}()
+}
+
+// StopSignals stops reacting to SIGUSR1 and SIGUSR2
+func (l *ConcurrencyLimiter) StopSignals() {
+	if l.signals != nil {
+		signal.Stop(l.signals)
+		close(l.signals)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Error replies with which the server rejects requests until a condition clears
+const (
+	conditionOOM     = "OOM"     // Writes rejected above maxmemory
+	conditionLoading = "LOADING" // Requests rejected while the dataset is loaded
+)
+
+// conditionBackoffStart is the first delay after a rejected request
+const conditionBackoffStart = 10 * time.Millisecond
+
+// serverCondition returns the condition reported by an error reply, or "" for other errors
+func serverCondition(err error) string {
+	words := strings.FieldsFunc(err.Error(), func(r rune) bool {
+		return r == ' ' || r == ':' || r == '-' || r == '(' || r == ')'
+	})
+	for _, word := range words {
+		if word == conditionOOM || word == conditionLoading {
+			return word
+		}
+	}
+	return ""
+}
+
+// AddCondition counts a request rejected because of a server condition. It is
+// reported in its own bucket instead of the error count.
+func (s *BenchmarkStats) AddCondition(condition string) {
+	if condition == conditionOOM {
+		atomic.AddInt64(&s.ooms, 1)
+	} else {
+		atomic.AddInt64(&s.loading, 1)
+	}
+}
+
+// conditionBackoff delays the requests of a worker with exponentially growing
+// waits while the server rejects them, up to --server-backoff
+type conditionBackoff struct {
+	max   time.Duration
+	delay time.Duration
+}
+
+// newConditionBackoff creates the backoff of a worker (nil if disabled)
+func newConditionBackoff(config *Config) *conditionBackoff {
+	if config.ServerBackoff <= 0 {
+		return nil
+	}
+	return &conditionBackoff{max: time.Duration(config.ServerBackoff) * time.Millisecond}
+}
+
+// Wait sleeps after a rejected request and returns false if ctx was done first
+func (b *conditionBackoff) Wait(ctx context.Context, stats *BenchmarkStats) bool {
+	if b == nil {
+		return true
+	}
+	if b.delay == 0 {
+		b.delay = conditionBackoffStart
+	} else if b.delay *= 2; b.delay > b.max {
+		b.delay = b.max
+	}
+	atomic.AddInt64(&stats.backoffNanos, int64(b.delay))
+	return sleepContext(ctx, b.delay)
+}
+
+// Reset ends the backoff once a request succeeds
+func (b *conditionBackoff) Reset() {
+	if b != nil {
+		b.delay = 0
+	}
+}
+
+// printConditions prints the requests rejected by server conditions
+func (s *BenchmarkStats) printConditions() {
+	ooms := atomic.LoadInt64(&s.ooms)
+	loading := atomic.LoadInt64(&s.loading)
+	if ooms == 0 && loading == 0 {
+		return
+	}
+	fmt.Printf("Rejected with OOM (not errors): %d\n", ooms)
+	fmt.Printf("Rejected with LOADING (not errors): %d\n", loading)
+	if backoff := atomic.LoadInt64(&s.backoffNanos); backoff > 0 {
+		fmt.Printf("Time backed off by workers: %.2f seconds\n", time.Duration(backoff).Seconds())
+	}
+}
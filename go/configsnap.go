@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	glideconfig "github.com/valkey-io/valkey-glide/go/api/config"
+)
+
+// ConfigSnapshot is the server configuration captured at the start of a run
+type ConfigSnapshot struct {
+	RunID         string            `json:"run_id"`
+	Time          time.Time         `json:"time"`
+	Server        string            `json:"server,omitempty"`
+	ServerVersion string            `json:"server_version,omitempty"`
+	Config        map[string]string `json:"config"`
+}
+
+// snapshotServerConfig captures CONFIG GET * from the server, or from one node in cluster mode
+func (b *Benchmark) snapshotServerConfig() (*ConfigSnapshot, error) {
+	reply, err := executeOnRoute(b.poolClient(false, 0), []string{"CONFIG", "GET", "*"}, glideconfig.RandomRoute)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read server config: %v", err)
+	}
+	snapshot := &ConfigSnapshot{RunID: b.config.RunID, Time: time.Now(), Config: configValues(reply)}
+	if b.metadata != nil {
+		snapshot.Server, snapshot.ServerVersion = b.metadata.Server, b.metadata.ServerVersion
+	}
+	return snapshot, nil
+}
+
+// configValues converts a CONFIG GET reply, a map (RESP3) or a flat
+// name/value list (RESP2), to a map of strings
+func configValues(reply interface{}) map[string]string {
+	values := make(map[string]string)
+	switch v := reply.(type) {
+	case map[string]interface{}:
+		for name, value := range v {
+			values[name] = fmt.Sprint(value)
+		}
+	case []interface{}:
+		for i := 0; i+1 < len(v); i += 2 {
+			values[fmt.Sprint(v[i])] = fmt.Sprint(v[i+1])
+		}
+	}
+	return values
+}
+
+// writeConfigSnapshot writes a snapshot as a JSON document
+func writeConfigSnapshot(path string, snapshot *ConfigSnapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write config snapshot: %v", err)
+	}
+	return nil
+}
+
+// loadConfigSnapshot reads a snapshot written by an earlier run
+func loadConfigSnapshot(path string) (*ConfigSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config snapshot: %v", err)
+	}
+	var snapshot ConfigSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse config snapshot %s: %v", path, err)
+	}
+	return &snapshot, nil
+}
+
+// printConfigDiff prints the parameters that differ between an earlier
+// snapshot and the current one
+func printConfigDiff(previous, current *ConfigSnapshot) {
+	names := make(map[string]bool)
+	for name := range previous.Config {
+		names[name] = true
+	}
+	for name := range current.Config {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	fmt.Printf("\nServer CONFIG vs run %s (%s):\n", previous.RunID, previous.Time.Format(time.RFC3339))
+	if previous.ServerVersion != current.ServerVersion {
+		fmt.Printf("  server version: %s -> %s\n", previous.ServerVersion, current.ServerVersion)
+	}
+	changed := 0
+	for _, name := range sorted {
+		before, hadBefore := previous.Config[name]
+		after, hasAfter := current.Config[name]
+		switch {
+		case !hadBefore:
+			fmt.Printf("  + %s: %q\n", name, after)
+		case !hasAfter:
+			fmt.Printf("  - %s: %q\n", name, before)
+		case before != after:
+			fmt.Printf("  ~ %s: %q -> %q\n", name, before, after)
+		default:
+			continue
+		}
+		changed++
+	}
+	if changed == 0 {
+		fmt.Printf("  no differences in %d parameters\n", len(current.Config))
+	}
+	fmt.Println()
+}
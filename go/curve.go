@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// CurvePoint is one stage of a throughput-latency curve
+type CurvePoint struct {
+	OfferedQPS int
+	Summary    StatsSummary
+}
+
+// RunCurve runs one fixed-QPS stage per offered QPS level and reports
+// achieved throughput and latency for each, producing a saturation curve
+func (b *Benchmark) RunCurve(ctx context.Context) error {
+	var points []CurvePoint
+	for i, offered := range b.config.CurveQPS {
+		if ctx.Err() != nil {
+			break
+		}
+
+		stageConfig := *b.config
+		stageConfig.QPS = offered
+		stageConfig.StartQPS = 0
+		stageConfig.EndQPS = 0
+		stageConfig.QPSChangeInterval = 0
+		stageConfig.TestDuration = b.config.CurveStageSeconds
+		stageConfig.TotalRequests = 0
+		stageConfig.RampDownSeconds = 0
+
+		fmt.Printf("\nCurve stage %d/%d: offered QPS %d for %d seconds\n",
+			i+1, len(b.config.CurveQPS), offered, stageConfig.TestDuration)
+
+		stats := b.runStage(ctx, &stageConfig)
+		points = append(points, CurvePoint{OfferedQPS: offered, Summary: stats.Summary()})
+	}
+
+	printCurve(points)
+	if b.config.CurveCSV != "" {
+		if err := writeCurveCSV(b.config.CurveCSV, points); err != nil {
+			return err
+		}
+		fmt.Printf("Curve written to %s\n", b.config.CurveCSV)
+	}
+	return nil
+}
+
+// curveLatencies returns p50 and p99 of a stage, or zeros without successful requests
+func curveLatencies(summary StatsSummary) (float64, float64) {
+	if summary.Latency == nil {
+		return 0, 0
+	}
+	return summary.Latency.p50, summary.Latency.p99
+}
+
+// printCurve prints the curve as a table
+func printCurve(points []CurvePoint) {
+	fmt.Printf("\n\nThroughput-Latency Curve:\n")
+	fmt.Printf("=========================\n")
+	fmt.Printf("%12s %14s %10s %10s %10s\n", "Offered QPS", "Achieved QPS", "Errors", "p50 (ms)", "p99 (ms)")
+	for _, point := range points {
+		p50, p99 := curveLatencies(point.Summary)
+		fmt.Printf("%12d %14.2f %10d %10.3f %10.3f\n",
+			point.OfferedQPS, point.Summary.RPS, point.Summary.Errors, p50, p99)
+	}
+}
+
+// writeCurveCSV writes the curve as CSV with a header row
+func writeCurveCSV(path string, points []CurvePoint) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create curve CSV: %v", err)
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	w.Write([]string{"offered_qps", "achieved_qps", "requests", "errors", "p50_ms", "p99_ms"})
+	for _, point := range points {
+		p50, p99 := curveLatencies(point.Summary)
+		w.Write([]string{
+			strconv.Itoa(point.OfferedQPS),
+			strconv.FormatFloat(point.Summary.RPS, 'f', 2, 64),
+			strconv.FormatInt(point.Summary.Requests, 10),
+			strconv.FormatInt(point.Summary.Errors, 10),
+			strconv.FormatFloat(p50, 'f', 3, 64),
+			strconv.FormatFloat(p99, 'f', 3, 64),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+)
+
+// latencyDumpMagic identifies latency dataset files. The gzip-compressed file
+// contains the 8 byte magic, the number of samples as little-endian uint64 and
+// then every latency in milliseconds as little-endian float64.
+const latencyDumpMagic = "VKLAT001"
+
+// LatencyDataset collects raw latencies for offline analysis, either all of
+// them or a fixed-size uniform reservoir sample
+type LatencyDataset struct {
+	samples   []float64
+	seen      int64 // Number of latencies offered to the dataset
+	reservoir int   // Reservoir size (0 = keep all)
+	rng       *rand.Rand
+	mu        sync.Mutex
+}
+
+// NewLatencyDataset creates a dataset keeping all latencies, or a reservoir
+// sample of the given size
+func NewLatencyDataset(reservoir int) *LatencyDataset {
+	return &LatencyDataset{
+		reservoir: reservoir,
+		rng:       rand.New(rand.NewSource(benchRand.Int63())),
+	}
+}
+
+// Record adds a latency using reservoir sampling (Algorithm R) when a
+// reservoir size is configured
+func (d *LatencyDataset) Record(latency float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.seen++
+	if d.reservoir == 0 || len(d.samples) < d.reservoir {
+		d.samples = append(d.samples, latency)
+		return
+	}
+	if j := d.rng.Int63n(d.seen); j < int64(d.reservoir) {
+		d.samples[j] = latency
+	}
+}
+
+// WriteFile writes the dataset to a gzip-compressed binary file
+func (d *LatencyDataset) WriteFile(path string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create latency dump: %v", err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	w := bufio.NewWriter(gz)
+	w.WriteString(latencyDumpMagic)
+	binary.Write(w, binary.LittleEndian, uint64(len(d.samples)))
+	if err := binary.Write(w, binary.LittleEndian, d.samples); err != nil {
+		return fmt.Errorf("failed to write latency dump: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write latency dump: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write latency dump: %v", err)
+	}
+	return nil
+}
+
+// Len returns the number of samples kept and the number of latencies seen
+func (d *LatencyDataset) Len() (int, int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.samples), d.seen
+}
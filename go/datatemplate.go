@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// payloadGenerator creates one value of roughly size bytes
+type payloadGenerator func(rng *rand.Rand, size int) string
+
+// dataGenerator generates the values of --data-template (nil for random letters)
+var dataGenerator payloadGenerator
+
+// defaultJSONSchema is the document of --data-template json: a user profile
+// padded with a "bio" field to the data size
+const defaultJSONSchema = `{
+	"id": "int",
+	"name": "string:12",
+	"email": "email",
+	"age": "int:18:90",
+	"active": "bool",
+	"score": "float",
+	"created_at": "timestamp",
+	"tags": ["string:6"],
+	"address": {"street": "string:16", "city": "string:10", "zip": "int:10000:99999"}
+}`
+
+// loadDataTemplate parses --data-template, which is one of json,
+// json:<schema file>, csv or binary
+func loadDataTemplate(config *Config) (payloadGenerator, error) {
+	name, arg, _ := strings.Cut(config.DataTemplate, ":")
+	switch {
+	case name == "json" && arg == "":
+		return jsonGenerator([]byte(defaultJSONSchema), true)
+	case name == "json":
+		schema, err := os.ReadFile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read data schema: %v", err)
+		}
+		return jsonGenerator(schema, false)
+	case name == "csv" && arg == "":
+		return csvRows, nil
+	case name == "binary" && arg == "":
+		return protoBlob, nil
+	}
+	return nil, fmt.Errorf("unknown data template %q (expected json, json:<schema file>, csv or binary)", config.DataTemplate)
+}
+
+// schemaNode is a parsed element of a JSON document schema
+type schemaNode struct {
+	kind      string                 // Field type, "object", "array" or "literal"
+	low, high int64                  // Bounds of int, length of string
+	fields    map[string]*schemaNode // Members of an object
+	elem      *schemaNode            // Element of an array
+	literal   interface{}            // Value copied as is
+}
+
+// parseSchema converts a decoded schema: every string names a field type
+// (string[:N], int[:MIN:MAX], float, bool, timestamp, email or uuid), an
+// array holds the schema of its elements and other values are copied as is
+func parseSchema(node interface{}) (*schemaNode, error) {
+	switch n := node.(type) {
+	case string:
+		return parseFieldType(n)
+	case []interface{}:
+		if len(n) != 1 {
+			return nil, fmt.Errorf("arrays must hold exactly one element schema")
+		}
+		elem, err := parseSchema(n[0])
+		if err != nil {
+			return nil, err
+		}
+		return &schemaNode{kind: "array", elem: elem}, nil
+	case map[string]interface{}:
+		object := &schemaNode{kind: "object", fields: make(map[string]*schemaNode, len(n))}
+		names := make([]string, 0, len(n))
+		for field := range n {
+			names = append(names, field)
+		}
+		sort.Strings(names) // Report the first invalid field deterministically
+		for _, field := range names {
+			child, err := parseSchema(n[field])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", field, err)
+			}
+			object.fields[field] = child
+		}
+		return object, nil
+	}
+	return &schemaNode{kind: "literal", literal: node}, nil
+}
+
+// parseFieldType parses a field type such as "int:18:90"
+func parseFieldType(spec string) (*schemaNode, error) {
+	parts := strings.Split(spec, ":")
+	args := make([]int64, len(parts)-1)
+	for i, part := range parts[1:] {
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid argument in %q", spec)
+		}
+		args[i] = n
+	}
+	node := &schemaNode{kind: parts[0]}
+	switch parts[0] {
+	case "string":
+		node.high = 8
+		if len(args) == 1 && args[0] >= 0 {
+			node.high = args[0]
+		} else if len(args) != 0 {
+			return nil, fmt.Errorf("expected string or string:N, got %q", spec)
+		}
+	case "int":
+		node.low, node.high = 0, 1<<31-1
+		if len(args) == 2 && args[0] <= args[1] {
+			node.low, node.high = args[0], args[1]
+		} else if len(args) != 0 {
+			return nil, fmt.Errorf("expected int or int:MIN:MAX, got %q", spec)
+		}
+	case "float", "bool", "timestamp", "email", "uuid":
+		if len(args) != 0 {
+			return nil, fmt.Errorf("%s takes no arguments", parts[0])
+		}
+	default:
+		return nil, fmt.Errorf("unknown field type %q", parts[0])
+	}
+	return node, nil
+}
+
+// generate builds a random value following the schema
+func (n *schemaNode) generate(rng *rand.Rand) interface{} {
+	switch n.kind {
+	case "object":
+		fields := make(map[string]interface{}, len(n.fields))
+		for field, child := range n.fields {
+			fields[field] = child.generate(rng)
+		}
+		return fields
+	case "array":
+		items := make([]interface{}, 1+rng.Intn(4))
+		for i := range items {
+			items[i] = n.elem.generate(rng)
+		}
+		return items
+	case "string":
+		return randomString(rng, int(n.high))
+	case "int":
+		return n.low + rng.Int63n(n.high-n.low+1)
+	case "float":
+		return float64(rng.Intn(1000000)) / 100
+	case "bool":
+		return rng.Intn(2) == 1
+	case "timestamp":
+		return time.Now().Add(-time.Duration(rng.Int63n(int64(365 * 24 * time.Hour)))).UTC().Format(time.RFC3339)
+	case "email":
+		return strings.ToLower(randomString(rng, 8)) + "@example.com"
+	case "uuid":
+		var b [16]byte
+		binary.LittleEndian.PutUint64(b[:8], rng.Uint64())
+		binary.LittleEndian.PutUint64(b[8:], rng.Uint64())
+		b[6], b[8] = b[6]&0x0f|0x40, b[8]&0x3f|0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	}
+	return n.literal
+}
+
+// jsonGenerator returns a generator of documents following schema. With
+// pad, a "bio" field fills an object document up to the data size.
+func jsonGenerator(schema []byte, pad bool) (payloadGenerator, error) {
+	var decoded interface{}
+	if err := json.Unmarshal(schema, &decoded); err != nil {
+		return nil, fmt.Errorf("failed to parse data schema: %v", err)
+	}
+	root, err := parseSchema(decoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data schema: %v", err)
+	}
+	return func(rng *rand.Rand, size int) string {
+		doc := root.generate(rng)
+		encoded, _ := json.Marshal(doc)
+		if fields, ok := doc.(map[string]interface{}); ok && pad {
+			// The padded document adds ,"bio":"" and the letters
+			if missing := size - len(encoded) - len(`,"bio":""`); missing > 0 {
+				fields["bio"] = randomString(rng, missing)
+				encoded, _ = json.Marshal(fields)
+			}
+		}
+		return string(encoded)
+	}, nil
+}
+
+// randomString returns size random letters
+func randomString(rng *rand.Rand, size int) string {
+	var sb strings.Builder
+	sb.Grow(size)
+	randomLetters(rng, size, func(c byte) { sb.WriteByte(c) })
+	return sb.String()
+}
+
+// csvCities are the values of the city column of csvRows
+var csvCities = []string{"Seattle", "Berlin", "Tokyo", "Sydney", "Toronto", "Paris", "Mumbai", "Sao Paulo"}
+
+// csvRows generates newline separated rows of an order table
+// (id,customer,city,quantity,price,timestamp) until the next row would
+// exceed size, with at least one row
+func csvRows(rng *rand.Rand, size int) string {
+	var sb strings.Builder
+	for {
+		row := fmt.Sprintf("%d,%s,%s,%d,%.2f,%d\n",
+			rng.Int63n(1e9), randomString(rng, 10), csvCities[rng.Intn(len(csvCities))],
+			1+rng.Intn(20), float64(rng.Intn(100000))/100, time.Now().Unix()-rng.Int63n(86400*365))
+		if sb.Len() > 0 && sb.Len()+len(row) > size {
+			break
+		}
+		sb.WriteString(row)
+	}
+	return sb.String()
+}
+
+// protoBlob generates a protobuf-like binary message of exactly size bytes
+// (at least 2): a sequence of varint, fixed64 and length-delimited fields
+// with random numbers and bytes, ending in a length-delimited field that
+// fills the remaining space
+func protoBlob(rng *rand.Rand, size int) string {
+	buf := make([]byte, 0, size+binary.MaxVarintLen64)
+	field := uint64(1)
+	for {
+		remaining := size - len(buf)
+		// Room for a final tag, a one byte length and its payload
+		if remaining <= 2+binary.MaxVarintLen64+9 {
+			buf = binary.AppendUvarint(buf, field<<3|2)
+			length := size - len(buf) - 1
+			if length < 0 {
+				length = 0
+			}
+			buf = append(buf, byte(length))
+			for i := 0; i < length; i++ {
+				buf = append(buf, byte(rng.Intn(256)))
+			}
+			return string(buf)
+		}
+		switch rng.Intn(3) {
+		case 0:
+			buf = binary.AppendUvarint(buf, field<<3)
+			buf = binary.AppendUvarint(buf, uint64(rng.Int63n(1<<uint(1+rng.Intn(62)))))
+		case 1:
+			buf = binary.AppendUvarint(buf, field<<3|1)
+			buf = binary.LittleEndian.AppendUint64(buf, rng.Uint64())
+		default:
+			length := rng.Intn(32)
+			if length > remaining-2-binary.MaxVarintLen64-9 {
+				length = remaining - 2 - binary.MaxVarintLen64 - 9
+			}
+			buf = binary.AppendUvarint(buf, field<<3|2)
+			buf = append(buf, byte(length))
+			for i := 0; i < length; i++ {
+				buf = append(buf, byte(rng.Intn(256)))
+			}
+		}
+		field = field%15 + 1
+	}
+}
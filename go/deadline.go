@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/valkey-io/valkey-glide/go/api"
+)
+
+var (
+	// errRequestDeadline is reported for requests exceeding --request-deadline
+	errRequestDeadline = errors.New("request deadline exceeded")
+	// errRequestCancelled is reported for requests interrupted by the end of the run
+	errRequestCancelled = errors.New("request cancelled by shutdown")
+)
+
+// requestResult is the outcome of one benchmark request
+type requestResult struct {
+	sent     int64 // Approximate request bytes
+	received int64 // Approximate reply bytes
+	miss     bool  // GET found no value
+	err      error
+}
+
+// runWithDeadline runs op with a per-request deadline derived from ctx. When
+// the deadline passes or ctx is done first, the request is abandoned and
+// reported as errRequestDeadline or errRequestCancelled. With waitAbandoned
+// the abandoned op is still awaited before returning, for callers that can't
+// run the next request concurrently.
+func runWithDeadline(ctx context.Context, deadline time.Duration, op func() requestResult, waitAbandoned bool) requestResult {
+	requestCtx, cancel := context.WithTimeout(ctx, deadline)
+	defer cancel()
+
+	done := make(chan requestResult, 1)
+	go func() {
+		done <- op()
+	}()
+
+	select {
+	case result := <-done:
+		return result
+	case <-requestCtx.Done():
+	}
+	if waitAbandoned {
+		<-done
+	}
+	if ctx.Err() != nil {
+		return requestResult{err: errRequestCancelled}
+	}
+	return requestResult{err: fmt.Errorf("%w after %s", errRequestDeadline, deadline)}
+}
+
+// sleepContext sleeps for d and returns false if ctx was done first
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// classifyError counts timeouts and deadline overruns among failed requests
+func (s *BenchmarkStats) classifyError(err error) {
+	var timeoutErr *api.TimeoutError
+	if errors.Is(err, errRequestDeadline) {
+		atomic.AddInt64(&s.deadlineExceeded, 1)
+	} else if errors.As(err, &timeoutErr) {
+		atomic.AddInt64(&s.timeouts, 1)
+	}
+}
+
+// AddCancelled counts a request abandoned because the run ended. It is not an error.
+func (s *BenchmarkStats) AddCancelled() {
+	atomic.AddInt64(&s.cancelled, 1)
+}
+
+// printCancellations prints the breakdown of timeouts and cancelled requests
+func (s *BenchmarkStats) printCancellations() {
+	timeouts := atomic.LoadInt64(&s.timeouts)
+	deadlineExceeded := atomic.LoadInt64(&s.deadlineExceeded)
+	cancelled := atomic.LoadInt64(&s.cancelled)
+	if timeouts == 0 && deadlineExceeded == 0 && cancelled == 0 {
+		return
+	}
+	fmt.Printf("Client timeouts: %d\n", timeouts)
+	fmt.Printf("Request deadline exceeded: %d\n", deadlineExceeded)
+	fmt.Printf("Cancelled by shutdown (not errors): %d\n", cancelled)
+}
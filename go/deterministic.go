@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// splitMixGamma is the increment of the SplitMix64 generator
+const splitMixGamma = 0x9E3779B97F4A7C15
+
+// splitMix64 advances a SplitMix64 state and returns its next output
+func splitMix64(state *uint64) uint64 {
+	*state += splitMixGamma
+	z := *state
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	return z ^ (z >> 31)
+}
+
+// SeededWorkload generates the keys and values of the set and get
+// workloads with --deterministic from the seed and the sequence number of the
+// request alone, using an algorithm simple enough to implement identically in
+// every language of the project (see the README):
+//
+//	r(i)  = SplitMix64 output after i+1 steps from state seed
+//	key   = <prefix>:<r(i) mod keyspace> with -r, <prefix>:<i mod keylen> with
+//	        --sequential, <prefix>:<i> otherwise
+//	value = 13 letters A-Z per output of a SplitMix64 stream started at state
+//	        r(i), least significant base-26 digit first
+//
+// Request numbers are handed out from a shared counter, so a run of -n
+// requests issues the same requests whatever the thread count or language;
+// only their interleaving differs.
+type SeededWorkload struct {
+	seed     uint64
+	next     int64 // Sequence number of the next request
+	keyspace int64
+	keylen   int64 // Key range of --sequential (0 if not sequential)
+	offset   int64 // First key number of --keyspace-offset
+}
+
+// NewSeededWorkload creates the generator of --deterministic, starting
+// at the first request of this process
+func NewSeededWorkload(config *Config) *SeededWorkload {
+	w := &SeededWorkload{
+		seed:     uint64(config.Seed),
+		next:     config.RequestOffset,
+		keyspace: config.RandomKeyspace,
+		offset:   config.KeyspaceOffset,
+	}
+	if config.UseSequential {
+		w.keylen = config.SequentialKeyLen
+	}
+	return w
+}
+
+// Next returns the sequence number of a new request
+func (w *SeededWorkload) Next() int64 {
+	return atomic.AddInt64(&w.next, 1) - 1
+}
+
+// random returns r(i), the random value of request i
+func (w *SeededWorkload) random(index int64) uint64 {
+	// The SplitMix64 state after i+1 steps is seed + (i+1)*gamma
+	state := w.seed + uint64(index)*splitMixGamma
+	return splitMix64(&state)
+}
+
+// Key returns the key of request i. keyspace overrides the -r key range
+// while --keyspace-growth grows it.
+func (w *SeededWorkload) Key(prefix string, index, keyspace int64) string {
+	switch {
+	case w.keyspace > 0:
+		return fmt.Sprintf("%s:%d", prefix, w.offset+int64(w.random(index)%uint64(keyspace)))
+	case w.keylen > 0:
+		return fmt.Sprintf("%s:%d", prefix, w.offset+index%w.keylen)
+	}
+	return fmt.Sprintf("%s:%d", prefix, index)
+}
+
+// Value returns the size letter value of request i
+func (w *SeededWorkload) Value(index int64, size int) string {
+	state := w.random(index)
+	buf := make([]byte, size)
+	for i := 0; i < size; {
+		v := splitMix64(&state)
+		for j := 0; j < payloadCharsPerDraw && i < size; j++ {
+			buf[i] = payloadChars[v%uint64(len(payloadChars))]
+			v /= uint64(len(payloadChars))
+			i++
+		}
+	}
+	return string(buf)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"syscall"
+	"time"
+)
+
+// watchDiagnostics writes a diagnostic dump whenever the process receives
+// SIGQUIT, instead of exiting with Go's default stack dump. The returned
+// function stops watching.
+func (b *Benchmark) watchDiagnostics() func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGQUIT)
+	go func() {
+		for range signals {
+			path, err := b.writeDiagnosticsFile()
+			if err != nil {
+				slog.Error("diagnostic dump failed", "error", err)
+				continue
+			}
+			slog.Info("diagnostic dump written", "path", path)
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(signals)
+	}
+}
+
+// writeDiagnosticsFile writes a dump to a new file in the dump directory
+func (b *Benchmark) writeDiagnosticsFile() (string, error) {
+	name := fmt.Sprintf("vkbench-%s-dump-%s.txt", b.config.RunID, time.Now().Format("20060102-150405.000"))
+	path := filepath.Join(b.config.DumpDir, name)
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to write diagnostic dump: %v", err)
+	}
+	defer file.Close()
+	b.writeDiagnostics(file)
+	return path, nil
+}
+
+// writeDiagnostics writes the rate limiter and pool state followed by the
+// stacks of all goroutines
+func (b *Benchmark) writeDiagnostics(w io.Writer) {
+	fmt.Fprintf(w, "Valkey Benchmark diagnostic dump\n")
+	fmt.Fprintf(w, "Run ID: %s\n", b.config.RunID)
+	fmt.Fprintf(w, "Time: %s\n", time.Now().Format(time.RFC3339Nano))
+	fmt.Fprintf(w, "Goroutines: %d\n", runtime.NumGoroutine())
+
+	b.phaseMu.Lock()
+	qps, watchdog := b.phaseQPS, b.phaseWatchdog
+	b.phaseMu.Unlock()
+
+	fmt.Fprintf(w, "\nRate limiter:\n")
+	if qps == nil {
+		fmt.Fprintf(w, "  no active phase\n")
+	} else {
+		fmt.Fprintf(w, "  target QPS: %d (%.1f per worker)\n", qps.Target(), qps.currentWorkerRate(time.Now()))
+		if qps.bytesPerSecond > 0 {
+			qps.mu.Lock()
+			fmt.Fprintf(w, "  bandwidth target: %.0f bytes/s, next transfer in %s\n",
+				qps.bytesPerSecond, time.Until(qps.nextTransfer).Round(time.Millisecond))
+			qps.mu.Unlock()
+		}
+	}
+	if b.inflight != nil {
+		b.inflight.mu.Lock()
+		fmt.Fprintf(w, "  in flight: %d of %d\n", b.inflight.inflight, b.inflight.limit)
+		b.inflight.mu.Unlock()
+	}
+
+	fmt.Fprintf(w, "\nClient pools:\n")
+	b.poolMu.RLock()
+	for i, client := range b.clientPool {
+		fmt.Fprintf(w, "  %s: %T\n", connectionName(b.config, "w", i), client)
+	}
+	for i, client := range b.replicaPool {
+		fmt.Fprintf(w, "  %s: %T\n", connectionName(b.config, "r", i), client)
+	}
+	b.poolMu.RUnlock()
+
+	if watchdog != nil {
+		fmt.Fprintf(w, "\nWorkers:\n")
+		now := time.Now()
+		for threadID := range watchdog.slots {
+			slot := &watchdog.slots[threadID]
+			slot.mu.Lock()
+			if slot.start.IsZero() {
+				fmt.Fprintf(w, "  thread %d: idle\n", threadID)
+			} else {
+				tag := "w"
+				if slot.replica {
+					tag = "r"
+				}
+				fmt.Fprintf(w, "  thread %d: request on %s running for %s\n", threadID,
+					connectionName(b.config, tag, slot.client), now.Sub(slot.start).Round(time.Millisecond))
+			}
+			slot.mu.Unlock()
+		}
+	}
+
+	fmt.Fprintf(w, "\nGoroutine stacks:\n")
+	pprof.Lookup("goroutine").WriteTo(w, 2)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// managedService returns the name of the managed service hosting an
+// endpoint, recognised from its domain, or ""
+func managedService(host string) string {
+	host = strings.ToLower(host)
+	switch {
+	case strings.HasSuffix(host, ".cache.amazonaws.com"):
+		return "ElastiCache"
+	case strings.Contains(host, ".memorydb.") && strings.HasSuffix(host, ".amazonaws.com"):
+		return "MemoryDB"
+	}
+	return ""
+}
+
+// applyConfigEndpoint connects through a cluster configuration endpoint: the
+// endpoint becomes the seed address, cluster mode is enabled so the client
+// discovers all nodes from it, and TLS is enabled unless set explicitly.
+// The endpoint must resolve, so a typo fails before any connection attempt.
+func applyConfigEndpoint(config *Config, tlsSet bool) error {
+	host, port := config.ConfigEndpoint, 6379
+	if h, p, err := net.SplitHostPort(config.ConfigEndpoint); err == nil {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return fmt.Errorf("invalid port in configuration endpoint %q", config.ConfigEndpoint)
+		}
+		host, port = h, n
+	}
+	if _, err := net.LookupHost(host); err != nil {
+		return fmt.Errorf("failed to resolve configuration endpoint %s: %v", host, err)
+	}
+
+	config.Host, config.Port = host, port
+	config.IsCluster = true
+	if !tlsSet {
+		config.UseTLS = true
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// evictionSampleInterval is how often the eviction counters are read
+const evictionSampleInterval = time.Second
+
+// EvictionMonitor follows the evicted_keys counter of all primaries while
+// --eviction-pressure writes past maxmemory
+type EvictionMonitor struct {
+	config    *Config
+	client    interface{}
+	baseline  int64 // Counter at the start of the phase
+	evicted   int64 // Keys evicted since the start of the phase
+	reported  int64 // Evicted count at the last recorded interval
+	policy    string
+	maxMemory int64
+	usedAtEnd int64
+}
+
+// evictionInfo returns the evicted keys, used memory and maxmemory summed over
+// all primaries, and the eviction policy of the first one
+func evictionInfo(config *Config, client interface{}) (evicted, used, maxMemory int64, policy string, err error) {
+	reply, err := executeOnAllPrimaries(client, []string{"INFO", "all"})
+	if err != nil {
+		return 0, 0, 0, "", fmt.Errorf("failed to read INFO: %v", err)
+	}
+	for _, info := range nodeStrings(config, reply) {
+		fields := infoFields(info)
+		n, _ := strconv.ParseInt(fields["evicted_keys"], 10, 64)
+		evicted += n
+		n, _ = strconv.ParseInt(fields["used_memory"], 10, 64)
+		used += n
+		n, _ = strconv.ParseInt(fields["maxmemory"], 10, 64)
+		maxMemory += n
+		if policy == "" {
+			policy = fields["maxmemory_policy"]
+		}
+	}
+	return evicted, used, maxMemory, policy, nil
+}
+
+// newEvictionMonitor reads the eviction baseline of a phase
+func newEvictionMonitor(config *Config, client interface{}) (*EvictionMonitor, error) {
+	evicted, used, maxMemory, policy, err := evictionInfo(config, client)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case maxMemory == 0:
+		slog.Warn("maxmemory is not set, the server will not evict keys")
+	case policy == "noeviction":
+		slog.Warn("maxmemory-policy is noeviction, writes past maxmemory fail with OOM instead of evicting keys")
+	}
+	fmt.Printf("Eviction pressure: maxmemory %d bytes, policy %s, used memory %d bytes\n", maxMemory, policy, used)
+	return &EvictionMonitor{config: config, client: client, baseline: evicted, policy: policy, maxMemory: maxMemory}, nil
+}
+
+// Run samples the eviction counter until the context is done
+func (m *EvictionMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(evictionSampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := m.sample(); err != nil && ctx.Err() == nil {
+			slog.Warn("eviction sampling failed", "error", err)
+		}
+	}
+}
+
+// sample reads the current eviction counter and used memory
+func (m *EvictionMonitor) sample() error {
+	evicted, used, _, _, err := evictionInfo(m.config, m.client)
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt64(&m.evicted, evicted-m.baseline)
+	atomic.StoreInt64(&m.usedAtEnd, used)
+	return nil
+}
+
+// intervalEvictions returns the keys evicted since the last call
+func (m *EvictionMonitor) intervalEvictions() int64 {
+	evicted := atomic.LoadInt64(&m.evicted)
+	delta := evicted - m.reported
+	m.reported = evicted
+	return delta
+}
+
+// printEviction prints the evicted keys and compares the intervals before
+// the first eviction with the intervals once the server was evicting
+func (s *BenchmarkStats) printEviction() {
+	m := s.eviction
+	if m == nil {
+		return
+	}
+	fmt.Printf("\nEviction Pressure (policy %s, maxmemory %d bytes):\n", m.policy, m.maxMemory)
+	fmt.Printf("=================\n")
+	fmt.Printf("Evicted keys: %d\n", atomic.LoadInt64(&m.evicted))
+	fmt.Printf("Used memory at end: %d bytes\n", atomic.LoadInt64(&m.usedAtEnd))
+
+	var before, during []IntervalStats
+	evicting := false
+	for _, interval := range s.Timeline() {
+		evicting = evicting || interval.Evicted > 0
+		if interval.Requests == 0 {
+			continue
+		}
+		if evicting {
+			during = append(during, interval)
+		} else {
+			before = append(before, interval)
+		}
+	}
+	if len(during) > 0 {
+		fmt.Printf("First eviction at: %.1f s\n", during[0].Elapsed)
+	}
+	fmt.Printf("%-18s %10s %12s %14s %12s %14s\n", "Phase", "Intervals", "Avg RPS", "Median p99", "Worst p99", "Evicted/s")
+	for _, phase := range []struct {
+		name      string
+		intervals []IntervalStats
+	}{{"before eviction", before}, {"evicting", during}} {
+		if len(phase.intervals) == 0 {
+			continue
+		}
+		var rps, evicted float64
+		p99s := make([]float64, len(phase.intervals))
+		for i, interval := range phase.intervals {
+			rps += interval.RPS
+			evicted += float64(interval.Evicted)
+			p99s[i] = interval.P99
+		}
+		sort.Float64s(p99s)
+		n := float64(len(phase.intervals))
+		fmt.Printf("%-18s %10d %12.2f %14.3f %12.3f %14.1f\n",
+			phase.name, len(phase.intervals), rps/n, p99s[len(p99s)/2], p99s[len(p99s)-1], evicted/n)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/valkey-io/valkey-glide/go/api"
+)
+
+// expiryBands is the number of offset ranges around the expiry time reads are reported for
+const expiryBands = 10
+
+// ExpiryStats counts the reads of -t expiry by their offset from the expiry
+// time of the key
+type ExpiryStats struct {
+	ttl       time.Duration
+	window    time.Duration
+	bandWidth time.Duration
+	hits      [expiryBands]int64
+	misses    [expiryBands]int64
+}
+
+// newExpiryStats creates the counters of the -t expiry workload
+func newExpiryStats(config *Config) *ExpiryStats {
+	window := time.Duration(config.ExpiryWindow) * time.Millisecond
+	return &ExpiryStats{
+		ttl:       time.Duration(config.ExpiryTTL) * time.Millisecond,
+		window:    window,
+		bandWidth: 2 * window / expiryBands,
+	}
+}
+
+// band returns the band of a read at the given offset from the expiry time
+func (e *ExpiryStats) band(offset time.Duration) int {
+	band := int((offset + e.window) / e.bandWidth)
+	if band < 0 {
+		band = 0
+	}
+	if band >= expiryBands {
+		band = expiryBands - 1
+	}
+	return band
+}
+
+// bandLabel returns the offset range of a band in milliseconds
+func (e *ExpiryStats) bandLabel(band int) string {
+	from := -e.window + time.Duration(band)*e.bandWidth
+	return fmt.Sprintf("%+d..%+d ms", from.Milliseconds(), (from + e.bandWidth).Milliseconds())
+}
+
+// expiringKey is a key written by a worker and the reads still planned for it
+type expiringKey struct {
+	key       string
+	expiresAt time.Time
+	reads     int // Reads already sent
+}
+
+// expiryCommand writes keys with a short TTL and reads each of them
+// --expiry-reads times, spread evenly from --expiry-window before to
+// --expiry-window after its expiry, exercising lazy and active expiration
+type expiryCommand struct {
+	config  *Config
+	stats   *ExpiryStats
+	next    *int64 // Shared index of the next key
+	prefix  string
+	data    string
+	pending []*expiringKey
+	read    *expiringKey // Key of the prepared read (nil for a write)
+	args    []string
+	hit     bool
+}
+
+// newExpiryFactory returns a factory for commands of the -t expiry workload
+func newExpiryFactory(config *Config, stats *ExpiryStats) CustomCommandFactory {
+	next := new(int64)
+	prefix := keyPrefix(config) + ":expiry"
+	data := generateRandomData(config.DataSize)
+	return func() CustomCommand {
+		return &expiryCommand{config: config, stats: stats, next: next, prefix: prefix, data: data}
+	}
+}
+
+func (c *expiryCommand) Setup(client interface{}, workerID int, args string) error {
+	return nil
+}
+
+// readAt returns when the given read of a key is due
+func (c *expiryCommand) readAt(k *expiringKey) time.Time {
+	reads := c.config.ExpiryReads
+	offset := -c.stats.window
+	if reads > 1 {
+		offset += time.Duration(k.reads) * 2 * c.stats.window / time.Duration(reads-1)
+	}
+	return k.expiresAt.Add(offset)
+}
+
+// Prepare reads the key whose next read is due, or writes a new key
+func (c *expiryCommand) Prepare() error {
+	now := time.Now()
+	c.read = nil
+	for _, k := range c.pending {
+		if !c.readAt(k).After(now) {
+			c.read = k
+			break
+		}
+	}
+	if c.read != nil {
+		c.args = []string{"GET", c.read.key}
+		return nil
+	}
+	key := fmt.Sprintf("%s:%d", c.prefix, atomic.AddInt64(c.next, 1)-1)
+	c.args = []string{"SET", key, c.data, "PX", strconv.FormatInt(c.config.ExpiryTTL, 10)}
+	return nil
+}
+
+// Label reports the offset band of reads, so latency can be compared before
+// and after the key expired
+func (c *expiryCommand) Label() string {
+	if c.read == nil {
+		return "set"
+	}
+	return "read " + c.stats.bandLabel(c.stats.band(time.Since(c.read.expiresAt)))
+}
+
+// Key returns the written or read key
+func (c *expiryCommand) Key() string {
+	return c.args[1]
+}
+
+func (c *expiryCommand) Execute(client interface{}) error {
+	if c.read == nil {
+		// The TTL starts when the server receives the SET
+		expiresAt := time.Now().Add(c.stats.ttl)
+		if _, err := executeCommand(client, c.args); err != nil {
+			return err
+		}
+		c.pending = append(c.pending, &expiringKey{key: c.args[1], expiresAt: expiresAt})
+		return nil
+	}
+
+	offset := time.Since(c.read.expiresAt)
+	var result api.Result[string]
+	var err error
+	if cl, ok := client.(*api.GlideClient); ok {
+		result, err = cl.Get(c.read.key)
+	} else if cl, ok := client.(*api.GlideClusterClient); ok {
+		result, err = cl.Get(c.read.key)
+	}
+	if err != nil {
+		return err
+	}
+	c.hit = !result.IsNil()
+	band := c.stats.band(offset)
+	if c.hit {
+		atomic.AddInt64(&c.stats.hits[band], 1)
+	} else {
+		atomic.AddInt64(&c.stats.misses[band], 1)
+	}
+
+	c.read.reads++
+	if c.read.reads >= c.config.ExpiryReads {
+		for i, k := range c.pending {
+			if k == c.read {
+				c.pending = append(c.pending[:i], c.pending[i+1:]...)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// TransferredBytes approximates the encoded size of the last request and its reply
+func (c *expiryCommand) TransferredBytes() (sent, received int64) {
+	sent = respCommandSize(c.args...)
+	switch {
+	case c.read == nil:
+		received = respOKSize
+	case c.hit:
+		received = respBulkSize(len(c.data))
+	default:
+		received = respNilSize
+	}
+	return sent, received
+}
+
+func (c *expiryCommand) Teardown(client interface{}) error {
+	return nil
+}
+
+// printExpiry prints the hit ratio of reads by their offset from the expiry
+// time and where the transition from hits to misses was observed
+func (s *BenchmarkStats) printExpiry() {
+	e := s.expiry
+	if e == nil {
+		return
+	}
+	fmt.Printf("\nExpiry Boundary (TTL %d ms, reads from %d ms before to %d ms after expiry):\n",
+		e.ttl.Milliseconds(), e.window.Milliseconds(), e.window.Milliseconds())
+	fmt.Printf("===============\n")
+	fmt.Printf("%-20s %10s %10s %8s\n", "Offset", "Hits", "Misses", "Hit %")
+	transition := -1
+	for band := 0; band < expiryBands; band++ {
+		hits := atomic.LoadInt64(&e.hits[band])
+		misses := atomic.LoadInt64(&e.misses[band])
+		if hits+misses == 0 {
+			continue
+		}
+		ratio := 100 * float64(hits) / float64(hits+misses)
+		if transition < 0 && ratio < 50 {
+			transition = band
+		}
+		fmt.Printf("%-20s %10d %10d %7.1f%%\n", e.bandLabel(band), hits, misses, ratio)
+	}
+	if transition >= 0 {
+		fmt.Printf("Reads turned mostly into misses at: %s relative to the expiry time\n", e.bandLabel(transition))
+	}
+}
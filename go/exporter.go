@@ -0,0 +1,191 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MetricsExporter receives the statistics of every reporting interval while
+// the benchmark runs, e.g. to forward them to a monitoring system
+type MetricsExporter interface {
+	// ExportInterval is called once per reporting interval
+	ExportInterval(interval IntervalStats) error
+	// Close flushes pending data and releases resources
+	Close() error
+}
+
+// EventExporter can be implemented by exporters that also forward lifecycle
+// events (started, stage-changed, finished) of the run
+type EventExporter interface {
+	ExportEvent(event BenchmarkEvent) error
+}
+
+// BenchmarkEvent is a lifecycle event of a benchmark run
+type BenchmarkEvent struct {
+	Type  string                 `json:"type"` // "started", "stage-changed" or "finished"
+	RunID string                 `json:"run_id"`
+	Time  time.Time              `json:"time"`
+	Data  map[string]interface{} `json:"data,omitempty"`
+}
+
+// exporterMessage is either an interval or an event queued for export
+type exporterMessage struct {
+	interval *IntervalStats
+	event    *BenchmarkEvent
+}
+
+// ExporterHub fans interval statistics out to exporters on a background
+// goroutine, so slow exporters never block the benchmark workers
+type ExporterHub struct {
+	runID     string
+	exporters []MetricsExporter
+	messages  chan exporterMessage
+	dropped   int64 // Events dropped because the exporters fell behind
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewExporterHub starts delivering intervals to the given exporters
+func NewExporterHub(runID string, exporters []MetricsExporter) *ExporterHub {
+	hub := &ExporterHub{
+		runID:     runID,
+		exporters: exporters,
+		messages:  make(chan exporterMessage, 64),
+		done:      make(chan struct{}),
+	}
+	go hub.run()
+	return hub
+}
+
+func (h *ExporterHub) run() {
+	defer close(h.done)
+	for message := range h.messages {
+		for _, exporter := range h.exporters {
+			var err error
+			if message.interval != nil {
+				err = exporter.ExportInterval(*message.interval)
+			} else if eventExporter, ok := exporter.(EventExporter); ok {
+				err = eventExporter.ExportEvent(*message.event)
+			}
+			if err != nil {
+				slog.Warn("metrics export failed", "error", err)
+			}
+		}
+	}
+}
+
+// Publish queues an interval for export. Intervals are dropped with a warning
+// if the exporters fall too far behind.
+func (h *ExporterHub) Publish(interval IntervalStats) {
+	select {
+	case h.messages <- exporterMessage{interval: &interval}:
+	default:
+		slog.Warn("metrics exporters are falling behind, dropping interval")
+	}
+}
+
+// PublishEvent queues an event raised while the run is in progress. It never
+// blocks, since it is called from worker goroutines: events are dropped and
+// counted if the exporters fall too far behind.
+func (h *ExporterHub) PublishEvent(eventType string, data map[string]interface{}) {
+	select {
+	case h.messages <- h.event(eventType, data):
+	default:
+		atomic.AddInt64(&h.dropped, 1)
+	}
+}
+
+// PublishLifecycleEvent queues the started or finished event of the run. It
+// waits for room in the queue, so it must not be called from the workers.
+func (h *ExporterHub) PublishLifecycleEvent(eventType string, data map[string]interface{}) {
+	h.messages <- h.event(eventType, data)
+}
+
+func (h *ExporterHub) event(eventType string, data map[string]interface{}) exporterMessage {
+	return exporterMessage{event: &BenchmarkEvent{
+		Type:  eventType,
+		RunID: h.runID,
+		Time:  time.Now(),
+		Data:  data,
+	}}
+}
+
+// Close delivers the queued intervals and closes all exporters
+func (h *ExporterHub) Close() {
+	h.closeOnce.Do(func() {
+		close(h.messages)
+		<-h.done
+		if dropped := atomic.LoadInt64(&h.dropped); dropped > 0 {
+			slog.Warn("metrics exporters fell behind, events were dropped", "dropped", dropped)
+		}
+		for _, exporter := range h.exporters {
+			if err := exporter.Close(); err != nil {
+				slog.Warn("closing metrics exporter failed", "error", err)
+			}
+		}
+	})
+}
+
+// exporting reports whether a metrics exporter is enabled
+func exporting(config *Config) bool {
+	return config.IntervalCSV != "" || config.InfluxFile != "" || config.InfluxURL != "" ||
+		config.EventsNATS != "" || config.EventsKafkaREST != "" || config.CloudWatchNamespace != "" ||
+		config.StatsDAddr != "" || config.OTelEndpoint != "" || config.StreamAddr != ""
+}
+
+// createExporters builds the exporters enabled in the configuration
+func createExporters(config *Config) ([]MetricsExporter, error) {
+	var exporters []MetricsExporter
+	if config.IntervalCSV != "" {
+		exporter, err := NewIntervalCSVExporter(config)
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, exporter)
+	}
+	if config.InfluxFile != "" || config.InfluxURL != "" {
+		exporter, err := NewInfluxExporter(config)
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, exporter)
+	}
+	if config.EventsNATS != "" || config.EventsKafkaREST != "" {
+		exporter, err := NewBusExporter(config)
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, exporter)
+	}
+	if config.CloudWatchNamespace != "" {
+		exporter, err := NewCloudWatchExporter(config)
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, exporter)
+	}
+	if config.StatsDAddr != "" {
+		exporter, err := NewStatsDExporter(config)
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, exporter)
+	}
+	if config.OTelEndpoint != "" {
+		exporter, err := NewOTelExporter(config)
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, exporter)
+	}
+	if config.StreamAddr != "" {
+		exporter, err := NewStreamExporter(config)
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, exporter)
+	}
+	return exporters, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Float64List is a flag.Value holding a comma-separated list of numbers
+type Float64List []float64
+
+func (l *Float64List) String() string {
+	parts := make([]string, len(*l))
+	for i, v := range *l {
+		parts[i] = strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (l *Float64List) Set(value string) error {
+	*l = (*l)[:0]
+	for _, part := range strings.Split(value, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", part)
+		}
+		*l = append(*l, v)
+	}
+	return nil
+}
+
+// IntList is a flag.Value holding a comma-separated list of integers
+type IntList []int
+
+func (l *IntList) String() string {
+	parts := make([]string, len(*l))
+	for i, v := range *l {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (l *IntList) Set(value string) error {
+	*l = (*l)[:0]
+	for _, part := range strings.Split(value, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", part)
+		}
+		*l = append(*l, v)
+	}
+	return nil
+}
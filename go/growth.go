@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// growthBands is the number of keyspace ranges the growth report is split into
+const growthBands = 5
+
+// growingKeyspace returns the random keyspace in effect at the given time into
+// the run: -r grows by KeyspaceGrowth keys per second up to KeyspaceMax
+func growingKeyspace(config *Config, elapsed time.Duration) int64 {
+	keyspace := config.RandomKeyspace + int64(config.KeyspaceGrowth*elapsed.Seconds())
+	if config.KeyspaceMax > 0 && keyspace > config.KeyspaceMax {
+		keyspace = config.KeyspaceMax
+	}
+	return keyspace
+}
+
+// printKeyspaceGrowth prints throughput and tail latency for equal ranges of
+// the keyspace size, showing how performance changed as the dataset grew
+func (s *BenchmarkStats) printKeyspaceGrowth() {
+	if s.config.KeyspaceGrowth <= 0 {
+		return
+	}
+	var intervals []IntervalStats
+	for _, interval := range s.Timeline() {
+		if interval.Keyspace > 0 {
+			intervals = append(intervals, interval)
+		}
+	}
+	if len(intervals) == 0 {
+		return
+	}
+	lo, hi := intervals[0].Keyspace, intervals[len(intervals)-1].Keyspace
+
+	fmt.Printf("\nKeyspace Growth (%.0f keys/s):\n", s.config.KeyspaceGrowth)
+	fmt.Printf("===============\n")
+	fmt.Printf("%-25s %10s %12s %12s\n", "Keyspace", "Intervals", "Avg RPS", "Worst p99")
+	span := (hi - lo + growthBands) / growthBands
+	for band := int64(0); band < growthBands; band++ {
+		from, to := lo+band*span, lo+(band+1)*span
+		var count int
+		var rps, p99 float64
+		for _, interval := range intervals {
+			if interval.Keyspace >= from && (interval.Keyspace < to || band == growthBands-1) {
+				count++
+				rps += interval.RPS
+				if interval.P99 > p99 {
+					p99 = interval.P99
+				}
+			}
+		}
+		if count == 0 {
+			continue
+		}
+		fmt.Printf("%-25s %10d %12.2f %12.3f\n", fmt.Sprintf("%d-%d", from, to-1), count, rps/float64(count), p99)
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// The latency histogram has the bucket layout of an HDR histogram tracking
+// nanoseconds with 3 significant digits: every power of two range is split
+// into 1024 linear sub-buckets, so a recorded value is off by less than 0.1%.
+// Values up to an hour are tracked; slower requests are clamped into the
+// highest bucket (the exact maximum is still reported).
+const (
+	histogramSubBucketBits = 11
+	histogramSubBuckets    = 1 << histogramSubBucketBits // Sub-buckets of the first bucket
+	histogramHalfBuckets   = histogramSubBuckets / 2     // Sub-buckets of every following bucket
+	histogramHighest       = int64(time.Hour)            // Highest trackable value in nanoseconds
+)
+
+// histogramLength is the number of counters covering 0 to histogramHighest
+var histogramLength = histogramIndex(histogramHighest) + 1
+
+// LatencyHistogram records latencies in constant memory (about 270 KB)
+// regardless of the number of requests, unlike a slice of every sample, and
+// computes percentiles without sorting. Count, sum, min and max are exact.
+type LatencyHistogram struct {
+	counts []int64
+	count  int64
+	sum    float64 // Milliseconds
+	min    float64 // Milliseconds
+	max    float64 // Milliseconds
+}
+
+// HistogramState is the serializable form of a histogram: its non-empty
+// counters and the exact count, sum, min and max
+type HistogramState struct {
+	Indexes []int32
+	Counts  []int64
+	Sum     float64
+	Min     float64
+	Max     float64
+}
+
+// NewLatencyHistogram creates an empty histogram
+func NewLatencyHistogram() *LatencyHistogram {
+	return &LatencyHistogram{counts: make([]int64, histogramLength)}
+}
+
+// histogramIndex returns the counter of a value in nanoseconds
+func histogramIndex(nanos int64) int {
+	if nanos < 0 {
+		nanos = 0
+	} else if nanos > histogramHighest {
+		nanos = histogramHighest
+	}
+	bucket := 63 - bits.LeadingZeros64(uint64(nanos)|(histogramSubBuckets-1)) - (histogramSubBucketBits - 1)
+	subBucket := int(nanos >> uint(bucket))
+	return bucket<<(histogramSubBucketBits-1) + subBucket
+}
+
+// histogramValue returns the midpoint in milliseconds of the values counted
+// by a counter
+func histogramValue(index int) float64 {
+	bucket := index>>(histogramSubBucketBits-1) - 1
+	subBucket := int64(index&(histogramHalfBuckets-1) + histogramHalfBuckets)
+	if bucket < 0 {
+		subBucket -= histogramHalfBuckets
+		bucket = 0
+	}
+	low := subBucket << uint(bucket)
+	width := int64(1) << uint(bucket)
+	return (float64(low) + float64(width-1)/2) / float64(time.Millisecond)
+}
+
+// Record adds a latency in milliseconds
+func (h *LatencyHistogram) Record(latency float64) {
+	h.counts[histogramIndex(int64(math.Round(latency*float64(time.Millisecond))))]++
+	if h.count == 0 || latency < h.min {
+		h.min = latency
+	}
+	if h.count == 0 || latency > h.max {
+		h.max = latency
+	}
+	h.count++
+	h.sum += latency
+}
+
+// Count returns the number of recorded latencies
+func (h *LatencyHistogram) Count() int64 {
+	return h.count
+}
+
+// Sum returns the sum of the recorded latencies in milliseconds
+func (h *LatencyHistogram) Sum() float64 {
+	return h.sum
+}
+
+// Merge adds all latencies of other to the histogram
+func (h *LatencyHistogram) Merge(other *LatencyHistogram) {
+	if other.count == 0 {
+		return
+	}
+	for i, count := range other.counts {
+		h.counts[i] += count
+	}
+	if h.count == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	if h.count == 0 || other.max > h.max {
+		h.max = other.max
+	}
+	h.count += other.count
+	h.sum += other.sum
+}
+
+// Copy returns an independent copy of the histogram
+func (h *LatencyHistogram) Copy() *LatencyHistogram {
+	c := *h
+	c.counts = append([]int64(nil), h.counts...)
+	return &c
+}
+
+// Since returns the latencies recorded after earlier, a copy taken from this
+// histogram. Min and max are estimated from the counters.
+func (h *LatencyHistogram) Since(earlier *LatencyHistogram) *LatencyHistogram {
+	diff := NewLatencyHistogram()
+	for i, count := range h.counts {
+		if count -= earlier.counts[i]; count > 0 {
+			diff.counts[i] = count
+			if diff.count == 0 {
+				diff.min = histogramValue(i)
+			}
+			diff.max = histogramValue(i)
+			diff.count += count
+		}
+	}
+	diff.sum = h.sum - earlier.sum
+	return diff
+}
+
+// ForEach calls fn with the midpoint and the count of every non-empty counter
+func (h *LatencyHistogram) ForEach(fn func(latency float64, count int64)) {
+	for i, count := range h.counts {
+		if count > 0 {
+			fn(histogramValue(i), count)
+		}
+	}
+}
+
+// CountAbove returns the number of latencies in counters above the one of latency
+func (h *LatencyHistogram) CountAbove(latency float64) int64 {
+	var above int64
+	for i := histogramIndex(int64(math.Round(latency*float64(time.Millisecond)))) + 1; i < len(h.counts); i++ {
+		above += h.counts[i]
+	}
+	return above
+}
+
+// Quantiles returns the latencies at the given quantiles, in ascending order,
+// in a single pass over the counters. Like indexing the sorted samples at
+// count*q, each is the latency of the sample at that rank, clamped to the
+// exact min and max.
+func (h *LatencyHistogram) Quantiles(qs ...float64) []float64 {
+	values := make([]float64, len(qs))
+	if h.count == 0 {
+		return values
+	}
+	var seen int64
+	next := 0
+	for i, count := range h.counts {
+		if count == 0 {
+			continue
+		}
+		seen += count
+		for next < len(qs) && seen > int64(float64(h.count)*qs[next]) {
+			values[next] = math.Min(math.Max(histogramValue(i), h.min), h.max)
+			next++
+		}
+		if next == len(qs) {
+			break
+		}
+	}
+	for ; next < len(qs); next++ {
+		values[next] = h.max
+	}
+	return values
+}
+
+// Stats computes the latency statistics, or returns nil if the histogram is empty
+func (h *LatencyHistogram) Stats() *LatencyStats {
+	if h.count == 0 {
+		return nil
+	}
+	q := h.Quantiles(0.50, 0.95, 0.99, 0.999)
+	return &LatencyStats{
+		min:  h.min,
+		max:  h.max,
+		avg:  h.sum / float64(h.count),
+		p50:  q[0],
+		p95:  q[1],
+		p99:  q[2],
+		p999: q[3],
+	}
+}
+
+// State returns the serializable form of the histogram
+func (h *LatencyHistogram) State() HistogramState {
+	state := HistogramState{Sum: h.sum, Min: h.min, Max: h.max}
+	for i, count := range h.counts {
+		if count > 0 {
+			state.Indexes = append(state.Indexes, int32(i))
+			state.Counts = append(state.Counts, count)
+		}
+	}
+	return state
+}
+
+// histogramFromState rebuilds a histogram from its serializable form
+func histogramFromState(state HistogramState) *LatencyHistogram {
+	h := NewLatencyHistogram()
+	for i, index := range state.Indexes {
+		h.counts[index] += state.Counts[i]
+		h.count += state.Counts[i]
+	}
+	h.sum, h.min, h.max = state.Sum, state.Min, state.Max
+	return h
+}
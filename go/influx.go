@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// influxMeasurement is the measurement name used for interval metrics
+const influxMeasurement = "valkey_benchmark"
+
+// InfluxExporter writes interval metrics in InfluxDB line protocol to a file
+// and/or an HTTP write endpoint
+type InfluxExporter struct {
+	tags   string // Pre-rendered tag set including the leading comma
+	file   *os.File
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewInfluxExporter creates an exporter for the configured file and endpoint
+func NewInfluxExporter(config *Config) (*InfluxExporter, error) {
+	e := &InfluxExporter{
+		tags:   influxTags(config),
+		url:    config.InfluxURL,
+		token:  config.InfluxToken,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+	if config.InfluxFile != "" {
+		file, err := os.OpenFile(config.InfluxFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open influx file: %v", err)
+		}
+		e.file = file
+	}
+	return e, nil
+}
+
+// influxTags renders the tags identifying the run
+func influxTags(config *Config) string {
+	tags := []struct{ key, value string }{
+		{"run_id", config.RunID},
+		{"command", config.Command},
+		{"target", fmt.Sprintf("%s:%d", config.Host, config.Port)},
+	}
+	var sb strings.Builder
+	for _, tag := range tags {
+		sb.WriteString(",")
+		sb.WriteString(tag.key)
+		sb.WriteString("=")
+		sb.WriteString(escapeInfluxTag(tag.value))
+	}
+	return sb.String()
+}
+
+// escapeInfluxTag escapes commas, equals signs and spaces in tag values
+func escapeInfluxTag(value string) string {
+	return strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `).Replace(value)
+}
+
+// formatLine renders one interval as a line protocol point with nanosecond precision
+func (e *InfluxExporter) formatLine(interval IntervalStats) string {
+	fields := []string{
+		"requests=" + strconv.FormatInt(interval.Requests, 10) + "i",
+		"errors=" + strconv.FormatInt(interval.Errors, 10) + "i",
+		"rps=" + strconv.FormatFloat(interval.RPS, 'f', 2, 64),
+		"p50_ms=" + strconv.FormatFloat(interval.P50, 'f', 3, 64),
+		"p95_ms=" + strconv.FormatFloat(interval.P95, 'f', 3, 64),
+		"p99_ms=" + strconv.FormatFloat(interval.P99, 'f', 3, 64),
+		"max_ms=" + strconv.FormatFloat(interval.Max, 'f', 3, 64),
+	}
+	return fmt.Sprintf("%s%s %s %d\n", influxMeasurement, e.tags, strings.Join(fields, ","), interval.Time.UnixNano())
+}
+
+// ExportInterval writes the interval to the file and posts it to the endpoint
+func (e *InfluxExporter) ExportInterval(interval IntervalStats) error {
+	line := e.formatLine(interval)
+	if e.file != nil {
+		if _, err := e.file.WriteString(line); err != nil {
+			return fmt.Errorf("influx file: %v", err)
+		}
+	}
+	if e.url != "" {
+		req, err := http.NewRequest(http.MethodPost, e.url, strings.NewReader(line))
+		if err != nil {
+			return fmt.Errorf("influx: %v", err)
+		}
+		req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+		if e.token != "" {
+			req.Header.Set("Authorization", "Token "+e.token)
+		}
+		resp, err := e.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("influx: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			return fmt.Errorf("influx: %s: %s", resp.Status, bytes.TrimSpace(body))
+		}
+	}
+	return nil
+}
+
+// Close closes the output file
+func (e *InfluxExporter) Close() error {
+	if e.file != nil {
+		return e.file.Close()
+	}
+	return nil
+}
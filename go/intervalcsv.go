@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// intervalCSVHeader is the header row of the --interval-csv file
+const intervalCSVHeader = "timestamp,elapsed_s,requests,rps,errors,p50_ms,p95_ms,p99_ms,max_ms\n"
+
+// IntervalCSVExporter appends one CSV row per reporting interval to a file,
+// so throughput and latency can be plotted over time (e.g. during a QPS ramp)
+type IntervalCSVExporter struct {
+	file *os.File
+}
+
+// NewIntervalCSVExporter opens the --interval-csv file for appending and
+// writes the header row if the file is empty
+func NewIntervalCSVExporter(config *Config) (*IntervalCSVExporter, error) {
+	file, err := os.OpenFile(config.IntervalCSV, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open interval CSV file: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open interval CSV file: %v", err)
+	}
+	if info.Size() == 0 {
+		if _, err := file.WriteString(intervalCSVHeader); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to write interval CSV header: %v", err)
+		}
+	}
+	return &IntervalCSVExporter{file: file}, nil
+}
+
+// ExportInterval appends the interval as a row
+func (e *IntervalCSVExporter) ExportInterval(interval IntervalStats) error {
+	_, err := fmt.Fprintf(e.file, "%s,%.3f,%d,%.2f,%d,%.3f,%.3f,%.3f,%.3f\n",
+		interval.Time.UTC().Format(time.RFC3339Nano), interval.Elapsed, interval.Requests, interval.RPS,
+		interval.Errors, interval.P50, interval.P95, interval.P99, interval.Max)
+	if err != nil {
+		return fmt.Errorf("interval csv: %v", err)
+	}
+	return nil
+}
+
+// Close closes the output file
+func (e *IntervalCSVExporter) Close() error {
+	return e.file.Close()
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+)
+
+// countKeys returns the DBSIZE of every primary keyed by node address. In
+// standalone mode the single server is keyed by the configured host and port.
+func (b *Benchmark) countKeys() (map[string]int64, error) {
+	result, err := executeOnAllPrimaries(b.clientPool[0], []string{"DBSIZE"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DBSIZE: %v", err)
+	}
+
+	counts := make(map[string]int64)
+	if nodes, ok := result.(map[string]interface{}); ok {
+		for node, value := range nodes {
+			if counts[node], err = toInt64(value); err != nil {
+				return nil, fmt.Errorf("unexpected DBSIZE reply from %s: %v", node, err)
+			}
+		}
+		return counts, nil
+	}
+
+	node := fmt.Sprintf("%s:%d", b.config.Host, b.config.Port)
+	if counts[node], err = toInt64(result); err != nil {
+		return nil, fmt.Errorf("unexpected DBSIZE reply: %v", err)
+	}
+	return counts, nil
+}
+
+// toInt64 converts an integer reply to int64
+func toInt64(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	}
+	return 0, fmt.Errorf("got %T", value)
+}
+
+// printKeyCountDelta prints the key count of every node before and after the
+// run as a sanity check of what the workload created or deleted
+func (b *Benchmark) printKeyCountDelta(before map[string]int64) {
+	after, err := b.countKeys()
+	if err != nil {
+		slog.Warn("key count failed", "error", err)
+		return
+	}
+
+	nodes := make([]string, 0, len(after))
+	for node := range after {
+		nodes = append(nodes, node)
+	}
+	for node := range before {
+		if _, ok := after[node]; !ok {
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Strings(nodes)
+
+	fmt.Printf("\nKeyspace (DBSIZE):\n")
+	fmt.Printf("==================\n")
+	fmt.Printf("%-24s %12s %12s %12s\n", "Node", "Before", "After", "Delta")
+	var totalBefore, totalAfter int64
+	for _, node := range nodes {
+		totalBefore += before[node]
+		totalAfter += after[node]
+		fmt.Printf("%-24s %12d %12d %+12d\n", node, before[node], after[node], after[node]-before[node])
+	}
+	if len(nodes) > 1 {
+		fmt.Printf("%-24s %12d %12d %+12d\n", "Total", totalBefore, totalAfter, totalAfter-totalBefore)
+	}
+}
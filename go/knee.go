@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// kneeGain is the relative throughput gain below which another doubling of
+// the thread count is considered to no longer help
+const kneeGain = 0.05
+
+// KneePoint is the outcome of one thread count of the knee search
+type KneePoint struct {
+	Threads int
+	Summary StatsSummary
+}
+
+// RunKneeSearch doubles the worker thread count from 1 up to KneeMaxThreads,
+// running one stage per count, until two doublings in a row improve
+// throughput by less than 5%. It then recommends the smallest thread count
+// reaching 95% of the best throughput.
+func (b *Benchmark) RunKneeSearch(ctx context.Context) error {
+	var points []KneePoint
+	flat := 0
+	for threads := 1; threads <= b.config.KneeMaxThreads && flat < 2; threads *= 2 {
+		if ctx.Err() != nil {
+			break
+		}
+
+		stageConfig := *b.config
+		stageConfig.NumThreads = threads
+		stageConfig.TestDuration = b.config.SweepStageSeconds
+		stageConfig.TotalRequests = 0
+		stageConfig.RampDownSeconds = 0
+
+		fmt.Printf("\nKnee search stage: %d threads for %d seconds\n", threads, stageConfig.TestDuration)
+		stats := b.runStage(ctx, &stageConfig)
+		point := KneePoint{Threads: threads, Summary: stats.Summary()}
+
+		if n := len(points); n > 0 && point.Summary.RPS < points[n-1].Summary.RPS*(1+kneeGain) {
+			flat++
+		} else {
+			flat = 0
+		}
+		points = append(points, point)
+	}
+
+	printKnee(points)
+	return nil
+}
+
+// kneeRecommendation returns the index of the smallest thread count reaching
+// 95% of the best throughput, or -1 without results
+func kneeRecommendation(points []KneePoint) int {
+	best := bestKneeRPS(points)
+	for i, point := range points {
+		if point.Summary.RPS > 0 && point.Summary.RPS >= best*(1-kneeGain) {
+			return i
+		}
+	}
+	return -1
+}
+
+// printKnee prints the knee search as a table with the recommended thread
+// count and marks counts beyond it whose p99 is more than twice as high
+func printKnee(points []KneePoint) {
+	recommended := kneeRecommendation(points)
+
+	fmt.Printf("\n\nThread Count Knee Search:\n")
+	fmt.Printf("=========================\n")
+	fmt.Printf("%10s %14s %10s %10s %10s\n", "Threads", "RPS", "Errors", "p50 (ms)", "p99 (ms)")
+	for i, point := range points {
+		p50, p99 := curveLatencies(point.Summary)
+		note := ""
+		if i == recommended {
+			note = "  <- recommended"
+		} else if recommended >= 0 && i > recommended {
+			if _, kneeP99 := curveLatencies(points[recommended].Summary); kneeP99 > 0 && p99 > 2*kneeP99 {
+				note = "  (p99 degraded)"
+			}
+		}
+		fmt.Printf("%10d %14.2f %10d %10.3f %10.3f%s\n",
+			point.Threads, point.Summary.RPS, point.Summary.Errors, p50, p99, note)
+	}
+
+	if recommended < 0 {
+		fmt.Printf("No stage completed requests, no recommendation\n")
+		return
+	}
+	knee := points[recommended]
+	fmt.Printf("Recommended concurrency: %d threads (%.2f RPS, %.0f%% of the best)\n",
+		knee.Threads, knee.Summary.RPS, 100*knee.Summary.RPS/bestKneeRPS(points))
+}
+
+// bestKneeRPS returns the highest throughput of the knee search
+func bestKneeRPS(points []KneePoint) float64 {
+	best := 0.0
+	for _, point := range points {
+		if point.Summary.RPS > best {
+			best = point.Summary.RPS
+		}
+	}
+	return best
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// requestErrorLimit is the number of failed requests logged per second; the
+// rest are counted and reported with the next logged failure
+const requestErrorLimit = 10
+
+// requestErrors rate-limits the logs of failed requests across all threads
+var requestErrors struct {
+	mu         sync.Mutex
+	second     time.Time
+	logged     int
+	suppressed int64
+}
+
+// logRequestError logs a failed request of a worker thread, so that a server
+// outage doesn't flood the log with one line per request
+func logRequestError(threadID int, err error) {
+	requestErrors.mu.Lock()
+	if second := time.Now().Truncate(time.Second); !second.Equal(requestErrors.second) {
+		requestErrors.second = second
+		requestErrors.logged = 0
+	}
+	if requestErrors.logged >= requestErrorLimit {
+		requestErrors.suppressed++
+		requestErrors.mu.Unlock()
+		return
+	}
+	requestErrors.logged++
+	suppressed := requestErrors.suppressed
+	requestErrors.suppressed = 0
+	requestErrors.mu.Unlock()
+
+	if suppressed > 0 {
+		slog.Error("request failed", "thread", threadID, "error", err, "suppressed", suppressed)
+		return
+	}
+	slog.Error("request failed", "thread", threadID, "error", err)
+}
+
+// setupLogging installs the default slog logger for benchmark internals such
+// as rate changes, reconnects and errors. Logs go to stderr, or to the log
+// file, so they stay separate from the results on stdout. The returned
+// function closes the log file.
+func setupLogging(config *Config) (func(), error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(config.LogLevel)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q, expected debug, info, warn or error", config.LogLevel)
+	}
+
+	var out io.Writer = os.Stderr
+	closeLog := func() {}
+	if config.LogFile != "" {
+		file, err := os.OpenFile(config.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file: %v", err)
+		}
+		out = file
+		closeLog = func() { file.Close() }
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(config.LogFormat) {
+	case "text":
+		handler = slog.NewTextHandler(out, options)
+	case "json":
+		handler = slog.NewJSONHandler(out, options)
+	default:
+		closeLog()
+		return nil, fmt.Errorf("invalid log format %q, expected text or json", config.LogFormat)
+	}
+	slog.SetDefault(slog.New(handler).With("run_id", config.RunID))
+	return closeLog, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// ManifestFile is an input file of a run with its content hash
+type ManifestFile struct {
+	Option string `json:"option"`
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// ReproManifest holds everything needed to re-run an identical benchmark
+type ReproManifest struct {
+	RunID            string                 `json:"run_id"`
+	Created          time.Time              `json:"created"`
+	BenchmarkVersion string                 `json:"benchmark_version"`
+	Revision         string                 `json:"revision,omitempty"` // VCS revision the binary was built from
+	GoVersion        string                 `json:"go_version"`
+	Seed             int64                  `json:"seed"`
+	CommandLine      []string               `json:"command_line"`
+	Config           map[string]interface{} `json:"config"`
+	Files            []ManifestFile         `json:"files,omitempty"`
+}
+
+// buildVersion returns the module version and VCS revision of the binary
+func buildVersion() (version, revision string) {
+	version = "unknown"
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return version, ""
+	}
+	version = info.Main.Version
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			if setting.Value == "true" && revision != "" {
+				revision += "-dirty"
+			}
+		}
+	}
+	return version, revision
+}
+
+// hashFile returns the hex SHA-256 of a file's content
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// writeManifest writes the reproducibility manifest of a run: the effective
+// configuration, the random seed, the hashes of the workload input files and
+// the version of the binary
+func writeManifest(path string, config *Config) error {
+	manifest := ReproManifest{
+		RunID:       config.RunID,
+		Created:     time.Now(),
+		GoVersion:   runtime.Version(),
+		Seed:        config.Seed,
+		CommandLine: redactedArgs(os.Args),
+		Config:      redactedConfig(config),
+	}
+	manifest.BenchmarkVersion, manifest.Revision = buildVersion()
+
+	inputs := []struct{ option, path string }{
+		{"--scenario", config.ScenarioFile},
+		{"--command-mix", config.CommandMixFile},
+		{"--plugin", config.PluginPath},
+		{"--vector-file", config.VectorFile},
+		{"--config-diff", config.ConfigDiffFile},
+	}
+	for _, input := range inputs {
+		if input.path == "" {
+			continue
+		}
+		sum, err := hashFile(input.path)
+		if err != nil {
+			return fmt.Errorf("failed to hash %s file: %v", input.option, err)
+		}
+		manifest.Files = append(manifest.Files, ManifestFile{Option: input.option, Path: input.path, SHA256: sum})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %v", err)
+	}
+	return nil
+}
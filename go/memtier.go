@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// memtierRow is one line of the memtier_benchmark ALL STATS table
+type memtierRow struct {
+	name   string
+	ops    int64
+	hits   int64 // -1 when the command has no hits and misses
+	misses int64
+	bytes  int64 // -1 when not known per command
+	stats  *LatencyStats
+}
+
+// AddLookup counts a successful GET as a hit or a miss
+func (s *BenchmarkStats) AddLookup(miss bool) {
+	if miss {
+		atomic.AddInt64(&s.misses, 1)
+	} else {
+		atomic.AddInt64(&s.hits, 1)
+	}
+}
+
+// memtierName names the row of a command like memtier_benchmark does ("Sets", "Gets")
+func memtierName(command string) string {
+	switch command {
+	case "set":
+		return "Sets"
+	case "get":
+		return "Gets"
+	}
+	return strings.ToUpper(command[:1]) + command[1:]
+}
+
+// printMemtierStats prints the final results as the ALL STATS table of
+// memtier_benchmark, so numbers can be compared with historical memtier runs:
+// Sets, Gets and Waits rows (plus a row for any other command) and the
+// Totals, with the rates per second, memtier's default p50, p99 and p99.9
+// latency percentiles and the throughput in KB/sec. With --ratio the Sets and
+// Gets rows come from the per-command statistics.
+func (s *BenchmarkStats) printMemtierStats(totalTime float64, stats *LatencyStats, pathStats []*LatencyStats) {
+	active := memtierRow{
+		name:   memtierName(s.config.Command),
+		ops:    s.requestsCompleted,
+		hits:   -1,
+		misses: -1,
+		bytes:  atomic.LoadInt64(&s.bytesSent) + atomic.LoadInt64(&s.bytesReceived),
+		stats:  stats,
+	}
+	if s.config.Command == "get" || s.config.Ratio != "" {
+		active.hits, active.misses = atomic.LoadInt64(&s.hits), atomic.LoadInt64(&s.misses)
+	}
+
+	rows := []memtierRow{{name: "Sets", hits: -1}, {name: "Gets"}}
+	switch {
+	case s.config.Ratio != "":
+		for i, path := range s.pathOrder {
+			row := memtierRow{name: memtierName(strings.ToLower(path)), ops: s.pathRequests(path),
+				hits: -1, misses: -1, bytes: -1, stats: pathStats[i]}
+			if path == "GET" {
+				row.hits, row.misses = active.hits, active.misses
+				rows[1] = row
+			} else {
+				rows[0] = row
+			}
+		}
+	case active.name == "Sets":
+		rows[0] = active
+	case active.name == "Gets":
+		rows[1] = active
+	default:
+		rows = append(rows, active)
+	}
+	rows = append(rows, memtierRow{name: "Waits", hits: -1})
+	totals := active
+	totals.name = "Totals"
+	if totals.hits < 0 {
+		totals.hits, totals.misses = 0, 0
+	}
+	rows = append(rows, totals)
+
+	line := strings.Repeat("=", 124)
+	fmt.Printf("\nALL STATS\n%s\n", line)
+	fmt.Printf("%-6s %12s %12s %12s %15s %15s %15s %15s %12s \n", "Type", "Ops/sec", "Hits/sec", "Misses/sec",
+		"Avg. Latency", "p50 Latency", "p99 Latency", "p99.9 Latency", "KB/sec")
+	fmt.Println(strings.Repeat("-", 124))
+	for _, row := range rows {
+		hits, misses := "---", "---"
+		if row.hits >= 0 {
+			hits = fmt.Sprintf("%.2f", float64(row.hits)/totalTime)
+			misses = fmt.Sprintf("%.2f", float64(row.misses)/totalTime)
+		}
+		avg, p50, p99, p999 := "---", "---", "---", "---"
+		if row.stats != nil && row.ops > 0 {
+			avg = fmt.Sprintf("%.5f", row.stats.avg)
+			p50 = fmt.Sprintf("%.5f", row.stats.p50)
+			p99 = fmt.Sprintf("%.5f", row.stats.p99)
+			p999 = fmt.Sprintf("%.5f", row.stats.p999)
+		}
+		kb := "---"
+		if row.bytes >= 0 {
+			kb = fmt.Sprintf("%.2f", float64(row.bytes)/1024/totalTime)
+		}
+		fmt.Printf("%-6s %12.2f %12s %12s %15s %15s %15s %15s %12s \n", row.name, float64(row.ops)/totalTime,
+			hits, misses, avg, p50, p99, p999, kb)
+	}
+}
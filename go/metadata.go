@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// glideModule is the module path of the client library
+const glideModule = "github.com/valkey-io/valkey-glide/go"
+
+// RunMetadata describes the environment of a run. It is embedded in the
+// result documents so historical results remain interpretable.
+type RunMetadata struct {
+	RunID            string                 `json:"run_id"`
+	Server           string                 `json:"server,omitempty"`
+	ServerVersion    string                 `json:"server_version,omitempty"`
+	Role             string                 `json:"role,omitempty"`
+	ClusterPrimaries int                    `json:"cluster_primaries,omitempty"`
+	ClusterReplicas  int                    `json:"cluster_replicas,omitempty"`
+	Modules          []string               `json:"modules,omitempty"`
+	BenchmarkVersion string                 `json:"benchmark_version"`
+	ClientLibrary    string                 `json:"client_library"`
+	GoVersion        string                 `json:"go_version"`
+	OS               string                 `json:"os"`
+	Arch             string                 `json:"arch"`
+	CPUs             int                    `json:"cpus"`
+	Hostname         string                 `json:"hostname,omitempty"`
+	Config           map[string]interface{} `json:"config"`
+	ServerConfig     map[string]string      `json:"server_config,omitempty"` // CONFIG GET * with --config-snapshot or --config-diff
+}
+
+// collectMetadata gathers the run metadata. Server details that can't be
+// queried, e.g. because of ACLs, are left empty.
+func (b *Benchmark) collectMetadata() *RunMetadata {
+	meta := &RunMetadata{
+		RunID:         b.config.RunID,
+		ClientLibrary: glideModule,
+		GoVersion:     runtime.Version(),
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		CPUs:          runtime.NumCPU(),
+		Config:        redactedConfig(b.config),
+	}
+	meta.Hostname, _ = os.Hostname()
+	meta.BenchmarkVersion, _ = buildVersion()
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == glideModule {
+				meta.ClientLibrary += " " + dep.Version
+			}
+		}
+	}
+
+	if b.config.ProxyMode {
+		// The server behind a proxy can't be inspected
+		meta.Role = "proxy"
+		return meta
+	}
+
+	client := b.poolClient(false, 0)
+	caps := b.capabilities
+	if caps == nil {
+		caps, _ = detectCapabilities(client, nil)
+	}
+	if caps != nil {
+		meta.Server, meta.ServerVersion, meta.Modules = caps.Server, caps.Version, caps.Modules
+	}
+
+	if b.config.IsCluster {
+		meta.Role = "cluster"
+		if nodes, err := executeCommand(client, []string{"CLUSTER", "NODES"}); err == nil {
+			for _, line := range strings.Split(firstString(nodes), "\n") {
+				fields := strings.Fields(line)
+				if len(fields) < 3 {
+					continue
+				}
+				if strings.Contains(fields[2], "master") {
+					meta.ClusterPrimaries++
+				} else if strings.Contains(fields[2], "slave") {
+					meta.ClusterReplicas++
+				}
+			}
+		}
+	} else if info, err := executeCommand(client, []string{"INFO", "replication"}); err == nil {
+		meta.Role = infoFields(firstString(info))["role"]
+	}
+	return meta
+}
+
+// infoFields parses the key:value lines of an INFO reply
+func infoFields(info string) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(info, "\n") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(line), ":"); ok {
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+// secretFlag reports whether a flag carries a password, token or header credentials
+func secretFlag(name string) bool {
+	return name == "a" || name == "otel-headers" || strings.HasSuffix(name, "token")
+}
+
+// redactedArgs returns the command line with the values of password and token
+// flags replaced, in both the "-a value" and "-a=value" forms
+func redactedArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+	for i := 0; i < len(redacted); i++ {
+		name := strings.TrimLeft(redacted[i], "-")
+		if name == redacted[i] || name == "" {
+			continue
+		}
+		if flagName, _, ok := strings.Cut(name, "="); ok {
+			if secretFlag(flagName) {
+				redacted[i] = redacted[i][:len(redacted[i])-len(name)] + flagName + "=<redacted>"
+			}
+		} else if secretFlag(name) && i+1 < len(redacted) {
+			redacted[i+1] = "<redacted>"
+			i++
+		}
+	}
+	return redacted
+}
+
+// redactedConfig returns the resolved configuration keyed by field name, with
+// tokens and passwords removed and credentials stripped from URLs
+func redactedConfig(config *Config) map[string]interface{} {
+	fields := make(map[string]interface{})
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fields
+	}
+	json.Unmarshal(data, &fields)
+
+	for key, value := range fields {
+		text, ok := value.(string)
+		if !ok || text == "" {
+			continue
+		}
+		if strings.HasSuffix(key, "Token") || key == "Password" || key == "OTelHeaders" {
+			fields[key] = "<redacted>"
+		} else if u, err := url.Parse(text); err == nil && u.User != nil {
+			u.User = url.User("redacted")
+			fields[key] = u.String()
+		}
+	}
+	return fields
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CommandMixEntry is one weighted command template of a command mix
+type CommandMixEntry struct {
+	Label    string
+	Weight   float64
+	Template *CommandTemplate
+}
+
+// CommandMix is a set of weighted command templates sampled per request
+type CommandMix struct {
+	Entries    []CommandMixEntry
+	cumulative []float64 // Cumulative weights for sampling
+}
+
+// LoadCommandMix reads a command mix file. Each non-empty line that doesn't
+// start with '#' has the form "<weight> <command template>", e.g.:
+//
+//	70 GET {{prefix}}:{{rand:0:99999}}
+//	20 SET {{prefix}}:{{rand:0:99999}} {{data}}
+//	10 HSET {{prefix}}:hash:{{rand:0:999}} f{{counter}} {{data}}
+func LoadCommandMix(path string, config *Config) (*CommandMix, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open command mix file: %v", err)
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read command mix file: %v", err)
+	}
+	return ParseCommandMix(lines, config)
+}
+
+// ParseCommandMix builds a command mix from "<weight> <command template>" lines
+func ParseCommandMix(lines []string, config *Config) (*CommandMix, error) {
+	mix := &CommandMix{}
+	labels := make(map[string]int)
+	var total float64
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		weightStr, source, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("command mix line %d: expected \"<weight> <command>\"", i+1)
+		}
+		weight, err := strconv.ParseFloat(weightStr, 64)
+		if err != nil || weight <= 0 {
+			return nil, fmt.Errorf("command mix line %d: invalid weight %q", i+1, weightStr)
+		}
+		template, err := ParseCommandTemplate(strings.TrimSpace(source), config)
+		if err != nil {
+			return nil, fmt.Errorf("command mix line %d: %v", i+1, err)
+		}
+
+		// Label entries by command name, numbering repeated commands
+		label := template.name
+		labels[label]++
+		if labels[label] > 1 {
+			label = fmt.Sprintf("%s#%d", label, labels[label])
+		}
+
+		total += weight
+		mix.Entries = append(mix.Entries, CommandMixEntry{Label: label, Weight: weight, Template: template})
+		mix.cumulative = append(mix.cumulative, total)
+	}
+	if len(mix.Entries) == 0 {
+		return nil, fmt.Errorf("command mix is empty")
+	}
+	return mix, nil
+}
+
+// Filter returns a mix with the entries for which keep returns true, and the
+// removed entries. The weights of the kept entries are preserved.
+func (m *CommandMix) Filter(keep func(entry *CommandMixEntry) bool) (*CommandMix, []CommandMixEntry) {
+	filtered := &CommandMix{}
+	var removed []CommandMixEntry
+	var total float64
+	for i := range m.Entries {
+		entry := m.Entries[i]
+		if !keep(&entry) {
+			removed = append(removed, entry)
+			continue
+		}
+		total += entry.Weight
+		filtered.Entries = append(filtered.Entries, entry)
+		filtered.cumulative = append(filtered.cumulative, total)
+	}
+	return filtered, removed
+}
+
+// Sample picks an entry according to the weights
+func (m *CommandMix) Sample(rng *rand.Rand) *CommandMixEntry {
+	total := m.cumulative[len(m.cumulative)-1]
+	idx := sort.SearchFloat64s(m.cumulative, rng.Float64()*total)
+	if idx >= len(m.Entries) {
+		idx = len(m.Entries) - 1
+	}
+	return &m.Entries[idx]
+}
+
+// mixCommand is a CustomCommand sampling a command template from a mix per request
+type mixCommand struct {
+	mix   *CommandMix
+	ctx   templateContext
+	entry *CommandMixEntry
+	next  []string
+	reply interface{}
+}
+
+// newMixCommandFactory returns a factory creating command mix custom commands
+func newMixCommandFactory(mix *CommandMix) CustomCommandFactory {
+	return func() CustomCommand {
+		return &mixCommand{mix: mix}
+	}
+}
+
+func (c *mixCommand) Setup(client interface{}, workerID int, args string) error {
+	c.ctx = templateContext{
+		threadID: workerID,
+		rng:      rand.New(rand.NewSource(benchRand.Int63())),
+	}
+	return nil
+}
+
+// Prepare samples and renders the next request outside of the timed section
+func (c *mixCommand) Prepare() error {
+	c.entry = c.mix.Sample(c.ctx.rng)
+	c.next = c.entry.Template.Render(&c.ctx)
+	c.ctx.counter++
+	return nil
+}
+
+// Label reports the mix entry of the prepared request for per-template statistics
+func (c *mixCommand) Label() string {
+	return c.entry.Label
+}
+
+// Key returns the first argument of the prepared request, which is the key of most commands
+func (c *mixCommand) Key() string {
+	if len(c.next) < 2 {
+		return ""
+	}
+	return c.next[1]
+}
+
+// CommandName returns the command of the prepared request
+func (c *mixCommand) CommandName() string {
+	return commandName(c.next)
+}
+
+func (c *mixCommand) Execute(client interface{}) error {
+	var err error
+	c.reply, err = executeCommand(client, c.next)
+	return err
+}
+
+// TransferredBytes approximates the encoded size of the last request and its reply
+func (c *mixCommand) TransferredBytes() (sent, received int64) {
+	return respCommandSize(c.next...), respReplySize(c.reply)
+}
+
+func (c *mixCommand) Teardown(client interface{}) error {
+	return nil
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// moduleWorkloads maps -t commands of server modules to their command templates
+var moduleWorkloads = map[string]func(config *Config) (*CommandTemplate, error){
+	"json.set":       jsonSetTemplate,
+	"json.get":       jsonGetTemplate,
+	"json.arrappend": jsonArrAppendTemplate,
+	"ft.search":      ftSearchTemplate,
+	"ft.knn":         vectorSearchTemplate,
+	"bf.add":         bloomAddTemplate,
+	"bf.exists":      bloomExistsTemplate,
+}
+
+// isCustomWorkload reports whether a -t command runs through the custom command interface
+func isCustomWorkload(command string) bool {
+	_, module := moduleWorkloads[command]
+	_, pagination := paginationCommands[command]
+	return module || pagination || command == "churn" || command == "expiry" || command == "custom"
+}
+
+// moduleKey returns the key expression for a module workload: random keys of
+// the -r keyspace, sequential keys of the -sequential keyspace, or new keys
+func moduleKey(config *Config, kind string) string {
+	switch {
+	case config.RandomKeyspace > 0:
+		return fmt.Sprintf("{{prefix}}:%s:{{rand:%d:%d}}", kind, config.KeyspaceOffset,
+			config.KeyspaceOffset+config.RandomKeyspace-1)
+	case config.SequentialKeyLen > 0:
+		return fmt.Sprintf("{{prefix}}:%s:{{seq:%d:%d}}", kind, config.SequentialKeyLen, config.KeyspaceOffset)
+	}
+	return fmt.Sprintf("{{prefix}}:%s:{{gcounter}}", kind)
+}
+
+// newModuleTemplate compiles the arguments of a module workload command
+func newModuleTemplate(config *Config, words ...string) (*CommandTemplate, error) {
+	return newCommandTemplate(strings.Join(words, " "), words, config)
+}
+
+// prepareWorkload runs the one-time preparation a built-in workload needs
+// before the benchmark, such as creating a search index
+func (b *Benchmark) prepareWorkload(ctx context.Context) error {
+	switch b.config.Command {
+	case "ft.search":
+		return b.prepareSearchIndex(ctx)
+	case "ft.knn":
+		return b.prepareVectorIndex(ctx)
+	case "bf.add", "bf.exists":
+		return b.reserveBloomFilters()
+	case "lrange", "zrange":
+		return b.fillCollections(ctx)
+	case "get":
+		if b.config.ScanSample > 0 {
+			return b.sampleKeys()
+		}
+	}
+	return nil
+}
+
+// defaultJSONDocument is written by json.set without --json-doc
+const defaultJSONDocument = `{"id":{{gcounter}},"name":"{{data}}","score":{{rand:0:1000}},"tags":["a","b"],"nested":{"level":{{rand:1:5}}}}`
+
+func jsonSetTemplate(config *Config) (*CommandTemplate, error) {
+	doc := config.JSONDocument
+	if doc == "" {
+		doc = defaultJSONDocument
+	}
+	return newModuleTemplate(config, "JSON.SET", moduleKey(config, "json"), "$", doc)
+}
+
+func jsonGetTemplate(config *Config) (*CommandTemplate, error) {
+	path := config.JSONPath
+	if path == "" {
+		path = "$"
+	}
+	return newModuleTemplate(config, "JSON.GET", moduleKey(config, "json"), path)
+}
+
+func jsonArrAppendTemplate(config *Config) (*CommandTemplate, error) {
+	path := config.JSONPath
+	if path == "" {
+		path = "$.tags"
+	}
+	value := config.JSONValue
+	if value == "" {
+		value = `"{{data}}"`
+	}
+	return newModuleTemplate(config, "JSON.ARRAPPEND", moduleKey(config, "json"), path, value)
+}
+
+// Defaults of the search workload
+const (
+	defaultSearchSchema   = "title TEXT tag TAG score NUMERIC"
+	defaultSearchDocument = "title {{choice:alpha,beta,gamma,delta,epsilon}} tag {{choice:red,green,blue}} score {{rand:0:1000}} body {{data}}"
+	defaultSearchQuery    = "@title:{{choice:alpha,beta,gamma,delta,epsilon}}"
+)
+
+// searchIndexName returns the FT index name, by default derived from the key prefix
+func searchIndexName(config *Config) string {
+	if config.SearchIndex != "" {
+		return config.SearchIndex
+	}
+	if config.Command == "ft.knn" {
+		return keyPrefix(config) + ":vidx"
+	}
+	return keyPrefix(config) + ":idx"
+}
+
+func ftSearchTemplate(config *Config) (*CommandTemplate, error) {
+	query := config.SearchQuery
+	if query == "" {
+		query = defaultSearchQuery
+	}
+	return newModuleTemplate(config, "FT.SEARCH", searchIndexName(config), query,
+		"LIMIT", "0", fmt.Sprint(config.SearchLimit))
+}
+
+// vectorSearchTemplate issues K nearest neighbour queries for a random vector
+func vectorSearchTemplate(config *Config) (*CommandTemplate, error) {
+	query := fmt.Sprintf("*=>[KNN %d @embedding $vec]", config.VectorK)
+	return newModuleTemplate(config, "FT.SEARCH", searchIndexName(config), query,
+		"PARAMS", "2", "vec", "{{vector}}", "DIALECT", "2")
+}
+
+// prepareSearchIndex creates the FT index over hashes and loads the documents.
+// The write latency of the documents is reported separately from the queries.
+func (b *Benchmark) prepareSearchIndex(ctx context.Context) error {
+	schema := b.config.SearchSchema
+	if schema == "" {
+		schema = defaultSearchSchema
+	}
+	if err := b.createSearchIndex(":doc:", strings.Fields(schema)); err != nil {
+		return err
+	}
+
+	document := b.config.SearchDocument
+	if document == "" {
+		document = defaultSearchDocument
+	}
+	source := fmt.Sprintf("HSET {{prefix}}:doc:{{seq:%d}} %s", b.config.SearchDocuments, document)
+	return b.indexDocuments(ctx, source, b.config.SearchDocuments)
+}
+
+// prepareVectorIndex creates the FT index with a vector field and loads the vectors
+func (b *Benchmark) prepareVectorIndex(ctx context.Context) error {
+	config := b.config
+	schema := []string{"embedding", "VECTOR", strings.ToUpper(config.VectorAlgorithm), "6",
+		"TYPE", "FLOAT32", "DIM", strconv.Itoa(config.VectorDim),
+		"DISTANCE_METRIC", strings.ToUpper(config.VectorMetric)}
+	if err := b.createSearchIndex(":vec:", schema); err != nil {
+		return err
+	}
+	source := fmt.Sprintf("HSET {{prefix}}:vec:{{seq:%d}} embedding {{vector:next}}", config.VectorDocuments)
+	return b.indexDocuments(ctx, source, config.VectorDocuments)
+}
+
+// createSearchIndex creates the FT index over the hashes under the key prefix
+// followed by the given suffix. An existing index is reused.
+func (b *Benchmark) createSearchIndex(suffix string, schema []string) error {
+	index := searchIndexName(b.config)
+	docPrefix := keyPrefix(b.config) + suffix
+
+	args := []string{"FT.CREATE", index, "ON", "HASH", "PREFIX", "1", docPrefix, "SCHEMA"}
+	args = append(args, schema...)
+	if _, err := executeCommand(b.poolClient(false, 0), args); err != nil {
+		if !strings.Contains(strings.ToLower(err.Error()), "already exists") {
+			return fmt.Errorf("failed to create search index %s: %v", index, err)
+		}
+		fmt.Printf("Search index %s already exists, reusing it\n", index)
+	} else {
+		fmt.Printf("Created search index %s on %s*\n", index, docPrefix)
+	}
+	return nil
+}
+
+// indexDocuments writes the documents of a search workload as a separate
+// stage and reports its write latency before the queries start
+func (b *Benchmark) indexDocuments(ctx context.Context, source string, count int64) error {
+	if count <= 0 {
+		return nil
+	}
+	template, err := ParseCommandTemplate(source, b.config)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nIndexing %d documents (HSET write latency):\n", count)
+	stats := b.runCommandStage(ctx, count, func(*Config) CustomCommandFactory {
+		return newTemplateCommandFactory(template)
+	})
+	stats.PrintFinalStats()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	fmt.Printf("\nQuery benchmark (FT.SEARCH):\n")
+	return nil
+}
+
+// bloomFilterKey returns the key of the n-th Bloom filter
+func bloomFilterKey(config *Config, n int) string {
+	return fmt.Sprintf("%s:bf:%d", keyPrefix(config), n)
+}
+
+// bloomItem returns the item expression of the Bloom workloads. By default
+// items are drawn from a range as large as the filter capacity, so bf.add
+// fills the filters up to capacity and bf.exists sees hits and misses.
+func bloomItem(config *Config) string {
+	if config.BloomItem != "" {
+		return config.BloomItem
+	}
+	return fmt.Sprintf("item:{{rand:0:%d}}", config.BloomCapacity-1)
+}
+
+func bloomAddTemplate(config *Config) (*CommandTemplate, error) {
+	key := fmt.Sprintf("{{prefix}}:bf:{{rand:0:%d}}", config.BloomFilters-1)
+	return newModuleTemplate(config, "BF.ADD", key, bloomItem(config))
+}
+
+func bloomExistsTemplate(config *Config) (*CommandTemplate, error) {
+	key := fmt.Sprintf("{{prefix}}:bf:{{rand:0:%d}}", config.BloomFilters-1)
+	return newModuleTemplate(config, "BF.EXISTS", key, bloomItem(config))
+}
+
+// reserveBloomFilters creates the filters with the configured capacity and
+// error rate. Filters left by an earlier run keep their original parameters.
+func (b *Benchmark) reserveBloomFilters() error {
+	config := b.config
+	errorRate := strconv.FormatFloat(config.BloomErrorRate, 'g', -1, 64)
+	capacity := strconv.FormatInt(config.BloomCapacity, 10)
+	existing := 0
+	for n := 0; n < config.BloomFilters; n++ {
+		key := bloomFilterKey(config, n)
+		args := []string{"BF.RESERVE", key, errorRate, capacity}
+		if _, err := executeCommand(b.poolClient(false, 0), args); err != nil {
+			if !strings.Contains(strings.ToLower(err.Error()), "exists") {
+				return fmt.Errorf("failed to reserve Bloom filter %s: %v", key, err)
+			}
+			existing++
+		}
+	}
+	fmt.Printf("Reserved %d Bloom filters (capacity %s, error rate %s)", config.BloomFilters-existing, capacity, errorRate)
+	if existing > 0 {
+		fmt.Printf(", reusing %d existing", existing)
+	}
+	fmt.Println()
+	return nil
+}
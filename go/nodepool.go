@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/valkey-io/valkey-glide/go/api"
+)
+
+// NodePools holds a separately sized pool of standalone connections to every
+// primary of a cluster. Keys of the built-in set and get workloads are routed
+// to the pool of the primary owning their slot, using the slot map read at
+// startup, so every node gets the connections it needs instead of one -c
+// value for all of them.
+type NodePools struct {
+	nodes    []string
+	pools    [][]interface{}
+	slotNode [clusterSlots]int32 // Index of the node owning a slot (-1 if unassigned)
+	next     []int64             // Round-robin position in every pool
+	requests []int64             // Requests sent to every node
+	busy     []int64             // Nanoseconds spent in requests on every node
+	first    int64               // Unix nanoseconds of the first request
+	last     int64               // Unix nanoseconds at which the latest request completed
+}
+
+// parseNodePoolSpec parses --node-pool: a connection count for every node, or
+// a comma separated list of host:port=count entries where *=count sets the
+// count of unlisted nodes (default: -c)
+func parseNodePoolSpec(spec string, fallback int) (map[string]int, int, error) {
+	if n, err := strconv.Atoi(spec); err == nil {
+		if n < 1 {
+			return nil, 0, fmt.Errorf("node-pool must be at least 1")
+		}
+		return nil, n, nil
+	}
+	sizes := make(map[string]int)
+	for _, entry := range strings.Split(spec, ",") {
+		node, count, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		n, err := strconv.Atoi(count)
+		if !ok || err != nil || n < 1 || node == "" {
+			return nil, 0, fmt.Errorf("invalid node-pool entry %q (expected host:port=count)", entry)
+		}
+		if node == "*" {
+			fallback = n
+		} else {
+			sizes[node] = n
+		}
+	}
+	return sizes, fallback, nil
+}
+
+// newNodePools creates the per-node pools for the primaries reported by CLUSTER SLOTS
+func newNodePools(config *Config, client interface{}) (*NodePools, error) {
+	sizes, fallback, err := parseNodePoolSpec(config.NodePool, config.PoolSize)
+	if err != nil {
+		return nil, err
+	}
+	shards, err := shardSlots(client)
+	if err != nil {
+		return nil, err
+	}
+	for node := range sizes {
+		if _, ok := shards[node]; !ok {
+			return nil, fmt.Errorf("node-pool lists %s, which is not a primary of the cluster", node)
+		}
+	}
+
+	p := &NodePools{}
+	for slot := range p.slotNode {
+		p.slotNode[slot] = -1
+	}
+	for node := range shards {
+		p.nodes = append(p.nodes, node)
+	}
+	sort.Strings(p.nodes)
+	p.pools = make([][]interface{}, len(p.nodes))
+	p.next = make([]int64, len(p.nodes))
+	p.requests = make([]int64, len(p.nodes))
+	p.busy = make([]int64, len(p.nodes))
+
+	for i, node := range p.nodes {
+		for _, r := range shards[node] {
+			for slot := r[0]; slot <= r[1] && slot < clusterSlots; slot++ {
+				p.slotNode[slot] = int32(i)
+			}
+		}
+		host, port, err := nodeHostPort(node)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		nodeConfig := *config
+		nodeConfig.IsCluster = false
+		nodeConfig.Host, nodeConfig.Port = host, port
+		size := fallback
+		if n, ok := sizes[node]; ok {
+			size = n
+		}
+		nodeConfig.PoolSize = size
+		pool, err := createClientPool(&nodeConfig, api.Primary, fmt.Sprintf("n%d-", i))
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("failed to connect to %s: %v", node, err)
+		}
+		p.pools[i] = pool
+	}
+	return p, nil
+}
+
+// nodeHostPort splits a host:port node address, which may be an unbracketed IPv6 address
+func nodeHostPort(node string) (string, int, error) {
+	i := strings.LastIndexByte(node, ':')
+	if i < 0 {
+		return "", 0, fmt.Errorf("invalid node address %q", node)
+	}
+	port, err := strconv.Atoi(node[i+1:])
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid node address %q", node)
+	}
+	return node[:i], port, nil
+}
+
+// Acquire returns the connection for a key and the function to call when the
+// request completed. Without per-node pools, or for a slot the startup slot
+// map didn't assign, it returns fallback.
+func (p *NodePools) Acquire(fallback interface{}, key string) (interface{}, func()) {
+	if p == nil {
+		return fallback, func() {}
+	}
+	node := p.slotNode[keySlot(key)]
+	if node < 0 {
+		return fallback, func() {}
+	}
+	pool := p.pools[node]
+	client := pool[(atomic.AddInt64(&p.next[node], 1)-1)%int64(len(pool))]
+	start := time.Now()
+	atomic.CompareAndSwapInt64(&p.first, 0, start.UnixNano())
+	return client, func() {
+		end := time.Now()
+		atomic.AddInt64(&p.requests[node], 1)
+		atomic.AddInt64(&p.busy[node], int64(end.Sub(start)))
+		atomic.StoreInt64(&p.last, end.UnixNano())
+	}
+}
+
+// Close closes the connections of all nodes
+func (p *NodePools) Close() {
+	if p == nil {
+		return
+	}
+	for _, pool := range p.pools {
+		closeClients(pool)
+	}
+}
+
+// printNodePools reports the requests of every node and how busy its pool
+// was: by Little's law the average number of requests in flight is the time
+// spent in requests divided by the run time, and the utilization compares it
+// with the connections of the node
+func (b *Benchmark) printNodePools() {
+	p := b.nodePools
+	elapsed := time.Duration(atomic.LoadInt64(&p.last) - atomic.LoadInt64(&p.first))
+	if atomic.LoadInt64(&p.first) == 0 || elapsed <= 0 {
+		return
+	}
+	fmt.Printf("\nPer-Node Pools:\n")
+	fmt.Printf("===============\n")
+	fmt.Printf("%-25s %12s %14s %12s %12s\n", "Node", "Connections", "Requests", "In-flight", "Utilization")
+	for i, node := range p.nodes {
+		inflight := float64(atomic.LoadInt64(&p.busy[i])) / float64(elapsed)
+		fmt.Printf("%-25s %12d %14d %12.2f %11.1f%%\n", node, len(p.pools[i]),
+			atomic.LoadInt64(&p.requests[i]), inflight, 100*inflight/float64(len(p.pools[i])))
+	}
+}
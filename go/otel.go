@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// otelScope is the instrumentation scope of the exported metrics and spans
+const otelScope = "valkey-benchmark"
+
+// otelSpanBatch is the number of spans sent per request, and otelSpanQueue
+// the number of spans buffered before new ones are dropped
+const (
+	otelSpanBatch = 512
+	otelSpanQueue = 16384
+)
+
+// otelAttribute is an OTLP key-value attribute in the JSON encoding
+type otelAttribute struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+func otelString(key, value string) otelAttribute {
+	return otelAttribute{Key: key, Value: map[string]interface{}{"stringValue": value}}
+}
+
+// otelResource returns the resource attributes identifying the run
+func otelResource(config *Config) map[string]interface{} {
+	return map[string]interface{}{
+		"attributes": []otelAttribute{
+			otelString("service.name", otelScope),
+			otelString("benchmark.run_id", config.RunID),
+			otelString("benchmark.command", config.Command),
+			otelString("server.address", config.Host),
+			{Key: "server.port", Value: map[string]interface{}{"intValue": strconv.Itoa(config.Port)}},
+		},
+	}
+}
+
+// otelClient posts OTLP/HTTP JSON payloads to a collector
+type otelClient struct {
+	endpoint string
+	headers  [][2]string
+	client   *http.Client
+}
+
+// newOTelClient parses the endpoint and the "name=value,..." headers
+func newOTelClient(config *Config) (*otelClient, error) {
+	c := &otelClient{
+		endpoint: strings.TrimSuffix(config.OTelEndpoint, "/"),
+		client:   &http.Client{Timeout: 5 * time.Second},
+	}
+	if config.OTelHeaders != "" {
+		for _, pair := range strings.Split(config.OTelHeaders, ",") {
+			name, value, ok := strings.Cut(pair, "=")
+			if !ok || strings.TrimSpace(name) == "" {
+				return nil, fmt.Errorf("otel: invalid header %q, expected name=value", pair)
+			}
+			c.headers = append(c.headers, [2]string{strings.TrimSpace(name), strings.TrimSpace(value)})
+		}
+	}
+	return c, nil
+}
+
+// post sends a payload to the signal path, e.g. /v1/metrics
+func (c *otelClient) post(path string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("otel: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, c.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("otel: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for _, header := range c.headers {
+		req.Header.Set(header[0], header[1])
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("otel: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("otel: %s: %s", resp.Status, bytes.TrimSpace(text))
+	}
+	return nil
+}
+
+// OTelExporter sends interval metrics to an OpenTelemetry collector with
+// OTLP/HTTP in the JSON encoding: request and error counts as delta sums,
+// the rate and latency percentiles as gauges
+type OTelExporter struct {
+	client   *otelClient
+	resource map[string]interface{}
+	last     time.Time // End of the previous interval, the start of the next delta
+}
+
+// NewOTelExporter creates an exporter for the configured collector
+func NewOTelExporter(config *Config) (*OTelExporter, error) {
+	client, err := newOTelClient(config)
+	if err != nil {
+		return nil, err
+	}
+	return &OTelExporter{client: client, resource: otelResource(config), last: time.Now()}, nil
+}
+
+// metricsPayload renders one interval as an ExportMetricsServiceRequest
+func (e *OTelExporter) metricsPayload(interval IntervalStats) map[string]interface{} {
+	start := strconv.FormatInt(e.last.UnixNano(), 10)
+	now := strconv.FormatInt(interval.Time.UnixNano(), 10)
+	sum := func(name, unit string, value int64) map[string]interface{} {
+		return map[string]interface{}{
+			"name": name,
+			"unit": unit,
+			"sum": map[string]interface{}{
+				"dataPoints": []map[string]interface{}{
+					{"startTimeUnixNano": start, "timeUnixNano": now, "asInt": strconv.FormatInt(value, 10)},
+				},
+				"aggregationTemporality": 1, // Delta
+				"isMonotonic":            true,
+			},
+		}
+	}
+	gauge := func(name, unit string, value float64) map[string]interface{} {
+		return map[string]interface{}{
+			"name": name,
+			"unit": unit,
+			"gauge": map[string]interface{}{
+				"dataPoints": []map[string]interface{}{{"timeUnixNano": now, "asDouble": value}},
+			},
+		}
+	}
+	return map[string]interface{}{
+		"resourceMetrics": []map[string]interface{}{{
+			"resource": e.resource,
+			"scopeMetrics": []map[string]interface{}{{
+				"scope": map[string]interface{}{"name": otelScope},
+				"metrics": []map[string]interface{}{
+					sum("valkey_benchmark.requests", "{request}", interval.Requests),
+					sum("valkey_benchmark.errors", "{request}", interval.Errors),
+					gauge("valkey_benchmark.rps", "{request}/s", interval.RPS),
+					gauge("valkey_benchmark.latency.p50", "ms", interval.P50),
+					gauge("valkey_benchmark.latency.p95", "ms", interval.P95),
+					gauge("valkey_benchmark.latency.p99", "ms", interval.P99),
+					gauge("valkey_benchmark.latency.max", "ms", interval.Max),
+				},
+			}},
+		}},
+	}
+}
+
+// ExportInterval posts the interval to /v1/metrics
+func (e *OTelExporter) ExportInterval(interval IntervalStats) error {
+	payload := e.metricsPayload(interval)
+	e.last = interval.Time
+	return e.client.post("/v1/metrics", payload)
+}
+
+// Close releases nothing; metrics are sent synchronously
+func (e *OTelExporter) Close() error {
+	return nil
+}
+
+// otelSpan is a sampled request
+type otelSpan struct {
+	name  string
+	start time.Time
+	end   time.Time
+	err   error
+}
+
+// OTelTracer exports a sampled fraction of the requests as client spans to
+// /v1/traces. Workers only enqueue spans; a background goroutine sends them in
+// batches, and spans are dropped rather than slowing the workers down when
+// the collector falls behind.
+type OTelTracer struct {
+	client   *otelClient
+	resource map[string]interface{}
+	sample   float64
+	spans    chan otelSpan
+	dropped  int64
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewOTelTracer starts exporting spans of the configured fraction of requests
+func NewOTelTracer(config *Config) (*OTelTracer, error) {
+	client, err := newOTelClient(config)
+	if err != nil {
+		return nil, err
+	}
+	t := &OTelTracer{
+		client:   client,
+		resource: otelResource(config),
+		sample:   config.OTelTraceSample,
+		spans:    make(chan otelSpan, otelSpanQueue),
+		done:     make(chan struct{}),
+	}
+	go t.run()
+	return t, nil
+}
+
+// Record samples a request that started at start and took latency milliseconds
+func (t *OTelTracer) Record(name string, start time.Time, latency float64, err error) {
+	if rand.Float64() >= t.sample {
+		return
+	}
+	span := otelSpan{name: name, start: start, end: start.Add(time.Duration(latency * float64(time.Millisecond))), err: err}
+	select {
+	case t.spans <- span:
+	default:
+		atomic.AddInt64(&t.dropped, 1)
+	}
+}
+
+func (t *OTelTracer) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	var batch []otelSpan
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.client.post("/v1/traces", t.tracesPayload(batch)); err != nil {
+			slog.Warn("span export failed", "spans", len(batch), "error", err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case span, ok := <-t.spans:
+			if !ok {
+				flush()
+				return
+			}
+			if batch = append(batch, span); len(batch) >= otelSpanBatch {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// tracesPayload renders spans as an ExportTraceServiceRequest. Every request
+// is the root span of its own trace.
+func (t *OTelTracer) tracesPayload(batch []otelSpan) map[string]interface{} {
+	spans := make([]map[string]interface{}, len(batch))
+	for i, span := range batch {
+		id := make([]byte, 24)
+		rand.Read(id)
+		status := map[string]interface{}{"code": 1} // Ok
+		if span.err != nil {
+			status = map[string]interface{}{"code": 2, "message": span.err.Error()} // Error
+		}
+		spans[i] = map[string]interface{}{
+			"traceId":           hex.EncodeToString(id[:16]),
+			"spanId":            hex.EncodeToString(id[16:]),
+			"name":              span.name,
+			"kind":              3, // Client
+			"startTimeUnixNano": strconv.FormatInt(span.start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(span.end.UnixNano(), 10),
+			"attributes": []otelAttribute{
+				otelString("db.system", "valkey"),
+				otelString("db.operation.name", span.name),
+			},
+			"status": status,
+		}
+	}
+	return map[string]interface{}{
+		"resourceSpans": []map[string]interface{}{{
+			"resource": t.resource,
+			"scopeSpans": []map[string]interface{}{{
+				"scope": map[string]interface{}{"name": otelScope},
+				"spans": spans,
+			}},
+		}},
+	}
+}
+
+// Close sends the buffered spans and reports the spans dropped while the
+// collector fell behind
+func (t *OTelTracer) Close() {
+	t.once.Do(func() {
+		close(t.spans)
+		<-t.done
+		if dropped := atomic.LoadInt64(&t.dropped); dropped > 0 {
+			slog.Warn("spans dropped because the collector fell behind", "dropped", dropped)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// pacerSlack is the lag behind the schedule a worker always makes up, since
+// sleeps overshoot by tens of microseconds: without it a worker could not
+// pace more requests per second than it can wake up
+const pacerSlack = 5 * time.Millisecond
+
+// WorkerPacer paces one worker at its share of the QPS target. Every worker
+// keeps its own token bucket (or Poisson schedule), so pacing takes no lock
+// shared with the other workers and scales with the thread count; the shares
+// add up to the target.
+type WorkerPacer struct {
+	qps      *QPSController
+	threadID int
+	tokens   float64    // Requests the worker may send without waiting
+	last     time.Time  // Time up to which tokens were refilled (zero before the first request)
+	next     time.Time  // Scheduled time of the next Poisson arrival
+	rng      *rand.Rand // Source of pacing jitter and Poisson gaps
+}
+
+// Pacer returns the pacer of a worker
+func (qps *QPSController) Pacer(threadID int) *WorkerPacer {
+	return &WorkerPacer{qps: qps, threadID: threadID, rng: rand.New(rand.NewSource(benchRand.Int63()))}
+}
+
+// workerRate advances the ramp and returns the requests per second paced by
+// each worker still running at the given time (0 if unlimited)
+func (qps *QPSController) workerRate(now time.Time) float64 {
+	qps.step(now)
+	return qps.currentWorkerRate(now)
+}
+
+// currentWorkerRate returns the per-worker rate of the current target without
+// advancing the ramp. During a ramp-down the scaled target is split across the
+// workers that have not retired yet.
+func (qps *QPSController) currentWorkerRate(now time.Time) float64 {
+	target := qps.target(now)
+	if target <= 0 {
+		return 0
+	}
+	workers := float64(qps.config.NumThreads)
+	if qps.rampDown != nil {
+		factor := qps.rampDown.Factor(now)
+		target = math.Max(target*factor, 1)
+		workers = math.Max(math.Ceil(factor*workers), 1)
+	}
+	return target / workers
+}
+
+// Throttle waits for the worker's next slot at the target rate. It returns
+// false if the context was done while waiting.
+func (p *WorkerPacer) Throttle(ctx context.Context) bool {
+	now := time.Now()
+	rate := p.qps.workerRate(now)
+	if rate <= 0 {
+		return true
+	}
+	interval := time.Duration(float64(time.Second) / rate)
+	stagger := float64(p.threadID) / float64(p.qps.config.NumThreads)
+
+	// Open-loop arrivals: exponentially distributed gaps at the target rate.
+	// The schedule is not reset when the worker falls behind, so missed
+	// arrivals are issued back to back like requests queueing at the server.
+	// The workers' independent Poisson processes add up to one at the target.
+	if p.qps.config.Arrival == "poisson" {
+		if p.next.IsZero() {
+			// Stagger the first arrivals so the workers don't fire in lockstep
+			p.next = now.Add(time.Duration(stagger * float64(interval)))
+		}
+		slot := p.next
+		p.next = p.next.Add(time.Duration(p.rng.ExpFloat64() * float64(interval)))
+		return !now.Before(slot) || p.qps.wait(ctx, slot.Sub(now))
+	}
+
+	// Token bucket refilled continuously at the worker's rate. The bucket
+	// holds at most the worker's share of --qps-burst tokens (or the slack),
+	// so a worker that fell behind catches up with at most that many
+	// back-to-back requests instead of bursting through the missed slots.
+	capacity := math.Max(float64(p.qps.config.QPSBurst)/float64(p.qps.config.NumThreads), 1)
+	capacity = math.Max(capacity, rate*pacerSlack.Seconds())
+	if p.last.IsZero() {
+		// Stagger the first requests so the workers don't fire in lockstep
+		p.tokens, p.last = 1-stagger, now
+	}
+	if now.After(p.last) {
+		p.tokens = math.Min(p.tokens+now.Sub(p.last).Seconds()*rate, capacity)
+		p.last = now
+	}
+	if p.tokens >= 1 {
+		p.tokens--
+		return true
+	}
+
+	// Wait for the next token. The bucket counts as refilled up to the time
+	// the token becomes available, so the wait isn't credited twice.
+	ready := p.last.Add(time.Duration((1 - p.tokens) * float64(interval)))
+	wait := ready.Sub(now)
+	p.tokens, p.last = 0, ready
+
+	// Randomize the slot by up to ±PacingJitter percent of the interval so
+	// many workers don't fire in lockstep
+	if p.qps.config.PacingJitter > 0 {
+		jitter := (p.rng.Float64()*2 - 1) * p.qps.config.PacingJitter / 100
+		wait += time.Duration(jitter * float64(interval))
+	}
+
+	return wait <= 0 || p.qps.wait(ctx, wait)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+)
+
+// paginationCommands maps the -t pagination workloads to the command they issue
+var paginationCommands = map[string]string{
+	"lrange": "LRANGE",
+	"zrange": "ZRANGE",
+}
+
+// paginationBands is the number of offset ranges latency is reported for
+const paginationBands = 10
+
+// paginationFillBatch is the number of elements written per fill request
+const paginationFillBatch = 100
+
+// collectionKey returns the key of the n-th collection of a pagination workload
+func collectionKey(config *Config, n int) string {
+	kind := "list"
+	if config.Command == "zrange" {
+		kind = "zset"
+	}
+	return fmt.Sprintf("%s:%s:%d", keyPrefix(config), kind, n)
+}
+
+// paginationCommand pages through a collection with windows of --page-size
+// elements, advancing the offset by --page-stride and wrapping at the end
+type paginationCommand struct {
+	config   *Config
+	name     string
+	key      string
+	offset   int64
+	stride   int64
+	bandSize int64
+	next     []string
+	reply    interface{}
+}
+
+// newPaginationFactory returns a factory for the -t lrange and zrange workloads
+func newPaginationFactory(config *Config) CustomCommandFactory {
+	stride := config.PageStride
+	if stride <= 0 {
+		stride = config.PageSize
+	}
+	bandSize := (config.CollectionSize + paginationBands - 1) / paginationBands
+	return func() CustomCommand {
+		return &paginationCommand{
+			config:   config,
+			name:     paginationCommands[config.Command],
+			stride:   stride,
+			bandSize: bandSize,
+		}
+	}
+}
+
+// Setup assigns the worker a collection; each worker pages from the start
+func (c *paginationCommand) Setup(client interface{}, workerID int, args string) error {
+	c.key = collectionKey(c.config, workerID%c.config.Collections)
+	return nil
+}
+
+// Prepare builds the window of the next request and advances the offset
+func (c *paginationCommand) Prepare() error {
+	if c.offset >= c.config.CollectionSize {
+		c.offset = 0
+	}
+	stop := c.offset + c.config.PageSize - 1
+	c.next = []string{c.name, c.key, strconv.FormatInt(c.offset, 10), strconv.FormatInt(stop, 10)}
+	c.offset += c.stride
+	return nil
+}
+
+// Label reports the offset band of the prepared request, so latency can be
+// compared between the head and the tail of the collection
+func (c *paginationCommand) Label() string {
+	start, _ := strconv.ParseInt(c.next[2], 10, 64)
+	band := start / c.bandSize
+	return fmt.Sprintf("offset %d-%d", band*c.bandSize, (band+1)*c.bandSize-1)
+}
+
+// Key returns the paged collection
+func (c *paginationCommand) Key() string {
+	return c.key
+}
+
+func (c *paginationCommand) Execute(client interface{}) error {
+	var err error
+	c.reply, err = executeCommand(client, c.next)
+	return err
+}
+
+// TransferredBytes approximates the encoded size of the last request and its reply
+func (c *paginationCommand) TransferredBytes() (sent, received int64) {
+	return respCommandSize(c.next...), respReplySize(c.reply)
+}
+
+func (c *paginationCommand) Teardown(client interface{}) error {
+	return nil
+}
+
+// collectionFillCommand writes the elements of all collections in batches,
+// claiming batches from a cursor shared by all workers
+type collectionFillCommand struct {
+	config  *Config
+	next    *int64
+	batches int64 // Batches per collection
+	data    string
+	args    []string
+}
+
+func (c *collectionFillCommand) Setup(client interface{}, workerID int, args string) error {
+	return nil
+}
+
+// Prepare builds the next batch, or reports that all collections are filled
+func (c *collectionFillCommand) Prepare() error {
+	index := atomic.AddInt64(c.next, 1) - 1
+	if index >= c.batches*int64(c.config.Collections) {
+		return errCustomCommandDone
+	}
+	key := collectionKey(c.config, int(index/c.batches))
+	first := (index % c.batches) * paginationFillBatch
+	last := first + paginationFillBatch
+	if last > c.config.CollectionSize {
+		last = c.config.CollectionSize
+	}
+
+	if c.config.Command == "zrange" {
+		c.args = []string{"ZADD", key}
+		for i := first; i < last; i++ {
+			member := strconv.FormatInt(i, 10)
+			c.args = append(c.args, member, member+":"+c.data)
+		}
+	} else {
+		c.args = []string{"RPUSH", key}
+		for i := first; i < last; i++ {
+			c.args = append(c.args, c.data)
+		}
+	}
+	return nil
+}
+
+func (c *collectionFillCommand) Execute(client interface{}) error {
+	_, err := executeCommand(client, c.args)
+	return err
+}
+
+func (c *collectionFillCommand) Teardown(client interface{}) error {
+	return nil
+}
+
+// fillCollections recreates the collections of a pagination workload with
+// --collection-size elements each and reports the write latency separately
+func (b *Benchmark) fillCollections(ctx context.Context) error {
+	config := b.config
+	if !config.PageFill {
+		return nil
+	}
+	for n := 0; n < config.Collections; n++ {
+		key := collectionKey(config, n)
+		if _, err := executeCommand(b.poolClient(false, 0), []string{"DEL", key}); err != nil {
+			return fmt.Errorf("failed to reset collection %s: %v", key, err)
+		}
+	}
+
+	batches := (config.CollectionSize + paginationFillBatch - 1) / paginationFillBatch
+	fmt.Printf("\nFilling %d collections with %d elements\n", config.Collections, config.CollectionSize)
+	stats := b.runCommandStage(ctx, batches*int64(config.Collections), func(*Config) CustomCommandFactory {
+		next := new(int64)
+		data := generateRandomData(config.DataSize)
+		return func() CustomCommand {
+			return &collectionFillCommand{config: config, next: next, batches: batches, data: data}
+		}
+	})
+	stats.PrintFinalStats()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	fmt.Printf("\nPagination benchmark (%s):\n", paginationCommands[config.Command])
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// pauseSampleInterval is the resolution of the throughput recovery measurement
+const pauseSampleInterval = 100 * time.Millisecond
+
+// pauseRecoveryLimit bounds how long recovery is waited for after the pause lifts
+const pauseRecoveryLimit = 60 * time.Second
+
+// PauseRecovery holds the outcome of a --pause-at measurement
+type PauseRecovery struct {
+	Mode        string
+	Start       time.Time
+	End         time.Time
+	BaselineRPS float64       // Throughput in the second before the pause
+	Recovery    time.Duration // Time from the lift until throughput reached 90% of the baseline (-1 if it didn't)
+	Spike       *LatencyStats // Latencies of the requests completed from the pause until recovery
+}
+
+// measurePause pauses the clients of all primaries once, PauseAt seconds into
+// the phase, and measures how long throughput takes to return to 90% of the
+// rate before the pause and the latency of the requests queued behind it
+func (b *Benchmark) measurePause(ctx context.Context, config *Config, stats *BenchmarkStats) {
+	phaseStart := time.Now()
+	pauseAt := time.Duration(config.PauseAt) * time.Second
+	if !sleepContext(ctx, pauseAt-time.Second-time.Since(phaseStart)) {
+		return
+	}
+	before := atomic.LoadInt64(&stats.requestsCompleted)
+	baselineStart := time.Now()
+	if !sleepContext(ctx, pauseAt-time.Since(phaseStart)) {
+		return
+	}
+	baseline := float64(atomic.LoadInt64(&stats.requestsCompleted)-before) / time.Since(baselineStart).Seconds()
+
+	mode := strings.ToUpper(config.PauseMode)
+	result := &PauseRecovery{Mode: "pause-" + config.PauseMode, BaselineRPS: baseline, Recovery: -1}
+	stats.mu.Lock()
+	baselineLatencies := stats.latencies.Copy()
+	stats.mu.Unlock()
+
+	stats.StartStall(result.Mode)
+	result.Start = time.Now()
+	args := []string{"CLIENT", "PAUSE", strconv.Itoa(config.PauseDuration), mode}
+	if _, err := executeOnAllPrimaries(b.stallClient, args); err != nil {
+		stats.EndStall()
+		slog.Error("CLIENT PAUSE failed", "error", err)
+		return
+	}
+	sleepContext(ctx, time.Duration(config.PauseDuration)*time.Millisecond-time.Since(result.Start))
+	stats.EndStall()
+	result.End = time.Now()
+
+	// Sample completions until the rate over one sample reaches 90% of the baseline,
+	// waiting at least a second so the queued requests are included in the spike
+	last := atomic.LoadInt64(&stats.requestsCompleted)
+	lastTime := result.End
+	ticker := time.NewTicker(pauseSampleInterval)
+	defer ticker.Stop()
+	for done := false; !done; {
+		select {
+		case <-ctx.Done():
+			done = true
+		case now := <-ticker.C:
+			completed := atomic.LoadInt64(&stats.requestsCompleted)
+			rate := float64(completed-last) / now.Sub(lastTime).Seconds()
+			last, lastTime = completed, now
+			if result.Recovery < 0 && rate >= 0.9*baseline {
+				result.Recovery = now.Sub(result.End)
+			}
+			sinceLift := now.Sub(result.End)
+			done = (result.Recovery >= 0 && sinceLift >= time.Second) || sinceLift >= pauseRecoveryLimit
+		}
+	}
+
+	stats.mu.Lock()
+	result.Spike = stats.latencies.Since(baselineLatencies).Stats()
+	stats.pauseRecovery = result
+	stats.mu.Unlock()
+}
+
+// printPauseRecovery prints the outcome of a --pause-at measurement
+func (s *BenchmarkStats) printPauseRecovery() {
+	s.mu.Lock()
+	result := s.pauseRecovery
+	s.mu.Unlock()
+	if result == nil {
+		return
+	}
+
+	fmt.Printf("\nPause Recovery (%s):\n", result.Mode)
+	fmt.Printf("==============\n")
+	fmt.Printf("Paused at: %.1f s for %d ms\n", result.Start.Sub(s.startTime).Seconds(), result.End.Sub(result.Start).Milliseconds())
+	fmt.Printf("Baseline RPS: %.2f\n", result.BaselineRPS)
+	if result.Recovery >= 0 {
+		fmt.Printf("Throughput recovered to 90%% after: %d ms\n", result.Recovery.Milliseconds())
+	} else {
+		fmt.Printf("Throughput did not recover to 90%% within %s\n", pauseRecoveryLimit)
+	}
+	if result.Spike != nil {
+		fmt.Printf("Latency spike (ms) - p50: %.3f, p99: %.3f, max: %.3f\n", result.Spike.p50, result.Spike.p99, result.Spike.max)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+)
+
+// payloadChars is the alphabet of generated values
+const payloadChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// payloadCharsPerDraw is the number of letters taken from one 64-bit random value (26^13 < 2^64)
+const payloadCharsPerDraw = 13
+
+// randomLetters calls write with size random letters of payloadChars,
+// drawing several letters per random value so large payloads are cheap to
+// regenerate on every request
+func randomLetters(rng *rand.Rand, size int, write func(byte)) {
+	for i := 0; i < size; {
+		v := rng.Uint64()
+		for j := 0; j < payloadCharsPerDraw && i < size; j++ {
+			write(payloadChars[v%uint64(len(payloadChars))])
+			v /= uint64(len(payloadChars))
+			i++
+		}
+	}
+}
+
+// PayloadSource supplies the values written by a worker: one string generated
+// up front and reused, or with --randomize-data-per-request a freshly
+// generated string for every request, so server or proxy side deduplication
+// and compression can't make the results unrealistically good. Values are
+// random letters, or documents of the --data-template generator.
+type PayloadSource struct {
+	data     string
+	buf      []byte
+	rng      *rand.Rand
+	generate payloadGenerator
+	fresh    bool
+}
+
+// newPayloadSource creates the payload source of one worker
+func newPayloadSource(config *Config, size int) *PayloadSource {
+	p := &PayloadSource{
+		buf:      make([]byte, size),
+		rng:      rand.New(rand.NewSource(benchRand.Int63())),
+		generate: dataGenerator,
+		fresh:    config.RandomizeData,
+	}
+	if !p.fresh {
+		if p.generate != nil {
+			p.data = p.generate(p.rng, size)
+		} else {
+			p.data = generateRandomData(size)
+		}
+	}
+	return p
+}
+
+// Next returns the value of the next request
+func (p *PayloadSource) Next() string {
+	if !p.fresh {
+		return p.data
+	}
+	if p.generate != nil {
+		return p.generate(p.rng, len(p.buf))
+	}
+	i := 0
+	randomLetters(p.rng, len(p.buf), func(c byte) {
+		p.buf[i] = c
+		i++
+	})
+	return string(p.buf)
+}
+
+// randomDataSegment renders a {{data:N}} template expression with a fresh
+// value on every request
+func randomDataSegment(size int) templateSegment {
+	if generate := dataGenerator; generate != nil {
+		return func(ctx *templateContext, sb *strings.Builder) {
+			sb.WriteString(generate(ctx.rng, size))
+		}
+	}
+	return func(ctx *templateContext, sb *strings.Builder) {
+		sb.Grow(size)
+		randomLetters(ctx.rng, size, func(c byte) { sb.WriteByte(c) })
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// workerRequest is a request of a worker, prepared before it is sent and
+// timed when its reply arrives
+type workerRequest struct {
+	number  int64 // Sequence number of the request within the worker
+	command string
+	path    string // Label of the request in the per-path statistics
+	name    string // Command name in the per-command statistics ("" if unknown)
+	key     string
+	data    string
+	start   time.Time
+	latency float64 // Milliseconds from start to the reply
+}
+
+// finish records the latency of a request sent at start
+func (r *workerRequest) finish(start time.Time) {
+	r.start = start
+	r.latency = float64(time.Since(start).Microseconds()) / 1000.0
+}
+
+// runPipeline sends the requests of a -pipeline batch concurrently on one
+// client, one goroutine per request, and waits for all replies. The worker
+// thus keeps N requests in flight on the client's multiplexed connection;
+// every request is a separate call of the client, which has no pipeline or
+// batch API in this version, and is timed on its own.
+func runPipeline(client interface{}, pending []*workerRequest, send func(interface{}, *workerRequest) requestResult) []requestResult {
+	results := make([]requestResult, len(pending))
+	var wg sync.WaitGroup
+	for i, r := range pending {
+		wg.Add(1)
+		go func(i int, r *workerRequest) {
+			defer wg.Done()
+			start := time.Now()
+			results[i] = send(client, r)
+			r.finish(start)
+		}(i, r)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"plugin"
+
+	"github.com/valkey-io/valkey-glide/go/api"
+)
+
+// CustomCommand is implemented by custom workloads run with "-t custom".
+// One instance is created per worker thread, so implementations don't need
+// to be safe for concurrent use. The client passed to each method is either
+// a *api.GlideClient or a *api.GlideClusterClient depending on --cluster.
+type CustomCommand interface {
+	// Setup is called once per worker before its first request
+	Setup(client interface{}, workerID int, args string) error
+	// Execute is called for every benchmark request
+	Execute(client interface{}) error
+	// Teardown is called once per worker after its last request
+	Teardown(client interface{}) error
+}
+
+// CustomCommandPreparer can be implemented by custom commands that need to do
+// work before each request which must not be included in the measured latency.
+type CustomCommandPreparer interface {
+	Prepare() error
+}
+
+// CustomCommandLabeler can be implemented by custom commands issuing different
+// kinds of requests. The label of the prepared request is used to break down
+// statistics in the final report.
+type CustomCommandLabeler interface {
+	Label() string
+}
+
+// errCustomCommandDone is returned by a custom command when its workload is exhausted.
+// The worker stops cleanly instead of counting an error.
+var errCustomCommandDone = errors.New("custom command workload finished")
+
+// pluginFactorySymbol is the symbol a plugin must export. Its type must be
+// func() interface{} and the returned value must implement CustomCommand.
+const pluginFactorySymbol = "NewCustomCommand"
+
+// CustomCommandFactory creates a new CustomCommand instance for a worker
+type CustomCommandFactory func() CustomCommand
+
+// loadCustomCommandFactory returns the factory for custom commands. Without a
+// plugin path the built-in default command is used.
+func loadCustomCommandFactory(path string) (CustomCommandFactory, error) {
+	if path == "" {
+		return func() CustomCommand { return &defaultCustomCommand{} }, nil
+	}
+
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plugin %s: %v", path, err)
+	}
+	sym, err := p.Lookup(pluginFactorySymbol)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s does not export %s: %v", path, pluginFactorySymbol, err)
+	}
+
+	newCommand, ok := sym.(func() interface{})
+	if !ok {
+		return nil, fmt.Errorf("plugin symbol %s has type %T, expected func() interface{}", pluginFactorySymbol, sym)
+	}
+
+	// Validate once up front so a broken plugin fails before any worker starts
+	if _, ok := newCommand().(CustomCommand); !ok {
+		return nil, fmt.Errorf("value returned by %s does not implement Setup/Execute/Teardown", pluginFactorySymbol)
+	}
+
+	return func() CustomCommand {
+		return newCommand().(CustomCommand)
+	}, nil
+}
+
+// defaultCustomCommand is used when no plugin is given. It issues a single SET.
+type defaultCustomCommand struct{}
+
+func (c *defaultCustomCommand) Setup(client interface{}, workerID int, args string) error {
+	return nil
+}
+
+func (c *defaultCustomCommand) Execute(client interface{}) error {
+	var err error
+	if cl, ok := client.(*api.GlideClient); ok {
+		_, err = cl.Set("custom key", "custom value")
+	} else if cl, ok := client.(*api.GlideClusterClient); ok {
+		_, err = cl.Set("custom key", "custom value")
+	}
+	return err
+}
+
+func (c *defaultCustomCommand) Teardown(client interface{}) error {
+	return nil
+}
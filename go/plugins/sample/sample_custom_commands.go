@@ -0,0 +1,80 @@
+// Package main is a sample custom command plugin for the Go benchmark.
+//
+// Build it with the same Go toolchain and valkey-glide version as the benchmark:
+//
+//	go build -buildmode=plugin -o sample.so ./plugins/sample
+//
+// and run it with:
+//
+//	./valkey-benchmark -t custom --plugin ./sample.so --plugin-args "operation=hset,key_prefix=sample"
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/valkey-io/valkey-glide/go/api"
+)
+
+// SampleCommand implements Setup/Execute/Teardown. One instance is created per worker.
+type SampleCommand struct {
+	workerID  int
+	operation string
+	keyPrefix string
+	counter   int64
+}
+
+// NewCustomCommand is the factory symbol looked up by the benchmark
+func NewCustomCommand() interface{} {
+	return &SampleCommand{operation: "set", keyPrefix: "sample"}
+}
+
+// Setup parses the plugin arguments ("key=value" pairs separated by commas)
+func (c *SampleCommand) Setup(client interface{}, workerID int, args string) error {
+	c.workerID = workerID
+	for _, pair := range strings.Split(args, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "operation":
+			c.operation = strings.TrimSpace(value)
+		case "key_prefix":
+			c.keyPrefix = strings.TrimSpace(value)
+		}
+	}
+	if c.operation != "set" && c.operation != "hset" {
+		return fmt.Errorf("unknown operation %q", c.operation)
+	}
+	return nil
+}
+
+// Execute issues one request
+func (c *SampleCommand) Execute(client interface{}) error {
+	var args []string
+	switch c.operation {
+	case "set":
+		args = []string{"SET", fmt.Sprintf("%s:key:%d:%d", c.keyPrefix, c.workerID, c.counter), "value"}
+	case "hset":
+		args = []string{"HSET", fmt.Sprintf("%s:hash:%d", c.keyPrefix, c.workerID),
+			fmt.Sprintf("field:%d", c.counter), "value"}
+	}
+	c.counter++
+
+	var err error
+	if cl, ok := client.(*api.GlideClient); ok {
+		_, err = cl.CustomCommand(args)
+	} else if cl, ok := client.(*api.GlideClusterClient); ok {
+		_, err = cl.CustomCommand(args)
+	}
+	return err
+}
+
+// Teardown is called once per worker after its last request
+func (c *SampleCommand) Teardown(client interface{}) error {
+	return nil
+}
+
+// main is required for the package to build outside of -buildmode=plugin
+func main() {}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/valkey-io/valkey-glide/go/api"
+)
+
+// RTTProbe measures PING round trips on a dedicated connection at a low rate
+// while the workload runs. The probe does not queue behind workload requests
+// on the client, so a probe latency rising together with the workload latency
+// points at the network or the server's event loop, while a flat probe
+// latency points at queuing of the workload itself.
+type RTTProbe struct {
+	interval time.Duration
+	mu       sync.Mutex
+	window   []float64 // Round trips of the current reporting interval
+	all      []float64 // All round trips of the phase
+	errors   int64
+}
+
+// createProbeClient creates the dedicated connection of --rtt-probe
+func createProbeClient(config *Config) (interface{}, error) {
+	return createClient(config, api.Primary, fmt.Sprintf("vkbench-%s-probe", config.RunID))
+}
+
+// newRTTProbe creates the probe of a phase (nil if disabled)
+func newRTTProbe(config *Config) *RTTProbe {
+	if config.ProbeInterval <= 0 {
+		return nil
+	}
+	return &RTTProbe{interval: time.Duration(config.ProbeInterval) * time.Millisecond}
+}
+
+// Run sends PING on the client every interval until the context is done
+func (p *RTTProbe) Run(ctx context.Context, client interface{}) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		start := time.Now()
+		_, err := executeCommand(client, []string{"PING"})
+		rtt := float64(time.Since(start).Microseconds()) / 1000.0
+		p.mu.Lock()
+		if err != nil {
+			p.errors++
+		} else {
+			p.window = append(p.window, rtt)
+			p.all = append(p.all, rtt)
+		}
+		p.mu.Unlock()
+		if err != nil && ctx.Err() == nil {
+			slog.Warn("RTT probe failed", "error", err)
+		}
+	}
+}
+
+// drain returns the statistics of the round trips since the last call (nil without samples)
+func (p *RTTProbe) drain() *LatencyStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := calculateLatencyStats(p.window)
+	p.window = p.window[:0]
+	return stats
+}
+
+// printProbe prints the probe round trips of the run and how the probe p99
+// moved compared to the workload p99 across intervals
+func (s *BenchmarkStats) printProbe() {
+	probe := s.probe
+	if probe == nil {
+		return
+	}
+	probe.mu.Lock()
+	all := append([]float64(nil), probe.all...)
+	errors := probe.errors
+	probe.mu.Unlock()
+
+	fmt.Printf("\nRTT Probe (PING every %s on a dedicated connection):\n", probe.interval)
+	fmt.Printf("=========\n")
+	fmt.Printf("Probes: %d, Errors: %d\n", len(all), errors)
+	stats := calculateLatencyStats(all)
+	if stats == nil {
+		return
+	}
+	fmt.Printf("Round trip (ms) - min: %.3f, p50: %.3f, p99: %.3f, max: %.3f\n", stats.min, stats.p50, stats.p99, stats.max)
+
+	// Compare the intervals with the best and worst workload tail latency
+	var intervals []IntervalStats
+	for _, interval := range s.Timeline() {
+		if interval.Requests > 0 && interval.ProbeP99 > 0 {
+			intervals = append(intervals, interval)
+		}
+	}
+	if len(intervals) < 2 {
+		return
+	}
+	sort.SliceStable(intervals, func(i, j int) bool { return intervals[i].P99 < intervals[j].P99 })
+	best, worst := intervals[0], intervals[len(intervals)-1]
+	fmt.Printf("Best workload interval:  t=%7.1fs workload p99: %.3f, probe p99: %.3f\n", best.Elapsed, best.P99, best.ProbeP99)
+	fmt.Printf("Worst workload interval: t=%7.1fs workload p99: %.3f, probe p99: %.3f\n", worst.Elapsed, worst.P99, worst.ProbeP99)
+}
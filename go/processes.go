@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// processLogTail is the number of output lines shown of a failed child process
+const processLogTail = 20
+
+// processReadyPoll is how often the other children check whether child 0
+// has populated and warmed up
+const processReadyPoll = 50 * time.Millisecond
+
+// scaleForProcess turns the configuration of a --processes run into the share
+// of one child: the request count and the global rate limits are divided
+// between the children, the thread and client settings apply to every child
+func scaleForProcess(config *Config) {
+	n := int64(config.Processes)
+	index := int64(config.ProcessIndex)
+	share := config.TotalRequests / n
+	if index < config.TotalRequests%n {
+		share++
+	}
+	config.RequestOffset = index*(config.TotalRequests/n) + min(index, config.TotalRequests%n)
+	config.TotalRequests = share
+
+	divide := func(v int) int {
+		if v <= 0 {
+			return v
+		}
+		if v /= int(n); v < 1 {
+			return 1
+		}
+		return v
+	}
+	config.QPS = divide(config.QPS)
+	config.StartQPS = divide(config.StartQPS)
+	config.EndQPS = divide(config.EndQPS)
+	config.QPSChange = divide(config.QPSChange)
+	config.MaxInflight = divide(config.MaxInflight)
+	config.TargetMbps /= float64(n)
+
+	// Files written once for the whole run are left to the parent
+	config.ManifestFile = ""
+	config.OutputJSON = ""
+
+	// The keyspace is populated and warmed up once, by child 0
+	if index > 0 {
+		config.Populate = false
+		config.Warmup = 0
+	}
+}
+
+// syncProcesses starts the measured phases of the children together after
+// child 0 has populated the keyspace and warmed up: child 0 creates the ready
+// file and the other children wait for it
+func syncProcesses(ctx context.Context, config *Config) error {
+	if config.ProcessIndex == 0 {
+		file, err := os.Create(config.ProcessReady)
+		if err != nil {
+			return fmt.Errorf("failed to signal the other processes: %v", err)
+		}
+		return file.Close()
+	}
+	ticker := time.NewTicker(processReadyPoll)
+	defer ticker.Stop()
+	for {
+		if _, err := os.Stat(config.ProcessReady); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// runProcesses runs the benchmark in --processes child processes of this
+// binary, each with its own client runtime, and reports their merged results.
+// Every child writes its final statistics in the checkpoint format to a
+// temporary file read by the parent.
+func runProcesses(ctx context.Context, config *Config) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the benchmark binary: %v", err)
+	}
+	dir, err := os.MkdirTemp("", "vkbench-"+config.RunID+"-")
+	if err != nil {
+		return fmt.Errorf("failed to create process directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	printConfig(config)
+	fmt.Printf("Processes: %d\n\n", config.Processes)
+	if config.ManifestFile != "" {
+		if err := writeManifest(config.ManifestFile, config); err != nil {
+			return err
+		}
+	}
+
+	var ready string
+	if config.Populate || config.Warmup > 0 {
+		ready = filepath.Join(dir, "ready")
+	}
+
+	start := time.Now()
+	children := make([]*exec.Cmd, config.Processes)
+	logs := make([]string, config.Processes)
+	results := make([]string, config.Processes)
+	for i := range children {
+		logs[i] = filepath.Join(dir, fmt.Sprintf("process-%d.log", i))
+		results[i] = filepath.Join(dir, fmt.Sprintf("process-%d.result", i))
+		output, err := os.Create(logs[i])
+		if err != nil {
+			return fmt.Errorf("failed to create process log: %v", err)
+		}
+		// The flags come first so they apply even if the command line ends in arguments
+		args := []string{"--process-result", results[i], "--process-index", strconv.Itoa(i)}
+		if ready != "" {
+			args = append(args, "--process-ready", ready)
+		}
+		args = append(args, os.Args[1:]...)
+		cmd := exec.Command(executable, args...)
+		cmd.Stdout, cmd.Stderr = output, output
+		err = cmd.Start()
+		output.Close()
+		if err != nil {
+			stopProcesses(children[:i])
+			return fmt.Errorf("failed to start process %d: %v", i, err)
+		}
+		children[i] = cmd
+	}
+
+	// Interrupt the children when the run is cancelled; they stop gracefully and still report
+	stopWatching := context.AfterFunc(ctx, func() { stopProcesses(children) })
+	defer stopWatching()
+
+	failed := 0
+	for i, cmd := range children {
+		if err := cmd.Wait(); err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "Process %d failed: %v\n", i, err)
+			printLogTail(logs[i])
+			if _, statErr := os.Stat(ready); i == 0 && ready != "" && statErr != nil {
+				// The others would wait forever for the keyspace
+				stopProcesses(children[1:])
+			}
+			continue
+		}
+		fmt.Printf("Process %d finished after %.1f seconds\n", i, time.Since(start).Seconds())
+	}
+
+	stats := NewBenchmarkStats(config)
+	merged := 0
+	for i, path := range results {
+		state, err := loadCheckpoint(path)
+		if err != nil {
+			if _, statErr := os.Stat(path); statErr == nil {
+				fmt.Fprintf(os.Stderr, "Process %d: %v\n", i, err)
+			}
+			continue
+		}
+		stats.mergeProcess(state)
+		merged++
+	}
+	if merged == 0 {
+		return fmt.Errorf("none of the %d processes reported results", config.Processes)
+	}
+	stats.Stop()
+	fmt.Printf("\nResults of %d of %d processes, merged from their latency histograms (percentiles at bucket resolution)\n",
+		merged, config.Processes)
+	stats.PrintFinalStats()
+	if config.OutputJSON != "" {
+		if err := writeResults(config.OutputJSON, stats.Results(nil)); err != nil {
+			return err
+		}
+		fmt.Printf("\nResults written to %s\n", config.OutputJSON)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d processes failed", failed, config.Processes)
+	}
+	return nil
+}
+
+// stopProcesses interrupts the started child processes
+func stopProcesses(children []*exec.Cmd) {
+	for _, cmd := range children {
+		if cmd != nil && cmd.Process != nil {
+			cmd.Process.Signal(os.Interrupt)
+		}
+	}
+}
+
+// printLogTail prints the last lines of a child process log to stderr
+func printLogTail(path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) > processLogTail {
+			lines = lines[1:]
+		}
+	}
+	for _, line := range lines {
+		fmt.Fprintf(os.Stderr, "  %s\n", line)
+	}
+}
+
+// mergeProcess adds the final statistics of a child process. Its latency
+// histograms are merged counter by counter. The run lasts as long as the
+// slowest child. Interval timelines are aligned by position:
+// counts and rates are summed and the worst percentile of the children is
+// kept, since percentiles of separate processes can't be combined exactly.
+func (s *BenchmarkStats) mergeProcess(state *checkpointState) {
+	latencies := histogramFromState(state.Latencies)
+	s.replayLatencies(latencies, state.Errors)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if start := time.Now().Add(-time.Duration(state.Elapsed * float64(time.Second))); start.Before(s.startTime) {
+		s.startTime = start
+	}
+	atomic.AddInt64(&s.requestsCompleted, state.Requests)
+	atomic.AddInt64(&s.errors, state.Errors)
+	atomic.AddInt64(&s.bytesSent, state.BytesSent)
+	atomic.AddInt64(&s.bytesReceived, state.BytesReceived)
+	atomic.AddInt64(&s.hits, state.Hits)
+	atomic.AddInt64(&s.misses, state.Misses)
+	s.latencies.Merge(latencies)
+	for _, path := range state.PathOrder {
+		s.registerPath(path)
+	}
+	for path, latencies := range state.PathLatencies {
+		if h, ok := s.pathLatencies[path]; ok {
+			h.Merge(histogramFromState(latencies))
+		} else {
+			s.pathLatencies[path] = histogramFromState(latencies)
+		}
+	}
+	for path, errors := range state.PathErrors {
+		s.pathErrors[path] += errors
+	}
+	for _, command := range state.CommandOrder {
+		s.registerCommand(command)
+	}
+	for command, latencies := range state.CommandLatencies {
+		if h, ok := s.commandLatencies[command]; ok {
+			h.Merge(histogramFromState(latencies))
+		} else {
+			s.commandLatencies[command] = histogramFromState(latencies)
+		}
+	}
+	for command, errors := range state.CommandErrors {
+		s.commandErrors[command] += errors
+	}
+	for i, interval := range state.Timeline {
+		if i == len(s.timeline) {
+			s.timeline = append(s.timeline, interval)
+			continue
+		}
+		merged := &s.timeline[i]
+		merged.Requests += interval.Requests
+		merged.Errors += interval.Errors
+		merged.RPS += interval.RPS
+		if interval.P50 > merged.P50 {
+			merged.P50 = interval.P50
+		}
+		if interval.P95 > merged.P95 {
+			merged.P95 = interval.P95
+		}
+		if interval.P99 > merged.P99 {
+			merged.P99 = interval.P99
+		}
+		if interval.Max > merged.Max {
+			merged.Max = interval.Max
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QPSProfile returns the QPS target at a time since the start of the phase
+type QPSProfile func(elapsed time.Duration) float64
+
+// QPSSchedulePoint is one row of a --qps-schedule file
+type QPSSchedulePoint struct {
+	At  float64 // Seconds since the start of the run
+	QPS float64
+}
+
+// newQPSProfile returns the profile of --qps-profile or --qps-schedule, or
+// nil if the target is set by --qps or a ramp
+func newQPSProfile(config *Config) (QPSProfile, error) {
+	if config.QPSSchedule != "" {
+		points, err := LoadQPSSchedule(config.QPSSchedule)
+		if err != nil {
+			return nil, err
+		}
+		return scheduleProfile(points), nil
+	}
+	if config.QPSProfile == "sine" {
+		return sineProfile(float64(config.StartQPS), float64(config.EndQPS), time.Duration(config.QPSPeriod)*time.Second), nil
+	}
+	return nil, nil
+}
+
+// sineProfile oscillates between from and to with the given period, starting
+// at from and reaching to after half a period
+func sineProfile(from, to float64, period time.Duration) QPSProfile {
+	return func(elapsed time.Duration) float64 {
+		phase := 2 * math.Pi * float64(elapsed) / float64(period)
+		return from + (to-from)*(1-math.Cos(phase))/2
+	}
+}
+
+// scheduleProfile interpolates linearly between the points of a schedule and
+// holds the first and last targets before and after it. Two rows with the same
+// time make an instant step.
+func scheduleProfile(points []QPSSchedulePoint) QPSProfile {
+	return func(elapsed time.Duration) float64 {
+		at := elapsed.Seconds()
+		if at <= points[0].At {
+			return points[0].QPS
+		}
+		for i := 1; i < len(points); i++ {
+			prev, next := points[i-1], points[i]
+			if at < next.At {
+				return prev.QPS + (next.QPS-prev.QPS)*(at-prev.At)/(next.At-prev.At)
+			}
+		}
+		return points[len(points)-1].QPS
+	}
+}
+
+// LoadQPSSchedule reads a CSV file of time,qps rows (seconds since the start
+// of the run and the target at that time). A header row is skipped.
+func LoadQPSSchedule(path string) ([]QPSSchedulePoint, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read QPS schedule: %v", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+	var points []QPSSchedulePoint
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid QPS schedule %s: %v", path, err)
+		}
+		at, atErr := strconv.ParseFloat(strings.TrimSpace(record[0]), 64)
+		qps, qpsErr := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if row == 1 && (atErr != nil || qpsErr != nil) {
+			continue // Header
+		}
+		if atErr != nil || qpsErr != nil {
+			return nil, fmt.Errorf("invalid QPS schedule %s: row %d is not time,qps", path, row)
+		}
+		if at < 0 || qps <= 0 {
+			return nil, fmt.Errorf("invalid QPS schedule %s: row %d needs a non-negative time and a positive qps", path, row)
+		}
+		if n := len(points); n > 0 && at < points[n-1].At {
+			return nil, fmt.Errorf("invalid QPS schedule %s: row %d goes back in time", path, row)
+		}
+		points = append(points, QPSSchedulePoint{At: at, QPS: qps})
+	}
+	if len(points) == 0 {
+		return nil, fmt.Errorf("QPS schedule %s has no rows", path)
+	}
+	return points, nil
+}
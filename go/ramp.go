@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// RampStage holds the statistics collected while one QPS level of a ramp was active
+type RampStage struct {
+	targetQPS int
+	start     time.Time
+	end       time.Time // Zero for the active stage
+	latencies *LatencyHistogram
+	errors    int64
+}
+
+// StartRampStage closes the active ramp stage and starts collecting
+// statistics for a new QPS target
+func (s *BenchmarkStats) StartRampStage(targetQPS int) {
+	now := time.Now()
+	s.mu.Lock()
+	if n := len(s.rampStages); n > 0 {
+		s.rampStages[n-1].end = now
+	}
+	s.rampStages = append(s.rampStages, &RampStage{targetQPS: targetQPS, start: now, latencies: NewLatencyHistogram()})
+	stage := len(s.rampStages)
+	s.mu.Unlock()
+
+	if s.exporters != nil && stage > 1 {
+		s.exporters.PublishEvent("stage-changed", map[string]interface{}{
+			"stage":      stage,
+			"target_qps": targetQPS,
+		})
+	}
+}
+
+// printRampStages prints one row per QPS level so the load level at which
+// tail latency started degrading can be identified
+func (s *BenchmarkStats) printRampStages() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	end := s.endTime
+	if end.IsZero() {
+		end = time.Now()
+	}
+
+	fmt.Printf("\nPer-Stage Statistics:\n")
+	fmt.Printf("=====================\n")
+	fmt.Printf("%10s %10s %14s %10s %10s %10s %10s\n",
+		"Target QPS", "Duration", "Achieved QPS", "Errors", "p50 (ms)", "p95 (ms)", "p99 (ms)")
+	for _, stage := range s.rampStages {
+		stageEnd := stage.end
+		if stageEnd.IsZero() {
+			stageEnd = end
+		}
+		duration := stageEnd.Sub(stage.start).Seconds()
+		achieved := 0.0
+		if duration > 0 {
+			achieved = float64(stage.latencies.Count()) / duration
+		}
+		fmt.Printf("%10d %9.1fs %14.2f %10d", stage.targetQPS, duration, achieved, stage.errors)
+		if stats := stage.latencies.Stats(); stats != nil {
+			fmt.Printf(" %10.3f %10.3f %10.3f\n", stats.p50, stats.p95, stats.p99)
+		} else {
+			fmt.Printf(" %10s %10s %10s\n", "-", "-", "-")
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRampStagesStopAtEndQPS steps a linear ramp well past its end and checks
+// that the clamped target doesn't open a new stage on every interval
+func TestRampStagesStopAtEndQPS(t *testing.T) {
+	config := &Config{StartQPS: 100, EndQPS: 300, QPSChange: 100, QPSChangeInterval: 1}
+	qps := NewQPSController(config)
+	stats := NewBenchmarkStats(config)
+	stats.StartRampStage(qps.Target())
+	qps.onUpdate = stats.StartRampStage
+
+	for i := 1; i <= 10; i++ {
+		qps.step(qps.start.Add(time.Duration(i) * time.Second))
+	}
+
+	if got := qps.Target(); got != 300 {
+		t.Fatalf("target after the ramp = %d, want 300", got)
+	}
+	if got := len(stats.rampStages); got != 3 {
+		t.Fatalf("ramp stages = %d, want 3 (100, 200 and 300 QPS)", got)
+	}
+	for i, want := range []int{100, 200, 300} {
+		if got := stats.rampStages[i].targetQPS; got != want {
+			t.Errorf("stage %d target = %d, want %d", i+1, got, want)
+		}
+	}
+}
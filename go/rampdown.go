@@ -0,0 +1,43 @@
+package main
+
+import (
+	"time"
+)
+
+// RampDown describes the final period of a timed run during which the offered
+// load decreases linearly to zero, so in-flight requests drain instead of
+// being cut off when the run ends
+type RampDown struct {
+	start    time.Time
+	duration time.Duration
+}
+
+// newRampDown returns the ramp-down of a phase starting now, or nil if the
+// phase has no duration or no ramp-down is configured
+func newRampDown(config *Config, phaseStart time.Time) *RampDown {
+	if config.RampDownSeconds <= 0 || config.TestDuration <= 0 {
+		return nil
+	}
+	duration := time.Duration(config.RampDownSeconds) * time.Second
+	end := phaseStart.Add(time.Duration(config.TestDuration) * time.Second)
+	return &RampDown{start: end.Add(-duration), duration: duration}
+}
+
+// Factor returns the fraction of the full load offered at the given time:
+// 1 before the ramp-down starts, decreasing linearly to 0 at its end
+func (r *RampDown) Factor(now time.Time) float64 {
+	if r == nil || now.Before(r.start) {
+		return 1
+	}
+	factor := 1 - float64(now.Sub(r.start))/float64(r.duration)
+	if factor < 0 {
+		return 0
+	}
+	return factor
+}
+
+// WorkerRetired reports whether the worker should stop at the given time.
+// Workers retire one by one, the highest thread ID first.
+func (r *RampDown) WorkerRetired(threadID, threads int, now time.Time) bool {
+	return float64(threadID) >= r.Factor(now)*float64(threads)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// lockedSource is a rand.Source safe for concurrent use, so a single seeded
+// source can back all random choices of the benchmark
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source64
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Uint64()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
+// benchRand generates the payloads and keys and seeds the per-worker
+// generators. It is reseeded with --seed so a run's random choices can be
+// reproduced; the interleaving of concurrent workers is not deterministic.
+var benchRand = rand.New(&lockedSource{src: rand.NewSource(time.Now().UnixNano()).(rand.Source64)})
+
+// seedRandom reseeds benchRand
+func seedRandom(seed int64) {
+	benchRand.Seed(seed)
+}
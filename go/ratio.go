@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CommandRatio is the SET:GET mix of --ratio. Every worker repeats a cycle of
+// sets SETs followed by gets GETs, so the mix is exact over any number of
+// whole cycles.
+type CommandRatio struct {
+	sets int64
+	gets int64
+}
+
+// parseRatio parses a --ratio of the form SETS:GETS, such as 1:10
+func parseRatio(spec string) (*CommandRatio, error) {
+	setPart, getPart, ok := strings.Cut(spec, ":")
+	sets, setErr := strconv.ParseInt(strings.TrimSpace(setPart), 10, 64)
+	gets, getErr := strconv.ParseInt(strings.TrimSpace(getPart), 10, 64)
+	if !ok || setErr != nil || getErr != nil || sets < 0 || gets < 0 || sets+gets == 0 {
+		return nil, fmt.Errorf("invalid ratio %q (expected SETS:GETS, e.g. 1:10)", spec)
+	}
+	return &CommandRatio{sets: sets, gets: gets}, nil
+}
+
+// Command returns the command of a worker's request number request
+func (r *CommandRatio) Command(request int64) string {
+	if request%(r.sets+r.gets) < r.sets {
+		return "set"
+	}
+	return "get"
+}
+
+// String returns the ratio as given on the command line
+func (r *CommandRatio) String() string {
+	return fmt.Sprintf("%d:%d", r.sets, r.gets)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/valkey-io/valkey-glide/go/api"
+)
+
+// waitRetryInterval is the pause between connection attempts while waiting for the server
+const waitRetryInterval = time.Second
+
+// waitForServer retries connecting and PING until the server answers or the
+// timeout expires, so a benchmark started together with a fresh server
+// doesn't fail instantly
+func waitForServer(ctx context.Context, config *Config, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	name := fmt.Sprintf("vkbench-%s-wait", config.RunID)
+	for attempt := 1; ; attempt++ {
+		err := pingServer(config, name)
+		if err == nil {
+			if attempt > 1 {
+				slog.Info("server is ready", "attempts", attempt)
+			}
+			return nil
+		}
+		if time.Now().Add(waitRetryInterval).After(deadline) {
+			return fmt.Errorf("server %s:%d not ready after %s: %v", config.Host, config.Port, timeout, err)
+		}
+		slog.Info("waiting for server", "attempt", attempt, "error", err)
+		if !sleepContext(ctx, waitRetryInterval) {
+			return ctx.Err()
+		}
+	}
+}
+
+// pingServer connects with a short-lived client and sends PING
+func pingServer(config *Config, name string) error {
+	client, err := createClient(config, api.Primary, name)
+	if err != nil {
+		return err
+	}
+	defer closeClients([]interface{}{client})
+	_, err = executeCommand(client, []string{"PING"})
+	return err
+}
+
+// nodeStrings returns the string reply of every node keyed by node address.
+// A standalone reply is keyed by the configured address.
+func nodeStrings(config *Config, reply interface{}) map[string]string {
+	replies := make(map[string]string)
+	if nodes, ok := reply.(map[string]interface{}); ok {
+		for node, value := range nodes {
+			replies[node] = fmt.Sprint(value)
+		}
+		return replies
+	}
+	replies[fmt.Sprintf("%s:%d", config.Host, config.Port)] = fmt.Sprint(reply)
+	return replies
+}
+
+// checkHealth verifies that the target can serve the benchmark before it
+// starts: every node answers PING, the cluster state is ok in cluster mode,
+// and every primary has a connected replica when replica reads are requested
+func (b *Benchmark) checkHealth() error {
+	client := b.poolClient(false, 0)
+	if _, err := executeOnAllNodes(client, []string{"PING"}); err != nil {
+		return fmt.Errorf("health check failed: PING: %v", err)
+	}
+
+	if b.config.IsCluster {
+		reply, err := executeOnAllNodes(client, []string{"CLUSTER", "INFO"})
+		if err != nil {
+			return fmt.Errorf("health check failed: CLUSTER INFO: %v", err)
+		}
+		var failing []string
+		for node, info := range nodeStrings(b.config, reply) {
+			if state := infoFields(info)["cluster_state"]; state != "ok" {
+				failing = append(failing, fmt.Sprintf("%s (cluster_state:%s)", node, state))
+			}
+		}
+		if len(failing) > 0 {
+			sort.Strings(failing)
+			return fmt.Errorf("health check failed: cluster is not ok on %s", strings.Join(failing, ", "))
+		}
+	}
+
+	if b.config.ReadFromReplica || b.config.ReplicaReadRatio > 0 {
+		reply, err := executeOnAllPrimaries(client, []string{"INFO", "replication"})
+		if err != nil {
+			return fmt.Errorf("health check failed: INFO replication: %v", err)
+		}
+		var missing []string
+		for node, info := range nodeStrings(b.config, reply) {
+			fields := infoFields(info)
+			if fields["role"] == "master" && fields["connected_slaves"] == "0" {
+				missing = append(missing, node)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			return fmt.Errorf("health check failed: replica reads requested but no replica is connected to %s", strings.Join(missing, ", "))
+		}
+	}
+	return nil
+}
+
+// waitForStartTime blocks until the --start-at instant so independently
+// launched benchmark instances begin load together. It returns immediately
+// with a warning if the instant has already passed.
+func waitForStartTime(ctx context.Context, config *Config) error {
+	startAt, err := time.Parse(time.RFC3339Nano, config.StartAt)
+	if err != nil {
+		return fmt.Errorf("invalid start-at time: %v", err)
+	}
+	wait := time.Until(startAt)
+	if wait <= 0 {
+		slog.Warn("start-at time already passed, starting now", "start_at", config.StartAt, "late", -wait)
+		return nil
+	}
+	fmt.Printf("Waiting %.1f seconds until %s to start\n", wait.Seconds(), startAt.Format(time.RFC3339Nano))
+	if !sleepContext(ctx, wait) {
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reconcileTolerance is the relative difference between the client and server
+// counts above which the reconciliation warns (1%)
+const reconcileTolerance = 0.01
+
+// serverCalls is a snapshot of the command counters of the servers
+type serverCalls struct {
+	taken    time.Time
+	commands int64 // Calls of the benchmarked commands, including rejected calls
+	total    int64 // total_commands_processed
+}
+
+// benchmarkedCommands returns the commandstats names of the commands sent by
+// the workload, or nil if they are unknown (plugins and workload subprocesses)
+func (b *Benchmark) benchmarkedCommands() []string {
+	names := b.customCommandNames
+	if b.ratio != nil {
+		names = []string{"set", "get"}
+	} else if !isCustomWorkload(b.config.Command) {
+		names = []string{b.config.Command}
+	}
+	commands := make([]string, len(names))
+	for i, name := range names {
+		commands[i] = strings.ReplaceAll(strings.ToLower(name), " ", "|")
+	}
+	return commands
+}
+
+// commandCalls returns the calls and rejected calls of a commandstats entry
+// such as "calls=10,usec=32,usec_per_call=3.20,rejected_calls=0,failed_calls=0"
+func commandCalls(entry string) int64 {
+	var calls int64
+	for _, field := range strings.Split(entry, ",") {
+		name, value, _ := strings.Cut(field, "=")
+		if name == "calls" || name == "rejected_calls" {
+			n, _ := strconv.ParseInt(value, 10, 64)
+			calls += n
+		}
+	}
+	return calls
+}
+
+// readServerCalls sums the command counters of the primaries, and of the
+// replicas when -t get reads from them
+func (b *Benchmark) readServerCalls() (*serverCalls, error) {
+	reply, err := executeOnAllNodes(b.poolClient(false, 0), []string{"INFO", "all"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read INFO: %v", err)
+	}
+	replicas := b.config.Command == "get" && (b.config.ReadFromReplica || b.config.ReplicaReadRatio > 0)
+	commands := b.benchmarkedCommands()
+	snapshot := &serverCalls{taken: time.Now()}
+	for _, info := range nodeStrings(b.config, reply) {
+		fields := infoFields(info)
+		if fields["role"] != "master" && !replicas {
+			continue
+		}
+		n, _ := strconv.ParseInt(fields["total_commands_processed"], 10, 64)
+		snapshot.total += n
+		for _, name := range commands {
+			snapshot.commands += commandCalls(fields["cmdstat_"+name])
+		}
+	}
+	return snapshot, nil
+}
+
+// printReconciliation compares the requests the client completed or saw fail
+// with the calls the servers counted over the same window. A discrepancy
+// points at a measurement artifact: requests dropped or counted twice by the
+// client, retries, or another client sending the same commands.
+func (b *Benchmark) printReconciliation(before *serverCalls, stats *BenchmarkStats) {
+	after, err := b.readServerCalls()
+	if err != nil {
+		slog.Warn("reconciliation failed", "error", err)
+		return
+	}
+	clientRequests := stats.requestsCompleted + stats.errors
+	clientElapsed := stats.elapsed()
+	serverElapsed := after.taken.Sub(before.taken).Seconds()
+	commands := b.benchmarkedCommands()
+
+	fmt.Printf("\nClient/Server Reconciliation:\n")
+	fmt.Printf("=============================\n")
+	fmt.Printf("%-28s %14s %14s\n", "", "Count", "Per second")
+	fmt.Printf("%-28s %14d %14.2f\n", "Client requests", clientRequests, float64(clientRequests)/clientElapsed)
+	server, label := after.total-before.total, "Server commands (all)"
+	if len(commands) > 0 {
+		server, label = after.commands-before.commands, "Server calls ("+strings.Join(commands, ", ")+")"
+	}
+	fmt.Printf("%-28s %14d %14.2f\n", label, server, float64(server)/serverElapsed)
+	if len(commands) > 0 {
+		total := after.total - before.total
+		fmt.Printf("%-28s %14d %14.2f\n", "Server commands (all)", total, float64(total)/serverElapsed)
+	}
+
+	diff := server - clientRequests
+	relative := 0.0
+	if clientRequests > 0 {
+		relative = float64(diff) / float64(clientRequests)
+	}
+	fmt.Printf("Discrepancy: %+d (%+.2f%%)\n", diff, 100*relative)
+	if math.Abs(relative) > reconcileTolerance {
+		slog.Warn("client and server request counts differ", "client", clientRequests, "server", server,
+			"discrepancy_pct", math.Round(10000*relative)/100)
+	}
+}
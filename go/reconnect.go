@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReconnectStats measures the connection handshakes of --reconnect-every
+type ReconnectStats struct {
+	every      int64
+	requests   [2][]int64 // Requests per pool slot of the primary and replica pools
+	mu         sync.Mutex
+	handshakes []float64 // Handshake durations in milliseconds
+	failures   int64
+}
+
+// newReconnectStats creates the counters of a phase (nil if reconnects are disabled)
+func newReconnectStats(config *Config) *ReconnectStats {
+	if config.ReconnectEvery <= 0 {
+		return nil
+	}
+	r := &ReconnectStats{every: config.ReconnectEvery}
+	r.requests[0] = make([]int64, config.PoolSize)
+	r.requests[1] = make([]int64, config.PoolSize)
+	return r
+}
+
+// Due counts a request sent on a pool slot and reports whether the slot has
+// now served another ReconnectEvery requests
+func (r *ReconnectStats) Due(replica bool, index int) bool {
+	if r == nil {
+		return false
+	}
+	pool := 0
+	if replica {
+		pool = 1
+	}
+	return atomic.AddInt64(&r.requests[pool][index], 1)%r.every == 0
+}
+
+// reconnectClient tears down the connection of a pool slot and establishes a
+// new one, including TLS and AUTH, recording how long the handshake took.
+// The old client is closed after a grace period so requests other workers
+// still have in flight on it complete.
+func (b *Benchmark) reconnectClient(replica bool, index int, reconnects *ReconnectStats) {
+	start := time.Now()
+	old, err := b.replaceClient(replica, index)
+	elapsed := float64(time.Since(start).Microseconds()) / 1000.0
+	if err != nil {
+		atomic.AddInt64(&reconnects.failures, 1)
+		slog.Warn("reconnect failed", "slot", index, "replica", replica, "error", err)
+		return
+	}
+	reconnects.mu.Lock()
+	reconnects.handshakes = append(reconnects.handshakes, elapsed)
+	reconnects.mu.Unlock()
+
+	grace := time.Second + time.Duration(b.config.RequestTimeout)*time.Millisecond
+	time.AfterFunc(grace, func() { closeClients([]interface{}{old}) })
+}
+
+// printReconnects prints the handshake cost of --reconnect-every and its
+// overhead amortized over all requests
+func (s *BenchmarkStats) printReconnects() {
+	r := s.reconnects
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	handshakes := append([]float64(nil), r.handshakes...)
+	r.mu.Unlock()
+	s.mu.Lock()
+	requests := s.requestsCompleted + s.errors
+	requestTime := s.latencies.Sum()
+	s.mu.Unlock()
+
+	fmt.Printf("\nReconnects (every %d requests per connection):\n", r.every)
+	fmt.Printf("==========\n")
+	fmt.Printf("Reconnects: %d\n", len(handshakes))
+	if failures := atomic.LoadInt64(&r.failures); failures > 0 {
+		fmt.Printf("Failed reconnects: %d\n", failures)
+	}
+	if len(handshakes) == 0 {
+		return
+	}
+	var handshakeTime float64
+	for _, handshake := range handshakes {
+		handshakeTime += handshake
+	}
+	stats := calculateLatencyStats(handshakes)
+	fmt.Printf("Handshake (ms) - avg: %.3f, p50: %.3f, p99: %.3f, max: %.3f\n", stats.avg, stats.p50, stats.p99, stats.max)
+	if requests > 0 {
+		fmt.Printf("Amortized handshake overhead: %.3f ms per request\n", handshakeTime/float64(requests))
+	}
+	if requestTime+handshakeTime > 0 {
+		fmt.Printf("Share of client time spent in handshakes: %.2f%%\n", 100*handshakeTime/(requestTime+handshakeTime))
+	}
+}
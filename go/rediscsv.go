@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// redisCSVHeader is the header row of redis-benchmark --csv
+const redisCSVHeader = `"test","rps","avg_latency_ms","min_latency_ms","p50_latency_ms","p95_latency_ms","p99_latency_ms","max_latency_ms"`
+
+// redisCSV receives the --csv rows (nil when --csv is off)
+var redisCSV io.Writer
+
+// redisCSVStarted records whether the header row was written
+var redisCSVStarted bool
+
+// startRedisCSV makes stdout carry only redis-benchmark compatible CSV: the
+// rows keep the real stdout and the regular report is discarded, so scripts
+// parsing the output of redis-benchmark --csv work unchanged. Logs still go
+// to stderr.
+func startRedisCSV() error {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", os.DevNull, err)
+	}
+	redisCSV = os.Stdout
+	os.Stdout = devNull
+	return nil
+}
+
+// writeRedisCSVRow writes one test as a CSV row, preceded by the header row
+// for the first test
+func writeRedisCSVRow(test string, rps float64, stats *LatencyStats) {
+	if !redisCSVStarted {
+		fmt.Fprintln(redisCSV, redisCSVHeader)
+		redisCSVStarted = true
+	}
+	if stats == nil {
+		stats = &LatencyStats{}
+	}
+	fmt.Fprintf(redisCSV, "\"%s\",\"%.2f\",\"%.3f\",\"%.3f\",\"%.3f\",\"%.3f\",\"%.3f\",\"%.3f\"\n",
+		strings.ReplaceAll(test, `"`, `""`), rps, stats.avg, stats.min, stats.p50, stats.p95, stats.p99, stats.max)
+}
+
+// printRedisCSV writes the final results in the format of redis-benchmark
+// --csv: a row named after the command (as redis-benchmark names its tests),
+// followed by a row for every labelled path. A --ratio run has a "SET" and a
+// "GET" row, like redis-benchmark -t set,get.
+func (s *BenchmarkStats) printRedisCSV(totalTime float64, stats *LatencyStats, pathStats []*LatencyStats) {
+	test := strings.ToUpper(s.config.Command)
+	if s.config.Ratio == "" {
+		writeRedisCSVRow(test, float64(s.requestsCompleted)/totalTime, stats)
+	}
+	for i, path := range s.pathOrder {
+		requests := s.pathRequests(path)
+		name := fmt.Sprintf("%s (%s)", test, path)
+		if s.config.Ratio != "" {
+			name = path
+		}
+		writeRedisCSVRow(name, float64(requests)/totalTime, pathStats[i])
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// LatencyPercentiles is the latency distribution of a results document in milliseconds
+type LatencyPercentiles struct {
+	Min   float64 `json:"min"`
+	Avg   float64 `json:"avg"`
+	P50   float64 `json:"p50"`
+	P75   float64 `json:"p75"`
+	P90   float64 `json:"p90"`
+	P95   float64 `json:"p95"`
+	P99   float64 `json:"p99"`
+	P999  float64 `json:"p99_9"`
+	P9999 float64 `json:"p99_99"`
+	Max   float64 `json:"max"`
+}
+
+// PathResults are the results of one labelled path of a results document
+type PathResults struct {
+	Path     string              `json:"path"`
+	Requests int64               `json:"requests"`
+	Errors   int64               `json:"errors"`
+	RPS      float64             `json:"rps"`
+	Latency  *LatencyPercentiles `json:"latency_ms,omitempty"`
+}
+
+// CommandResults are the results of one command of a results document
+type CommandResults struct {
+	Command  string              `json:"command"`
+	Requests int64               `json:"requests"`
+	Errors   int64               `json:"errors"`
+	RPS      float64             `json:"rps"`
+	Latency  *LatencyPercentiles `json:"latency_ms,omitempty"`
+}
+
+// ErrorCounts breaks the errors of a results document down by kind
+type ErrorCounts struct {
+	Total            int64 `json:"total"`
+	Timeouts         int64 `json:"timeouts"`
+	DeadlineExceeded int64 `json:"deadline_exceeded"`
+	OOM              int64 `json:"oom"`
+	Loading          int64 `json:"loading"`
+	Cancelled        int64 `json:"cancelled"` // Abandoned at the end of the run, not counted as errors
+}
+
+// ResultsDocument is the JSON document of --output-json
+type ResultsDocument struct {
+	RunID         string              `json:"run_id"`
+	Command       string              `json:"command"`
+	Started       time.Time           `json:"started"`
+	Duration      float64             `json:"duration_seconds"`
+	Requests      int64               `json:"requests"`
+	RPS           float64             `json:"rps"`
+	Errors        ErrorCounts         `json:"errors"`
+	BytesSent     int64               `json:"bytes_sent"`
+	BytesReceived int64               `json:"bytes_received"`
+	Latency       *LatencyPercentiles `json:"latency_ms,omitempty"`
+	Paths         []PathResults       `json:"paths,omitempty"`
+	Commands      []CommandResults    `json:"commands,omitempty"`
+	Metadata      *RunMetadata        `json:"metadata,omitempty"`
+}
+
+// latencyPercentiles computes the percentiles of a results document, or
+// returns nil without latencies
+func latencyPercentiles(latencies *LatencyHistogram) *LatencyPercentiles {
+	stats := latencies.Stats()
+	if stats == nil {
+		return nil
+	}
+	q := latencies.Quantiles(0.50, 0.75, 0.90, 0.95, 0.99, 0.999, 0.9999)
+	return &LatencyPercentiles{
+		Min:   stats.min,
+		Avg:   stats.avg,
+		P50:   q[0],
+		P75:   q[1],
+		P90:   q[2],
+		P95:   q[3],
+		P99:   q[4],
+		P999:  q[5],
+		P9999: q[6],
+		Max:   stats.max,
+	}
+}
+
+// Results builds the results document of the run
+func (s *BenchmarkStats) Results(metadata *RunMetadata) *ResultsDocument {
+	duration := s.elapsed()
+	requests := atomic.LoadInt64(&s.requestsCompleted)
+	doc := &ResultsDocument{
+		RunID:    s.config.RunID,
+		Command:  s.config.Command,
+		Duration: duration,
+		Requests: requests,
+		RPS:      float64(requests) / duration,
+		Errors: ErrorCounts{
+			Total:            atomic.LoadInt64(&s.errors),
+			Timeouts:         atomic.LoadInt64(&s.timeouts),
+			DeadlineExceeded: atomic.LoadInt64(&s.deadlineExceeded),
+			OOM:              atomic.LoadInt64(&s.ooms),
+			Loading:          atomic.LoadInt64(&s.loading),
+			Cancelled:        atomic.LoadInt64(&s.cancelled),
+		},
+		BytesSent:     atomic.LoadInt64(&s.bytesSent),
+		BytesReceived: atomic.LoadInt64(&s.bytesReceived),
+		Metadata:      metadata,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	doc.Started = s.startTime
+	doc.Latency = latencyPercentiles(s.latencies)
+	for _, path := range s.pathOrder {
+		latencies, ok := s.pathLatencies[path]
+		if !ok {
+			latencies = NewLatencyHistogram()
+		}
+		doc.Paths = append(doc.Paths, PathResults{
+			Path:     path,
+			Requests: latencies.Count(),
+			Errors:   s.pathErrors[path],
+			RPS:      float64(latencies.Count()) / duration,
+			Latency:  latencyPercentiles(latencies),
+		})
+	}
+	if s.breaksDownCommands() {
+		for _, command := range s.commandOrder {
+			latencies, ok := s.commandLatencies[command]
+			if !ok {
+				latencies = NewLatencyHistogram()
+			}
+			doc.Commands = append(doc.Commands, CommandResults{
+				Command:  command,
+				Requests: latencies.Count(),
+				Errors:   s.commandErrors[command],
+				RPS:      float64(latencies.Count()) / duration,
+				Latency:  latencyPercentiles(latencies),
+			})
+		}
+	}
+	return doc
+}
+
+// writeResults writes the results document of --output-json
+func writeResults(path string, doc *ResultsDocument) error {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write results: %v", err)
+	}
+	return nil
+}
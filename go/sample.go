@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	glideconfig "github.com/valkey-io/valkey-glide/go/api/config"
+)
+
+// scanKeys collects up to limit string keys matching the pattern with SCAN,
+// on the given route in cluster mode
+func scanKeys(client interface{}, route glideconfig.Route, pattern string, count, limit int) ([]string, error) {
+	var keys []string
+	cursor := "0"
+	for {
+		// Only strings can be read with GET
+		args := []string{"SCAN", cursor, "MATCH", pattern, "COUNT", strconv.Itoa(count), "TYPE", "string"}
+		reply, err := executeOnRoute(client, args, route)
+		if err != nil {
+			return nil, fmt.Errorf("failed to SCAN: %v", err)
+		}
+		fields, ok := reply.([]interface{})
+		if !ok || len(fields) != 2 {
+			return nil, fmt.Errorf("unexpected SCAN reply %v", reply)
+		}
+		cursor = fmt.Sprint(fields[0])
+		batch, _ := fields[1].([]interface{})
+		for _, key := range batch {
+			if len(keys) == limit {
+				return keys, nil
+			}
+			keys = append(keys, fmt.Sprint(key))
+		}
+		if cursor == "0" {
+			return keys, nil
+		}
+	}
+}
+
+// sampleKeys samples the existing keys that -t get reads with --scan-sample.
+// In cluster mode every primary is scanned for an equal share of the sample.
+func (b *Benchmark) sampleKeys() error {
+	client := b.poolClient(false, 0)
+	limit := b.config.ScanSample
+	var keys []string
+	if b.config.IsCluster {
+		shards, err := shardSlots(client)
+		if err != nil {
+			return err
+		}
+		share := (limit + len(shards) - 1) / len(shards)
+		for node := range shards {
+			route, err := glideconfig.NewByAddressRouteWithHost(node)
+			if err != nil {
+				return fmt.Errorf("failed to route SCAN to %s: %v", node, err)
+			}
+			nodeKeys, err := scanKeys(client, route, b.config.ScanMatch, b.config.ScanCount, share)
+			if err != nil {
+				return fmt.Errorf("%s: %v", node, err)
+			}
+			keys = append(keys, nodeKeys...)
+		}
+	} else {
+		var err error
+		if keys, err = scanKeys(client, glideconfig.RandomRoute, b.config.ScanMatch, b.config.ScanCount, limit); err != nil {
+			return err
+		}
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("SCAN found no keys matching %q to sample", b.config.ScanMatch)
+	}
+	b.sampledKeys = keys
+	fmt.Printf("Sampled %d existing keys matching %q with SCAN\n", len(keys), b.config.ScanMatch)
+	return nil
+}
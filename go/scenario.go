@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Scenario is an ordered list of phases executed in one invocation
+type Scenario struct {
+	Phases []ScenarioPhase `json:"phases"`
+}
+
+// ScenarioPhase describes the load of one phase and its expected outcome.
+// Unset load settings are inherited from the command line.
+type ScenarioPhase struct {
+	Name       string             `json:"name"`
+	Duration   int                `json:"duration"` // Seconds
+	Requests   int64              `json:"requests"` // Used when no duration is set
+	QPS        int                `json:"qps"`
+	Command    string             `json:"command"`
+	CommandMix string             `json:"command_mix"` // Command mix file of the phase, run as -t custom
+	DataSize   int                `json:"data_size"`
+	Expect     *PhaseExpectations `json:"expect"`
+}
+
+// PhaseExpectations are the assertions evaluated after a phase
+type PhaseExpectations struct {
+	MinRPS    float64 `json:"min_rps"`
+	MaxP99    float64 `json:"max_p99_ms"`
+	MaxErrors *int64  `json:"max_errors"`
+}
+
+// PhaseVerdict is the structured pass/fail result of one phase
+type PhaseVerdict struct {
+	Phase    string   `json:"phase"`
+	Passed   bool     `json:"passed"`
+	RPS      float64  `json:"rps"`
+	P99      float64  `json:"p99_ms"`
+	Errors   int64    `json:"errors"`
+	Failures []string `json:"failures,omitempty"`
+}
+
+// ScenarioOverall sums up all phases of a scenario
+type ScenarioOverall struct {
+	Phases   int     `json:"phases"`
+	Duration float64 `json:"duration_s"`
+	Requests int64   `json:"requests"`
+	Errors   int64   `json:"errors"`
+	RPS      float64 `json:"rps"`
+	P50      float64 `json:"p50_ms"`
+	P95      float64 `json:"p95_ms"`
+	P99      float64 `json:"p99_ms"`
+	Max      float64 `json:"max_ms"`
+}
+
+// LoadScenario reads and validates a JSON scenario file
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %v", err)
+	}
+	var scenario Scenario
+	if err := json.Unmarshal(data, &scenario); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %v", path, err)
+	}
+	if len(scenario.Phases) == 0 {
+		return nil, fmt.Errorf("scenario %s has no phases", path)
+	}
+	for i := range scenario.Phases {
+		phase := &scenario.Phases[i]
+		if phase.Name == "" {
+			phase.Name = fmt.Sprintf("phase-%d", i+1)
+		}
+		if phase.Duration <= 0 && phase.Requests <= 0 {
+			return nil, fmt.Errorf("scenario phase %q needs a duration or a request count", phase.Name)
+		}
+		phase.Command = strings.ToLower(phase.Command)
+		if phase.Command != "" && phase.Command != "set" && phase.Command != "get" && !isCustomWorkload(phase.Command) {
+			return nil, fmt.Errorf("scenario phase %q has unknown command %q", phase.Name, phase.Command)
+		}
+		if phase.CommandMix != "" && phase.Command != "" && phase.Command != "custom" {
+			return nil, fmt.Errorf("scenario phase %q sets both command and command_mix", phase.Name)
+		}
+	}
+	return &scenario, nil
+}
+
+// phaseConfig derives the configuration of a phase from the base configuration
+func phaseConfig(base *Config, phase *ScenarioPhase) *Config {
+	config := *base
+	// A phase with both a duration and a request count stops at whichever
+	// is reached first
+	config.TestDuration = phase.Duration
+	config.TotalRequests = phase.Requests
+	if phase.QPS > 0 {
+		config.QPS = phase.QPS
+		config.StartQPS = 0
+		config.EndQPS = 0
+		config.QPSChangeInterval = 0
+		config.TargetMbps = 0
+	}
+	if phase.Command != "" {
+		config.Command = phase.Command
+	}
+	if phase.CommandMix != "" {
+		config.Command = "custom"
+		config.CommandMixFile = phase.CommandMix
+		config.CommandTemplate = ""
+		config.WorkloadCommand = ""
+	}
+	if phase.DataSize > 0 {
+		config.DataSize = phase.DataSize
+	}
+	return &config
+}
+
+// Evaluate checks the phase expectations against its results
+func (e *PhaseExpectations) Evaluate(name string, summary StatsSummary) PhaseVerdict {
+	verdict := PhaseVerdict{Phase: name, Passed: true, RPS: summary.RPS, Errors: summary.Errors}
+	if summary.Latency != nil {
+		verdict.P99 = summary.Latency.p99
+	}
+	if e == nil {
+		return verdict
+	}
+
+	if e.MinRPS > 0 && summary.RPS < e.MinRPS {
+		verdict.Failures = append(verdict.Failures, fmt.Sprintf("rps %.2f < min %.2f", summary.RPS, e.MinRPS))
+	}
+	if e.MaxP99 > 0 {
+		if summary.Latency == nil {
+			verdict.Failures = append(verdict.Failures, "no successful requests to compute p99")
+		} else if summary.Latency.p99 > e.MaxP99 {
+			verdict.Failures = append(verdict.Failures, fmt.Sprintf("p99 %.3f ms > max %.3f ms", summary.Latency.p99, e.MaxP99))
+		}
+	}
+	if e.MaxErrors != nil && summary.Errors > *e.MaxErrors {
+		verdict.Failures = append(verdict.Failures, fmt.Sprintf("errors %d > max %d", summary.Errors, *e.MaxErrors))
+	}
+	verdict.Passed = len(verdict.Failures) == 0
+	return verdict
+}
+
+// RunScenario executes the phases in order, evaluates their expectations and
+// returns an error if any phase failed
+func (b *Benchmark) RunScenario(ctx context.Context, scenario *Scenario) error {
+	var verdicts []PhaseVerdict
+	var phases []*BenchmarkStats
+	for i := range scenario.Phases {
+		if ctx.Err() != nil {
+			break
+		}
+		phase := &scenario.Phases[i]
+		config := phaseConfig(b.config, phase)
+		if phase.CommandMix == "" && isCustomWorkload(config.Command) && config.Command != b.config.Command {
+			return fmt.Errorf("scenario phase %q uses -t %s, which must also be selected on the command line", phase.Name, config.Command)
+		}
+
+		fmt.Printf("\nScenario phase %d/%d: %s\n", i+1, len(scenario.Phases), phase.Name)
+		var stats *BenchmarkStats
+		if phase.CommandMix != "" {
+			restore, err := b.useCommandMix(config)
+			if err != nil {
+				return fmt.Errorf("scenario phase %q: %v", phase.Name, err)
+			}
+			stats = b.runStage(ctx, config)
+			restore()
+		} else {
+			stats = b.runStage(ctx, config)
+		}
+		stats.PrintFinalStats()
+
+		verdicts = append(verdicts, phase.Expect.Evaluate(phase.Name, stats.Summary()))
+		phases = append(phases, stats)
+	}
+
+	failed := printVerdicts(verdicts)
+	overall := scenarioOverall(phases)
+	printOverall(overall)
+	if b.config.ScenarioVerdictFile != "" {
+		if err := writeVerdicts(b.config.ScenarioVerdictFile, verdicts, overall, b.metadata); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d scenario phases failed their expectations", failed, len(verdicts))
+	}
+	return nil
+}
+
+// useCommandMix makes the custom workload issue the command mix of a phase and
+// returns the function restoring the workload of the command line
+func (b *Benchmark) useCommandMix(config *Config) (func(), error) {
+	mix, err := LoadCommandMix(config.CommandMixFile, config)
+	if err != nil {
+		return nil, err
+	}
+	factory, previous, names := b.newCustomCommand, b.mix, b.customCommandNames
+	b.newCustomCommand, b.mix, b.customCommandNames = newMixCommandFactory(mix), mix, nil
+	for _, entry := range mix.Entries {
+		b.customCommandNames = append(b.customCommandNames, entry.Template.name)
+	}
+	return func() {
+		b.newCustomCommand, b.mix, b.customCommandNames = factory, previous, names
+	}, nil
+}
+
+// scenarioOverall merges the statistics of all phases that ran
+func scenarioOverall(phases []*BenchmarkStats) ScenarioOverall {
+	overall := ScenarioOverall{Phases: len(phases)}
+	latencies := NewLatencyHistogram()
+	for _, stats := range phases {
+		summary := stats.Summary()
+		overall.Duration += summary.Duration
+		overall.Requests += summary.Requests
+		overall.Errors += summary.Errors
+		stats.mu.Lock()
+		latencies.Merge(stats.latencies)
+		stats.mu.Unlock()
+	}
+	if overall.Duration > 0 {
+		overall.RPS = float64(overall.Requests) / overall.Duration
+	}
+	if latency := latencies.Stats(); latency != nil {
+		overall.P50, overall.P95, overall.P99, overall.Max = latency.p50, latency.p95, latency.p99, latency.max
+	}
+	return overall
+}
+
+// printOverall prints the totals and latencies across all phases
+func printOverall(overall ScenarioOverall) {
+	fmt.Printf("\nScenario Overall (%d phases):\n", overall.Phases)
+	fmt.Printf("=================\n")
+	fmt.Printf("Total time: %.2f seconds\n", overall.Duration)
+	fmt.Printf("Requests: %d, RPS: %.2f, Errors: %d\n", overall.Requests, overall.RPS, overall.Errors)
+	fmt.Printf("Latency (ms) - p50: %.3f, p95: %.3f, p99: %.3f, max: %.3f\n", overall.P50, overall.P95, overall.P99, overall.Max)
+}
+
+// printVerdicts prints one line per phase and returns the number of failed phases
+func printVerdicts(verdicts []PhaseVerdict) int {
+	failed := 0
+	fmt.Printf("\n\nScenario Verdict:\n")
+	fmt.Printf("=================\n")
+	for _, verdict := range verdicts {
+		status := "PASS"
+		if !verdict.Passed {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Printf("%-4s %-20s RPS: %.2f, p99: %.3f ms, Errors: %d", status, verdict.Phase, verdict.RPS, verdict.P99, verdict.Errors)
+		if len(verdict.Failures) > 0 {
+			fmt.Printf(" (%s)", strings.Join(verdict.Failures, "; "))
+		}
+		fmt.Println()
+	}
+	return failed
+}
+
+// writeVerdicts writes the verdicts, the overall results and the run metadata
+// as a JSON document
+func writeVerdicts(path string, verdicts []PhaseVerdict, overall ScenarioOverall, metadata *RunMetadata) error {
+	passed := true
+	for _, verdict := range verdicts {
+		passed = passed && verdict.Passed
+	}
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"passed":   passed,
+		"phases":   verdicts,
+		"overall":  overall,
+		"metadata": metadata,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write scenario verdict: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"math/bits"
+	"sync/atomic"
+)
+
+// sizeHistogramBuckets covers sizes up to 2^40 bytes
+const sizeHistogramBuckets = 41
+
+// SizeHistogram counts payload sizes in power-of-two buckets. Bucket i holds
+// sizes in (2^(i-1), 2^i] bytes; bucket 0 holds sizes of 0 and 1 byte.
+type SizeHistogram struct {
+	counts [sizeHistogramBuckets]int64 // Updated atomically
+	total  int64                       // Sum of all recorded sizes
+	max    int64
+}
+
+// sizeBucket returns the bucket index of a size
+func sizeBucket(size int64) int {
+	if size <= 1 {
+		return 0
+	}
+	bucket := bits.Len64(uint64(size - 1))
+	if bucket >= sizeHistogramBuckets {
+		bucket = sizeHistogramBuckets - 1
+	}
+	return bucket
+}
+
+// Record counts a size in its bucket
+func (h *SizeHistogram) Record(size int64) {
+	atomic.AddInt64(&h.counts[sizeBucket(size)], 1)
+	atomic.AddInt64(&h.total, size)
+	for {
+		max := atomic.LoadInt64(&h.max)
+		if size <= max || atomic.CompareAndSwapInt64(&h.max, max, size) {
+			return
+		}
+	}
+}
+
+// Buckets returns the count of every non-empty bucket keyed by its upper bound in bytes
+func (h *SizeHistogram) Buckets() map[string]int64 {
+	buckets := make(map[string]int64)
+	for i := range h.counts {
+		if count := atomic.LoadInt64(&h.counts[i]); count > 0 {
+			buckets[fmt.Sprintf("le_%d", int64(1)<<i)] = count
+		}
+	}
+	return buckets
+}
+
+// formatSize renders a byte count with a binary unit
+func formatSize(size int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	unit := 0
+	for size >= 1024 && size%1024 == 0 && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%d %s", size, units[unit])
+}
+
+// Print outputs the size distribution under the given title
+func (h *SizeHistogram) Print(title string) {
+	var count int64
+	counts := make([]int64, len(h.counts))
+	for i := range h.counts {
+		counts[i] = atomic.LoadInt64(&h.counts[i])
+		count += counts[i]
+	}
+	if count == 0 {
+		return
+	}
+
+	fmt.Printf("\n%s (avg %.1f B, max %d B):\n", title,
+		float64(atomic.LoadInt64(&h.total))/float64(count), atomic.LoadInt64(&h.max))
+	fmt.Printf("=====================\n")
+	for i, n := range counts {
+		if n == 0 {
+			continue
+		}
+		fmt.Printf("<= %-10s %10d requests (%6.2f%%)\n", formatSize(int64(1)<<i), n, float64(n)*100/float64(count))
+	}
+}
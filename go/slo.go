@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+)
+
+// LatencyBuckets counts requests per latency bucket. A request with latency
+// below bounds[i] (and not below bounds[i-1]) falls into bucket i; the last
+// bucket holds everything at or above the highest bound.
+type LatencyBuckets struct {
+	bounds []float64 // Ascending upper bounds in milliseconds
+	counts []int64   // len(bounds)+1 counters, updated atomically
+}
+
+// NewLatencyBuckets creates bucket counters for the given bounds in milliseconds
+func NewLatencyBuckets(bounds []float64) *LatencyBuckets {
+	sorted := make([]float64, len(bounds))
+	copy(sorted, bounds)
+	sort.Float64s(sorted)
+	return &LatencyBuckets{
+		bounds: sorted,
+		counts: make([]int64, len(sorted)+1),
+	}
+}
+
+// Record counts a latency in its bucket
+func (b *LatencyBuckets) Record(latency float64) {
+	b.RecordN(latency, 1)
+}
+
+// RecordN counts n latencies of the same value, such as a histogram counter
+func (b *LatencyBuckets) RecordN(latency float64, n int64) {
+	idx := sort.Search(len(b.bounds), func(i int) bool { return latency < b.bounds[i] })
+	atomic.AddInt64(&b.counts[idx], n)
+}
+
+// Print outputs the fraction of requests in each bucket
+func (b *LatencyBuckets) Print() {
+	var total int64
+	counts := make([]int64, len(b.counts))
+	for i := range b.counts {
+		counts[i] = atomic.LoadInt64(&b.counts[i])
+		total += counts[i]
+	}
+	if total == 0 {
+		return
+	}
+
+	fmt.Printf("\nLatency SLO Buckets:\n")
+	fmt.Printf("=====================\n")
+	for i, count := range counts {
+		var label string
+		if i < len(b.bounds) {
+			label = fmt.Sprintf("< %g ms", b.bounds[i])
+		} else {
+			label = fmt.Sprintf(">= %g ms", b.bounds[len(b.bounds)-1])
+		}
+		fmt.Printf("%-12s %10d requests (%6.2f%%)\n", label, count, float64(count)*100/float64(total))
+	}
+}
+
+// ApdexCounter computes an Apdex score for a target threshold T. Requests
+// faster than or equal to T are satisfied, up to 4T tolerating, and slower
+// requests as well as errors are frustrated.
+type ApdexCounter struct {
+	threshold  float64 // Target threshold T in milliseconds
+	satisfied  int64
+	tolerating int64
+	frustrated int64
+}
+
+// NewApdexCounter creates an Apdex counter for the threshold in milliseconds
+func NewApdexCounter(threshold float64) *ApdexCounter {
+	return &ApdexCounter{threshold: threshold}
+}
+
+// Record classifies a successful request by its latency
+func (a *ApdexCounter) Record(latency float64) {
+	a.RecordN(latency, 1)
+}
+
+// RecordN classifies n successful requests of the same latency
+func (a *ApdexCounter) RecordN(latency float64, n int64) {
+	switch {
+	case latency <= a.threshold:
+		atomic.AddInt64(&a.satisfied, n)
+	case latency <= 4*a.threshold:
+		atomic.AddInt64(&a.tolerating, n)
+	default:
+		atomic.AddInt64(&a.frustrated, n)
+	}
+}
+
+// RecordError counts a failed request as frustrated
+func (a *ApdexCounter) RecordError() {
+	a.RecordErrors(1)
+}
+
+// RecordErrors counts n failed requests as frustrated
+func (a *ApdexCounter) RecordErrors(n int64) {
+	atomic.AddInt64(&a.frustrated, n)
+}
+
+// Score returns the Apdex score (satisfied + tolerating/2) / total, and false if nothing was recorded
+func (a *ApdexCounter) Score() (float64, bool) {
+	satisfied := atomic.LoadInt64(&a.satisfied)
+	tolerating := atomic.LoadInt64(&a.tolerating)
+	total := satisfied + tolerating + atomic.LoadInt64(&a.frustrated)
+	if total == 0 {
+		return 0, false
+	}
+	return (float64(satisfied) + float64(tolerating)/2) / float64(total), true
+}
+
+// apdexRating maps a score to the standard Apdex rating names
+func apdexRating(score float64) string {
+	switch {
+	case score >= 0.94:
+		return "Excellent"
+	case score >= 0.85:
+		return "Good"
+	case score >= 0.70:
+		return "Fair"
+	case score >= 0.50:
+		return "Poor"
+	}
+	return "Unacceptable"
+}
+
+// Print outputs the Apdex score with its request breakdown
+func (a *ApdexCounter) Print() {
+	score, ok := a.Score()
+	if !ok {
+		return
+	}
+	fmt.Printf("\nApdex (T = %g ms):\n", a.threshold)
+	fmt.Printf("=====================\n")
+	fmt.Printf("Score: %.3f (%s)\n", score, apdexRating(score))
+	fmt.Printf("Satisfied (<= %g ms): %d\n", a.threshold, atomic.LoadInt64(&a.satisfied))
+	fmt.Printf("Tolerating (<= %g ms): %d\n", 4*a.threshold, atomic.LoadInt64(&a.tolerating))
+	fmt.Printf("Frustrated (slower or failed): %d\n", atomic.LoadInt64(&a.frustrated))
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// clusterSlots is the number of hash slots of a Valkey cluster
+const clusterSlots = 16384
+
+// slotImbalanceThreshold flags a shard receiving this many times the mean
+// number of operations per shard
+const slotImbalanceThreshold = 1.25
+
+// CustomCommandKeyer can be implemented by custom commands to report the key
+// of the prepared request for the cluster slot balance report.
+type CustomCommandKeyer interface {
+	Key() string
+}
+
+// crc16Table is the CRC16-CCITT (XMODEM) table used for hash slots
+var crc16Table = func() [256]uint16 {
+	var table [256]uint16
+	for i := range table {
+		crc := uint16(i) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// keySlot returns the hash slot of a key, honouring {hash tags}
+func keySlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc = crc<<8 ^ crc16Table[byte(crc>>8)^key[i]]
+	}
+	return int(crc) % clusterSlots
+}
+
+// SlotCounter counts the operations issued per hash slot
+type SlotCounter struct {
+	counts [clusterSlots]int64
+}
+
+// Record counts an operation on a key
+func (c *SlotCounter) Record(key string) {
+	if c != nil && key != "" {
+		atomic.AddInt64(&c.counts[keySlot(key)], 1)
+	}
+}
+
+// shardSlots reads the slot ranges of every primary with CLUSTER SLOTS
+func shardSlots(client interface{}) (map[string][][2]int64, error) {
+	reply, err := executeCommand(client, []string{"CLUSTER", "SLOTS"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CLUSTER SLOTS: %v", err)
+	}
+	ranges, ok := reply.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected CLUSTER SLOTS reply %T", reply)
+	}
+	shards := make(map[string][][2]int64)
+	for _, entry := range ranges {
+		fields, ok := entry.([]interface{})
+		if !ok || len(fields) < 3 {
+			continue
+		}
+		start, err1 := toInt64(fields[0])
+		end, err2 := toInt64(fields[1])
+		primary, ok := fields[2].([]interface{})
+		if err1 != nil || err2 != nil || !ok || len(primary) < 2 {
+			continue
+		}
+		port, _ := toInt64(primary[1])
+		node := fmt.Sprintf("%v:%d", primary[0], port)
+		shards[node] = append(shards[node], [2]int64{start, end})
+	}
+	return shards, nil
+}
+
+// printSlotBalance prints how the operations of the run spread over hash
+// slots and shards, flagging shards well above the mean and hot slots
+func (b *Benchmark) printSlotBalance() {
+	var total int64
+	counts := make([]int64, clusterSlots)
+	touched := 0
+	for slot := range counts {
+		counts[slot] = atomic.LoadInt64(&b.slots.counts[slot])
+		total += counts[slot]
+		if counts[slot] > 0 {
+			touched++
+		}
+	}
+	if total == 0 {
+		return
+	}
+
+	fmt.Printf("\nSlot Balance (%d operations with known keys):\n", total)
+	fmt.Printf("============\n")
+	fmt.Printf("Slots used: %d of %d\n", touched, clusterSlots)
+
+	slots := make([]int, clusterSlots)
+	for slot := range slots {
+		slots[slot] = slot
+	}
+	sort.SliceStable(slots, func(i, j int) bool { return counts[slots[i]] > counts[slots[j]] })
+	fmt.Printf("Hottest slots:")
+	for _, slot := range slots[:5] {
+		if counts[slot] > 0 {
+			fmt.Printf(" %d (%.2f%%)", slot, 100*float64(counts[slot])/float64(total))
+		}
+	}
+	fmt.Println()
+
+	shards, err := shardSlots(b.poolClient(false, 0))
+	if err != nil {
+		slog.Warn("slot balance per shard unavailable", "error", err)
+		return
+	}
+	nodes := make([]string, 0, len(shards))
+	for node := range shards {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	mean := float64(total) / float64(len(nodes))
+	var imbalanced []string
+	fmt.Printf("%-25s %8s %14s %10s %10s\n", "Shard", "Slots", "Operations", "Share", "vs mean")
+	for _, node := range nodes {
+		var ops, owned int64
+		for _, r := range shards[node] {
+			for slot := r[0]; slot <= r[1] && slot < clusterSlots; slot++ {
+				ops += counts[slot]
+				owned++
+			}
+		}
+		ratio := float64(ops) / mean
+		flag := ""
+		if ratio >= slotImbalanceThreshold {
+			flag = "  IMBALANCED"
+			imbalanced = append(imbalanced, node)
+		}
+		fmt.Printf("%-25s %8d %14d %9.2f%% %9.2fx%s\n", node, owned, ops, 100*float64(ops)/float64(total), ratio, flag)
+	}
+	if len(imbalanced) > 0 {
+		fmt.Printf("Warning: %s received at least %.2fx the mean operations per shard; check the key generator and hash tags\n",
+			strings.Join(imbalanced, ", "), slotImbalanceThreshold)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/valkey-io/valkey-glide/go/api"
+)
+
+// StallWindow is one period during which the server was deliberately stalled
+type StallWindow struct {
+	Mode  string
+	Start time.Time
+	End   time.Time // Zero while the stall is in progress
+}
+
+// stallCommand returns the command stalling the server for the configured duration
+func stallCommand(config *Config) []string {
+	if config.StallMode == "pause" {
+		return []string{"CLIENT", "PAUSE", strconv.Itoa(config.StallDuration)}
+	}
+	seconds := float64(config.StallDuration) / 1000
+	return []string{"DEBUG", "SLEEP", strconv.FormatFloat(seconds, 'f', -1, 64)}
+}
+
+// createStallClient creates the dedicated client issuing stall and pause
+// commands, so that DEBUG SLEEP does not block a connection of the benchmark
+// workers and CLIENT PAUSE does not pause it. Its request timeout covers the stall.
+func createStallClient(config *Config) (interface{}, error) {
+	stallConfig := *config
+	stallConfig.RequestTimeout = config.StallDuration + 5000
+	if config.PauseDuration > config.StallDuration {
+		stallConfig.RequestTimeout = config.PauseDuration + 5000
+	}
+	return createClient(&stallConfig, api.Primary, fmt.Sprintf("vkbench-%s-stall", config.RunID))
+}
+
+// injectStalls stalls the server every StallInterval seconds until the context
+// is cancelled and records the injection windows in the stats. In cluster mode
+// all primaries are stalled at once.
+func (b *Benchmark) injectStalls(ctx context.Context, config *Config, stats *BenchmarkStats) {
+	ticker := time.NewTicker(time.Duration(config.StallInterval) * time.Second)
+	defer ticker.Stop()
+	args := stallCommand(config)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		stats.StartStall(config.StallMode)
+		start := time.Now()
+		_, err := executeOnAllPrimaries(b.stallClient, args)
+		if config.StallMode == "pause" && err == nil {
+			// CLIENT PAUSE returns immediately; the pause lasts for the given time
+			sleepContext(ctx, time.Duration(config.StallDuration)*time.Millisecond-time.Since(start))
+		}
+		stats.EndStall()
+		if err != nil {
+			slog.Error("stall injection failed", "mode", config.StallMode, "error", err)
+		}
+	}
+}
+
+// StartStall opens a new injection window
+func (s *BenchmarkStats) StartStall(mode string) {
+	s.mu.Lock()
+	s.stalls = append(s.stalls, StallWindow{Mode: mode, Start: time.Now()})
+	s.mu.Unlock()
+	if s.exporters != nil {
+		s.exporters.PublishEvent("stall-started", map[string]interface{}{"mode": mode})
+	}
+}
+
+// EndStall closes the open injection window
+func (s *BenchmarkStats) EndStall() {
+	s.mu.Lock()
+	n := len(s.stalls)
+	if n == 0 || !s.stalls[n-1].End.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	s.stalls[n-1].End = time.Now()
+	window := s.stalls[n-1]
+	s.mu.Unlock()
+
+	if s.exporters != nil {
+		s.exporters.PublishEvent("stall-ended", map[string]interface{}{
+			"mode":        window.Mode,
+			"duration_ms": window.End.Sub(window.Start).Milliseconds(),
+		})
+	}
+}
+
+// stalledBetween reports whether an injection window overlaps the given
+// period. Callers must hold s.mu.
+func (s *BenchmarkStats) stalledBetween(from, to time.Time) bool {
+	for _, stall := range s.stalls {
+		if stall.Start.Before(to) && (stall.End.IsZero() || stall.End.After(from)) {
+			return true
+		}
+	}
+	return false
+}
+
+// printStalls prints every injection window with the worst interval p99 while
+// it was active and the time until interval p99 returned to within 1.5x of the
+// median p99 of the unaffected intervals
+func (s *BenchmarkStats) printStalls() {
+	s.mu.Lock()
+	stalls := append([]StallWindow(nil), s.stalls...)
+	timeline := append([]IntervalStats(nil), s.timeline...)
+	s.mu.Unlock()
+	if len(stalls) == 0 {
+		return
+	}
+
+	var baseline []float64
+	for _, interval := range timeline {
+		if !interval.Stall && interval.Requests > 0 {
+			baseline = append(baseline, interval.P99)
+		}
+	}
+	threshold := 0.0
+	if len(baseline) > 0 {
+		sort.Float64s(baseline)
+		threshold = 1.5 * baseline[len(baseline)/2]
+	}
+
+	fmt.Printf("\nStall Injection (%d windows):\n", len(stalls))
+	fmt.Printf("================\n")
+	fmt.Printf("%10s %8s %12s %14s %14s\n", "Start (s)", "Mode", "Length (ms)", "Peak p99 (ms)", "Recovery (s)")
+	for _, stall := range stalls {
+		end := stall.End
+		if end.IsZero() {
+			end = s.endTime
+		}
+		peak := 0.0
+		recovery := "-"
+		intervalStart := s.startTime
+		for _, interval := range timeline {
+			from := intervalStart
+			intervalStart = interval.Time
+			if interval.Time.Before(stall.Start) {
+				continue
+			}
+			if from.Before(end) {
+				if interval.P99 > peak {
+					peak = interval.P99
+				}
+				continue
+			}
+			if threshold > 0 && interval.Requests > 0 && interval.P99 <= threshold {
+				recovery = fmt.Sprintf("%.1f", interval.Time.Sub(end).Seconds())
+				break
+			}
+		}
+		if recovery == "-" && threshold > 0 {
+			recovery = "not recovered"
+		}
+		fmt.Printf("%10.1f %8s %12d %14.3f %14s\n",
+			stall.Start.Sub(s.startTime).Seconds(), stall.Mode, end.Sub(stall.Start).Milliseconds(), peak, recovery)
+	}
+}
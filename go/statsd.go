@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// StatsDExporter sends interval metrics to a StatsD or DogStatsD agent over
+// UDP, all metrics of an interval in one datagram
+type StatsDExporter struct {
+	conn   net.Conn
+	prefix string
+	tags   string // DogStatsD tag suffix including the leading "|#" ("" for plain StatsD)
+}
+
+// NewStatsDExporter creates an exporter for the configured agent address
+func NewStatsDExporter(config *Config) (*StatsDExporter, error) {
+	conn, err := net.Dial("udp", config.StatsDAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to statsd: %v", err)
+	}
+	e := &StatsDExporter{conn: conn, prefix: config.StatsDPrefix}
+	if config.StatsDTags {
+		e.tags = "|#" + strings.Join([]string{
+			"run_id:" + config.RunID,
+			"command:" + config.Command,
+			"target:" + fmt.Sprintf("%s:%d", config.Host, config.Port),
+		}, ",")
+	}
+	return e, nil
+}
+
+// formatPacket renders one interval as StatsD lines: the request and error
+// counts as counters, the rate and latency percentiles (ms) as gauges
+func (e *StatsDExporter) formatPacket(interval IntervalStats) string {
+	metrics := []struct {
+		name  string
+		value string
+		kind  string
+	}{
+		{"requests", strconv.FormatInt(interval.Requests, 10), "c"},
+		{"errors", strconv.FormatInt(interval.Errors, 10), "c"},
+		{"rps", strconv.FormatFloat(interval.RPS, 'f', 2, 64), "g"},
+		{"latency.p50", strconv.FormatFloat(interval.P50, 'f', 3, 64), "g"},
+		{"latency.p95", strconv.FormatFloat(interval.P95, 'f', 3, 64), "g"},
+		{"latency.p99", strconv.FormatFloat(interval.P99, 'f', 3, 64), "g"},
+		{"latency.max", strconv.FormatFloat(interval.Max, 'f', 3, 64), "g"},
+	}
+	var sb strings.Builder
+	for i, metric := range metrics {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "%s.%s:%s|%s%s", e.prefix, metric.name, metric.value, metric.kind, e.tags)
+	}
+	return sb.String()
+}
+
+// ExportInterval sends the interval to the agent
+func (e *StatsDExporter) ExportInterval(interval IntervalStats) error {
+	if _, err := e.conn.Write([]byte(e.formatPacket(interval))); err != nil {
+		return fmt.Errorf("statsd: %v", err)
+	}
+	return nil
+}
+
+// Close closes the socket
+func (e *StatsDExporter) Close() error {
+	return e.conn.Close()
+}
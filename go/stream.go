@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websocketGUID is appended to the client key to compute Sec-WebSocket-Accept (RFC 6455)
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// streamBuffer is the number of messages queued per subscriber before a slow
+// subscriber starts missing messages
+const streamBuffer = 256
+
+// streamMessage is a JSON document sent to the live stream subscribers
+type streamMessage struct {
+	kind    string
+	payload []byte
+}
+
+// StreamExporter serves the interval statistics and lifecycle events of the
+// running benchmark as server-sent events on /events and as WebSocket text
+// frames on /ws, so a browser dashboard can render live charts. Messages use
+// the JSON format of the message bus exporter; subscribers joining late
+// first receive everything published so far.
+type StreamExporter struct {
+	runID    string
+	agent    string
+	server   *http.Server
+	mu       sync.Mutex
+	history  []streamMessage
+	channels map[chan streamMessage]struct{}
+	closed   bool
+}
+
+// NewStreamExporter starts the HTTP server of --stream-addr
+func NewStreamExporter(config *Config) (*StreamExporter, error) {
+	listener, err := net.Listen("tcp", config.StreamAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %v", config.StreamAddr, err)
+	}
+	agent, _ := os.Hostname()
+	e := &StreamExporter{runID: config.RunID, agent: agent, channels: make(map[chan streamMessage]struct{})}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", e.serveEvents)
+	mux.HandleFunc("/ws", e.serveWebSocket)
+	e.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := e.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			slog.Warn("live results stream stopped", "error", err)
+		}
+	}()
+	slog.Info("streaming live results", "events", "http://"+listener.Addr().String()+"/events",
+		"websocket", "ws://"+listener.Addr().String()+"/ws")
+	return e, nil
+}
+
+// publish sends a message to every subscriber and keeps it for late subscribers
+func (e *StreamExporter) publish(message busMessage) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	m := streamMessage{kind: message.Type, payload: payload}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.history = append(e.history, m)
+	for ch := range e.channels {
+		select {
+		case ch <- m:
+		default:
+			// The subscriber isn't keeping up; it misses this message
+		}
+	}
+	return nil
+}
+
+// ExportInterval streams an "interval" message
+func (e *StreamExporter) ExportInterval(interval IntervalStats) error {
+	return e.publish(busMessage{Type: "interval", RunID: e.runID, Agent: e.agent, Time: interval.Time, Interval: &interval})
+}
+
+// ExportEvent streams a lifecycle event message
+func (e *StreamExporter) ExportEvent(event BenchmarkEvent) error {
+	return e.publish(busMessage{Type: event.Type, RunID: e.runID, Agent: e.agent, Time: event.Time, Data: event.Data})
+}
+
+// subscribe returns a channel receiving the published messages, starting
+// with the history. The channel is closed when the exporter closes.
+func (e *StreamExporter) subscribe() chan streamMessage {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ch := make(chan streamMessage, len(e.history)+streamBuffer)
+	for _, m := range e.history {
+		ch <- m
+	}
+	if e.closed {
+		close(ch)
+	} else {
+		e.channels[ch] = struct{}{}
+	}
+	return ch
+}
+
+// unsubscribe stops delivering messages to a subscriber that went away
+func (e *StreamExporter) unsubscribe(ch chan streamMessage) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.channels[ch]; ok {
+		delete(e.channels, ch)
+		close(ch)
+	}
+}
+
+// serveEvents streams the messages as server-sent events named after their type
+func (e *StreamExporter) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	ch := e.subscribe()
+	defer e.unsubscribe(ch)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case m, ok := <-ch:
+			if !ok {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", m.kind, m.payload); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// serveWebSocket upgrades the connection and sends every message as a text frame
+func (e *StreamExporter) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || key == "" {
+		http.Error(w, "expected a WebSocket upgrade", http.StatusBadRequest)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket unsupported", http.StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	accept := sha1.Sum([]byte(key + websocketGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+		base64.StdEncoding.EncodeToString(accept[:]))
+	if err := rw.Flush(); err != nil {
+		return
+	}
+
+	// The client's frames are not used; reading detects when it goes away
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		io.Copy(io.Discard, rw.Reader)
+	}()
+
+	ch := e.subscribe()
+	defer e.unsubscribe(ch)
+	for {
+		select {
+		case <-gone:
+			return
+		case m, ok := <-ch:
+			if !ok {
+				writeWebSocketFrame(rw.Writer, 0x8, nil) // Close
+				rw.Flush()
+				return
+			}
+			if writeWebSocketFrame(rw.Writer, 0x1, m.payload) != nil || rw.Flush() != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeWebSocketFrame writes an unmasked, unfragmented server frame
+func writeWebSocketFrame(w *bufio.Writer, opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xffff:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// Close ends all subscriptions after the queued messages and stops the server
+func (e *StreamExporter) Close() error {
+	e.mu.Lock()
+	e.closed = true
+	for ch := range e.channels {
+		delete(e.channels, ch)
+		close(ch)
+	}
+	e.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return e.server.Shutdown(ctx)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// subprocessRequest is written to the workload subprocess to ask for the next command
+type subprocessRequest struct {
+	Type   string `json:"type"` // always "next"
+	Worker int    `json:"worker"`
+	Seq    int64  `json:"seq"`
+}
+
+// subprocessResult is written to the workload subprocess after a command was executed
+type subprocessResult struct {
+	Type      string      `json:"type"` // always "result"
+	Worker    int         `json:"worker"`
+	Seq       int64       `json:"seq"`
+	OK        bool        `json:"ok"`
+	LatencyMs float64     `json:"latency_ms"`
+	Error     string      `json:"error,omitempty"`
+	Response  interface{} `json:"response,omitempty"`
+}
+
+// subprocessSpec is read from the workload subprocess and describes the next command
+type subprocessSpec struct {
+	Args  []string `json:"args"`
+	Done  bool     `json:"done"`
+	Error string   `json:"error"`
+}
+
+// subprocessCommand is a CustomCommand driven by an external process speaking
+// newline-delimited JSON over stdin/stdout. One subprocess is started per worker.
+type subprocessCommand struct {
+	commandLine string
+	cmd         *exec.Cmd
+	stdin       io.WriteCloser
+	encoder     *json.Encoder
+	scanner     *bufio.Scanner
+	workerID    int
+	seq         int64
+	next        []string
+	reply       interface{}       // Reply to the last command
+	result      *subprocessResult // Outcome of the last command, not yet reported
+}
+
+// newSubprocessCommandFactory returns a factory creating subprocess-backed custom commands
+func newSubprocessCommandFactory(commandLine string) CustomCommandFactory {
+	return func() CustomCommand {
+		return &subprocessCommand{commandLine: commandLine}
+	}
+}
+
+// Setup starts the subprocess through the shell. The worker ID and plugin
+// arguments are exposed as VKBENCH_WORKER_ID and VKBENCH_ARGS.
+func (c *subprocessCommand) Setup(client interface{}, workerID int, args string) error {
+	c.workerID = workerID
+	c.cmd = exec.Command("sh", "-c", c.commandLine)
+	c.cmd.Env = append(os.Environ(),
+		fmt.Sprintf("VKBENCH_WORKER_ID=%d", workerID),
+		"VKBENCH_ARGS="+args)
+	c.cmd.Stderr = os.Stderr
+
+	stdin, err := c.cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := c.cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := c.cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start workload subprocess: %v", err)
+	}
+
+	c.stdin = stdin
+	c.encoder = json.NewEncoder(stdin)
+	c.scanner = bufio.NewScanner(stdout)
+	c.scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	return nil
+}
+
+// Prepare reports the outcome of the previous command and asks the subprocess
+// for the next one. It runs before the latency timer starts.
+func (c *subprocessCommand) Prepare() error {
+	if err := c.sendResult(); err != nil {
+		return err
+	}
+	c.seq++
+	if err := c.encoder.Encode(subprocessRequest{Type: "next", Worker: c.workerID, Seq: c.seq}); err != nil {
+		return fmt.Errorf("failed to write to workload subprocess: %v", err)
+	}
+	if !c.scanner.Scan() {
+		if err := c.scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read from workload subprocess: %v", err)
+		}
+		return errCustomCommandDone
+	}
+
+	var spec subprocessSpec
+	if err := json.Unmarshal(c.scanner.Bytes(), &spec); err != nil {
+		return fmt.Errorf("invalid workload subprocess output %q: %v", c.scanner.Text(), err)
+	}
+	if spec.Done {
+		return errCustomCommandDone
+	}
+	if spec.Error != "" {
+		return fmt.Errorf("workload subprocess: %s", spec.Error)
+	}
+	if len(spec.Args) == 0 {
+		return fmt.Errorf("workload subprocess returned an empty command")
+	}
+	c.next = spec.Args
+	return nil
+}
+
+// Execute runs the prepared command and keeps its outcome, which is reported
+// to the subprocess outside the timed section by the next Prepare or Teardown
+func (c *subprocessCommand) Execute(client interface{}) error {
+	start := time.Now()
+	response, err := executeCommand(client, c.next)
+	c.reply = response
+	c.result = &subprocessResult{
+		Type:      "result",
+		Worker:    c.workerID,
+		Seq:       c.seq,
+		OK:        err == nil,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000.0,
+		Response:  response,
+	}
+	if err != nil {
+		c.result.Error = err.Error()
+	}
+	return err
+}
+
+// sendResult writes the outcome of the last executed command to the subprocess
+func (c *subprocessCommand) sendResult() error {
+	if c.result == nil {
+		return nil
+	}
+	result := c.result
+	c.result = nil
+	err := c.encoder.Encode(result)
+	if _, ok := err.(*json.UnsupportedTypeError); ok {
+		// Responses that can't be represented in JSON are dropped from the report
+		result.Response = nil
+		err = c.encoder.Encode(result)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write to workload subprocess: %v", err)
+	}
+	return nil
+}
+
+// TransferredBytes approximates the encoded size of the last request and its reply
+func (c *subprocessCommand) TransferredBytes() (sent, received int64) {
+	return respCommandSize(c.next...), respReplySize(c.reply)
+}
+
+// Teardown reports the outcome of the last command, closes the subprocess's
+// stdin and waits for it to exit
+func (c *subprocessCommand) Teardown(client interface{}) error {
+	if c.cmd == nil {
+		return nil
+	}
+	resultErr := c.sendResult()
+	c.stdin.Close()
+	if err := c.cmd.Wait(); err != nil {
+		return fmt.Errorf("workload subprocess exited with error: %v", err)
+	}
+	return resultErr
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/valkey-io/valkey-glide/go/api"
+)
+
+// sweeping reports whether a parameter sweep was requested
+func sweeping(config *Config) bool {
+	return len(config.SweepDataSizes) > 0 || len(config.SweepPoolSizes) > 0 || len(config.SweepPipelines) > 0
+}
+
+// SweepPoint is one combination of a parameter sweep
+type SweepPoint struct {
+	DataSize int
+	PoolSize int
+	Pipeline int
+	Summary  StatsSummary
+}
+
+// RunSweep runs one fixed-length stage per combination of the swept data
+// sizes, pool sizes and pipeline depths and reports them side by side.
+// Parameters that are not swept keep their configured value. The pool is
+// recreated when its size changes.
+func (b *Benchmark) RunSweep(ctx context.Context) error {
+	dataSizes := []int(b.config.SweepDataSizes)
+	if len(dataSizes) == 0 {
+		dataSizes = []int{b.config.DataSize}
+	}
+	poolSizes := []int(b.config.SweepPoolSizes)
+	if len(poolSizes) == 0 {
+		poolSizes = []int{b.config.PoolSize}
+	}
+	pipelines := []int(b.config.SweepPipelines)
+	if len(pipelines) == 0 {
+		pipelines = []int{b.config.Pipeline}
+	}
+
+	var points []SweepPoint
+	total := len(dataSizes) * len(poolSizes) * len(pipelines)
+	for _, poolSize := range poolSizes {
+		if ctx.Err() != nil {
+			break
+		}
+		if err := b.resizePool(poolSize); err != nil {
+			return err
+		}
+		for _, pipeline := range pipelines {
+			for _, dataSize := range dataSizes {
+				if ctx.Err() != nil {
+					break
+				}
+
+				stageConfig := *b.config
+				stageConfig.DataSize = dataSize
+				stageConfig.PoolSize = poolSize
+				stageConfig.Pipeline = pipeline
+				stageConfig.TestDuration = b.config.SweepStageSeconds
+				stageConfig.TotalRequests = 0
+				stageConfig.RampDownSeconds = 0
+
+				fmt.Printf("\nSweep stage %d/%d: data size %d, pool size %d, pipeline %d for %d seconds\n",
+					len(points)+1, total, dataSize, poolSize, pipeline, stageConfig.TestDuration)
+
+				stats := b.runStage(ctx, &stageConfig)
+				points = append(points, SweepPoint{DataSize: dataSize, PoolSize: poolSize, Pipeline: pipeline, Summary: stats.Summary()})
+			}
+		}
+	}
+
+	printSweep(points)
+	if b.config.SweepCSV != "" {
+		if err := writeSweepCSV(b.config.SweepCSV, points); err != nil {
+			return err
+		}
+		fmt.Printf("Sweep written to %s\n", b.config.SweepCSV)
+	}
+	return nil
+}
+
+// resizePool replaces the primary client pool with one of the given size
+func (b *Benchmark) resizePool(size int) error {
+	b.poolMu.RLock()
+	current := len(b.clientPool)
+	b.poolMu.RUnlock()
+	if size == current {
+		return nil
+	}
+
+	readFrom := api.Primary
+	if b.config.ReadFromReplica {
+		readFrom = api.PreferReplica
+	}
+	poolConfig := *b.config
+	poolConfig.PoolSize = size
+	pool, err := createClientPool(&poolConfig, readFrom, "w")
+	if err != nil {
+		return err
+	}
+
+	b.poolMu.Lock()
+	old := b.clientPool
+	b.clientPool = pool
+	b.poolMu.Unlock()
+
+	closeClients(old)
+	return nil
+}
+
+// printSweep prints the sweep as a table
+func printSweep(points []SweepPoint) {
+	fmt.Printf("\n\nParameter Sweep:\n")
+	fmt.Printf("================\n")
+	fmt.Printf("%10s %10s %10s %14s %10s %10s %10s\n", "Data Size", "Pool Size", "Pipeline", "RPS", "Errors", "p50 (ms)", "p99 (ms)")
+	for _, point := range points {
+		p50, p99 := curveLatencies(point.Summary)
+		fmt.Printf("%10d %10d %10d %14.2f %10d %10.3f %10.3f\n",
+			point.DataSize, point.PoolSize, point.Pipeline, point.Summary.RPS, point.Summary.Errors, p50, p99)
+	}
+}
+
+// writeSweepCSV writes the sweep as CSV with a header row
+func writeSweepCSV(path string, points []SweepPoint) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create sweep CSV: %v", err)
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	w.Write([]string{"data_size", "pool_size", "pipeline", "rps", "requests", "errors", "p50_ms", "p99_ms"})
+	for _, point := range points {
+		p50, p99 := curveLatencies(point.Summary)
+		w.Write([]string{
+			strconv.Itoa(point.DataSize),
+			strconv.Itoa(point.PoolSize),
+			strconv.Itoa(point.Pipeline),
+			strconv.FormatFloat(point.Summary.RPS, 'f', 2, 64),
+			strconv.FormatInt(point.Summary.Requests, 10),
+			strconv.FormatInt(point.Summary.Errors, 10),
+			strconv.FormatFloat(p50, 'f', 3, 64),
+			strconv.FormatFloat(p99, 'f', 3, 64),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
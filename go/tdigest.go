@@ -0,0 +1,235 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// tdigestCompression bounds the number of centroids of the progress line
+// sketch to roughly this many; higher values trade memory for accuracy
+const tdigestCompression = 100
+
+// centroid is a cluster of samples summarized by their mean and count
+type centroid struct {
+	mean  float64
+	count float64
+}
+
+// TDigest is a merging t-digest: a fixed-size sketch of a latency
+// distribution whose quantile estimates are most accurate in the tails.
+// Samples are buffered and merged into the centroids in sorted batches, so
+// adding a sample is amortized O(1) and memory doesn't grow with the sample
+// count. Min, max and mean are tracked exactly.
+type TDigest struct {
+	compression float64
+	centroids   []centroid
+	buffer      []centroid
+	count       float64
+	sum         float64
+	min         float64
+	max         float64
+}
+
+// NewTDigest creates an empty sketch with the given compression
+func NewTDigest(compression float64) *TDigest {
+	return &TDigest{
+		compression: compression,
+		buffer:      make([]centroid, 0, int(5*compression)),
+	}
+}
+
+// Add records a sample
+func (t *TDigest) Add(value float64) {
+	if t.count == 0 || value < t.min {
+		t.min = value
+	}
+	if t.count == 0 || value > t.max {
+		t.max = value
+	}
+	t.count++
+	t.sum += value
+	t.buffer = append(t.buffer, centroid{mean: value, count: 1})
+	if len(t.buffer) == cap(t.buffer) {
+		t.merge()
+	}
+}
+
+// Merge adds all samples of other to the sketch
+func (t *TDigest) Merge(other *TDigest) {
+	if other.count == 0 {
+		return
+	}
+	if t.count == 0 || other.min < t.min {
+		t.min = other.min
+	}
+	if t.count == 0 || other.max > t.max {
+		t.max = other.max
+	}
+	t.count += other.count
+	t.sum += other.sum
+	other.merge()
+	for _, c := range other.centroids {
+		t.buffer = append(t.buffer, c)
+		if len(t.buffer) == cap(t.buffer) {
+			t.merge()
+		}
+	}
+}
+
+// Count returns the number of samples added since the last Reset
+func (t *TDigest) Count() int64 {
+	return int64(t.count)
+}
+
+// Reset discards all samples, keeping the allocated buffers
+func (t *TDigest) Reset() {
+	t.centroids = t.centroids[:0]
+	t.buffer = t.buffer[:0]
+	t.count, t.sum, t.min, t.max = 0, 0, 0, 0
+}
+
+// scale maps a quantile to the k-scale, which grows fastest near 0 and 1 so
+// centroids in the tails stay small
+func (t *TDigest) scale(q float64) float64 {
+	return t.compression / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+// merge folds the buffered samples into the centroids. Neighbouring
+// centroids are combined while the result spans at most one unit of the
+// k-scale.
+func (t *TDigest) merge() {
+	if len(t.buffer) == 0 {
+		return
+	}
+	points := append(t.buffer, t.centroids...)
+	sort.Slice(points, func(i, j int) bool { return points[i].mean < points[j].mean })
+
+	merged := make([]centroid, 0, len(t.centroids)+1)
+	current := points[0]
+	var cumulative float64 // Samples in the centroids before current
+	kLeft := t.scale(0)
+	for _, next := range points[1:] {
+		q := (cumulative + current.count + next.count) / t.count
+		if t.scale(q)-kLeft <= 1 {
+			current.count += next.count
+			current.mean += (next.mean - current.mean) * next.count / current.count
+			continue
+		}
+		merged = append(merged, current)
+		cumulative += current.count
+		kLeft = t.scale(cumulative / t.count)
+		current = next
+	}
+	t.centroids = append(merged, current)
+	t.buffer = t.buffer[:0]
+}
+
+// Quantile estimates the q-th quantile (0..1) by interpolating between
+// centroid means. It returns 0 for an empty sketch.
+func (t *TDigest) Quantile(q float64) float64 {
+	t.merge()
+	if len(t.centroids) == 0 {
+		return 0
+	}
+	if len(t.centroids) == 1 || q <= 0 {
+		if q >= 1 {
+			return t.max
+		}
+		if len(t.centroids) == 1 {
+			return t.centroids[0].mean
+		}
+		return t.min
+	}
+	if q >= 1 {
+		return t.max
+	}
+
+	index := q * t.count
+	first := t.centroids[0]
+	if index < first.count/2 {
+		return t.min + (first.mean-t.min)*index/(first.count/2)
+	}
+	cumulative := first.count / 2 // Samples up to the middle of centroid i
+	for i := 0; i < len(t.centroids)-1; i++ {
+		left, right := t.centroids[i], t.centroids[i+1]
+		delta := (left.count + right.count) / 2
+		if cumulative+delta > index {
+			return left.mean + (right.mean-left.mean)*(index-cumulative)/delta
+		}
+		cumulative += delta
+	}
+	last := t.centroids[len(t.centroids)-1]
+	remaining := t.count - cumulative // Half of the last centroid
+	if remaining <= 0 {
+		return t.max
+	}
+	return last.mean + (t.max-last.mean)*(index-cumulative)/remaining
+}
+
+// Stats summarizes the sketch like calculateLatencyStats, or returns nil if it is empty
+func (t *TDigest) Stats() *LatencyStats {
+	if t.count == 0 {
+		return nil
+	}
+	return &LatencyStats{
+		min:  t.min,
+		max:  t.max,
+		avg:  t.sum / t.count,
+		p50:  t.Quantile(0.50),
+		p95:  t.Quantile(0.95),
+		p99:  t.Quantile(0.99),
+		p999: t.Quantile(0.999),
+	}
+}
+
+// SlidingDigest keeps one sketch per reporting interval for the last few
+// intervals, so the live percentiles cover a fixed window instead of
+// whatever accumulated since the last progress line
+type SlidingDigest struct {
+	slots   []*TDigest
+	current int
+	merged  *TDigest
+}
+
+// NewSlidingDigest creates a window of the given number of intervals (at least one)
+func NewSlidingDigest(intervals int) *SlidingDigest {
+	if intervals < 1 {
+		intervals = 1
+	}
+	w := &SlidingDigest{
+		slots:  make([]*TDigest, intervals),
+		merged: NewTDigest(tdigestCompression),
+	}
+	for i := range w.slots {
+		w.slots[i] = NewTDigest(tdigestCompression)
+	}
+	return w
+}
+
+// Add records a sample in the current interval
+func (w *SlidingDigest) Add(value float64) {
+	w.slots[w.current].Add(value)
+}
+
+// Interval returns the sketch of the current interval
+func (w *SlidingDigest) Interval() *TDigest {
+	return w.slots[w.current]
+}
+
+// Stats summarizes all intervals in the window, or returns nil if they are empty
+func (w *SlidingDigest) Stats() *LatencyStats {
+	if len(w.slots) == 1 {
+		return w.slots[0].Stats()
+	}
+	w.merged.Reset()
+	for _, slot := range w.slots {
+		w.merged.Merge(slot)
+	}
+	return w.merged.Stats()
+}
+
+// Rotate starts a new interval, dropping the oldest one
+func (w *SlidingDigest) Rotate() {
+	w.current = (w.current + 1) % len(w.slots)
+	w.slots[w.current].Reset()
+}
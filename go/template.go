@@ -0,0 +1,315 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// templateContext holds the per-worker state used when rendering a command template
+type templateContext struct {
+	threadID int
+	counter  int64 // Worker-local request counter
+	rng      *rand.Rand
+}
+
+// templateSegment renders one part of a command argument
+type templateSegment func(ctx *templateContext, sb *strings.Builder)
+
+// CommandTemplate is a parsed command line whose arguments may contain
+// {{...}} expressions evaluated per request:
+//
+//	{{counter}}          worker-local counter, incremented per request
+//	{{gcounter}}         counter shared by all workers
+//	{{seq:N}}            counter shared by all workers, modulo N
+//	{{thread}}           worker thread ID
+//	{{rand:MIN:MAX}}     random integer in [MIN, MAX]
+//	{{randkey}}          random 12 digit number of the -r keyspace
+//	{{choice:A,B,C}}     one of the listed values
+//	{{data}}             random string of --datasize bytes
+//	{{data:N}}           random string of N bytes
+//	{{prefix}}           key prefix (includes the run ID with --namespace-keys)
+//	{{run}}              run ID
+//	{{vector}}           FLOAT32 vector blob of --vector-dim, random or from --vector-file
+//	{{vector:next}}      next vector of --vector-file, in file order
+type CommandTemplate struct {
+	source string
+	args   [][]templateSegment
+	name   string // Command name used for reporting, e.g. "HSET"
+}
+
+// globalTemplateCounter backs {{gcounter}}
+var globalTemplateCounter int64
+
+// ParseCommandTemplate compiles a template. Arguments are separated by
+// whitespace; double quotes group an argument containing spaces.
+func ParseCommandTemplate(source string, config *Config) (*CommandTemplate, error) {
+	words, err := splitCommandLine(source)
+	if err != nil {
+		return nil, err
+	}
+	return newCommandTemplate(source, words, config)
+}
+
+// newCommandTemplate compiles already split arguments, which may contain whitespace
+func newCommandTemplate(source string, words []string, config *Config) (*CommandTemplate, error) {
+	if len(words) == 0 {
+		return nil, fmt.Errorf("empty command template")
+	}
+
+	t := &CommandTemplate{source: source, name: strings.ToUpper(words[0])}
+	for _, word := range words {
+		segments, err := parseTemplateWord(word, config)
+		if err != nil {
+			return nil, fmt.Errorf("invalid command template %q: %v", source, err)
+		}
+		t.args = append(t.args, segments)
+	}
+	return t, nil
+}
+
+// Render evaluates the template for one request
+func (t *CommandTemplate) Render(ctx *templateContext) []string {
+	args := make([]string, len(t.args))
+	var sb strings.Builder
+	for i, segments := range t.args {
+		sb.Reset()
+		for _, segment := range segments {
+			segment(ctx, &sb)
+		}
+		args[i] = sb.String()
+	}
+	return args
+}
+
+// splitCommandLine splits a command line on whitespace, honouring double quotes
+func splitCommandLine(line string) ([]string, error) {
+	var words []string
+	var current strings.Builder
+	inQuotes, hasWord := false, false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			hasWord = true
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if hasWord {
+				words = append(words, current.String())
+				current.Reset()
+				hasWord = false
+			}
+		default:
+			current.WriteRune(r)
+			hasWord = true
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quote in %q", line)
+	}
+	if hasWord {
+		words = append(words, current.String())
+	}
+	return words, nil
+}
+
+// expandBenchmarkPlaceholders rewrites the placeholders of redis-benchmark
+// command lines into template expressions: __data__ becomes {{data}} and, with
+// -r, __rand_int__ becomes {{randkey}}. Without -r __rand_int__ is kept
+// verbatim, as redis-benchmark does.
+func expandBenchmarkPlaceholders(word string, config *Config) string {
+	word = strings.ReplaceAll(word, "__data__", "{{data}}")
+	if config.RandomKeyspace > 0 {
+		word = strings.ReplaceAll(word, "__rand_int__", "{{randkey}}")
+	}
+	return word
+}
+
+// parseTemplateWord splits one argument into literal and expression segments
+func parseTemplateWord(word string, config *Config) ([]templateSegment, error) {
+	word = expandBenchmarkPlaceholders(word, config)
+	var segments []templateSegment
+	for len(word) > 0 {
+		start := strings.Index(word, "{{")
+		if start < 0 {
+			segments = append(segments, literalSegment(word))
+			break
+		}
+		if start > 0 {
+			segments = append(segments, literalSegment(word[:start]))
+		}
+		end := strings.Index(word[start:], "}}")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated expression in %q", word)
+		}
+		segment, err := parseTemplateExpression(word[start+2:start+end], config)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment)
+		word = word[start+end+2:]
+	}
+	return segments, nil
+}
+
+func literalSegment(text string) templateSegment {
+	return func(ctx *templateContext, sb *strings.Builder) {
+		sb.WriteString(text)
+	}
+}
+
+// parseTemplateExpression compiles the content of a {{...}} expression
+func parseTemplateExpression(expr string, config *Config) (templateSegment, error) {
+	name, arg, _ := strings.Cut(strings.TrimSpace(expr), ":")
+	switch name {
+	case "counter":
+		return func(ctx *templateContext, sb *strings.Builder) {
+			sb.WriteString(strconv.FormatInt(ctx.counter, 10))
+		}, nil
+
+	case "gcounter":
+		return func(ctx *templateContext, sb *strings.Builder) {
+			sb.WriteString(strconv.FormatInt(atomic.AddInt64(&globalTemplateCounter, 1)-1, 10))
+		}, nil
+
+	case "seq":
+		nStr, startStr, hasStart := strings.Cut(arg, ":")
+		n, err := strconv.ParseInt(nStr, 10, 64)
+		var start int64
+		if err == nil && hasStart {
+			start, err = strconv.ParseInt(startStr, 10, 64)
+		}
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("seq expects {{seq:N}} or {{seq:N:START}} with N > 0, got {{%s}}", expr)
+		}
+		return func(ctx *templateContext, sb *strings.Builder) {
+			sb.WriteString(strconv.FormatInt(start+(atomic.AddInt64(&globalTemplateCounter, 1)-1)%n, 10))
+		}, nil
+
+	case "thread":
+		return func(ctx *templateContext, sb *strings.Builder) {
+			sb.WriteString(strconv.Itoa(ctx.threadID))
+		}, nil
+
+	case "rand":
+		minStr, maxStr, ok := strings.Cut(arg, ":")
+		if !ok {
+			return nil, fmt.Errorf("rand expects {{rand:MIN:MAX}}, got {{%s}}", expr)
+		}
+		lo, err1 := strconv.ParseInt(minStr, 10, 64)
+		hi, err2 := strconv.ParseInt(maxStr, 10, 64)
+		if err1 != nil || err2 != nil || hi < lo {
+			return nil, fmt.Errorf("invalid range in {{%s}}", expr)
+		}
+		span := hi - lo + 1
+		return func(ctx *templateContext, sb *strings.Builder) {
+			sb.WriteString(strconv.FormatInt(lo+ctx.rng.Int63n(span), 10))
+		}, nil
+
+	case "randkey":
+		keyspace, offset := config.RandomKeyspace, config.KeyspaceOffset
+		if keyspace <= 0 {
+			return nil, fmt.Errorf("{{randkey}} requires -r")
+		}
+		return func(ctx *templateContext, sb *strings.Builder) {
+			fmt.Fprintf(sb, "%012d", offset+ctx.rng.Int63n(keyspace))
+		}, nil
+
+	case "choice":
+		choices := strings.Split(arg, ",")
+		if arg == "" {
+			return nil, fmt.Errorf("choice expects {{choice:A,B,...}}, got {{%s}}", expr)
+		}
+		return func(ctx *templateContext, sb *strings.Builder) {
+			sb.WriteString(choices[ctx.rng.Intn(len(choices))])
+		}, nil
+
+	case "data":
+		size := config.DataSize
+		if arg != "" {
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid size in {{%s}}", expr)
+			}
+			size = n
+		}
+		if config.RandomizeData {
+			return randomDataSegment(size), nil
+		}
+		// Generated once per template and reused, like the SET payload
+		data := generateRandomData(size)
+		if dataGenerator != nil {
+			data = dataGenerator(benchRand, size)
+		}
+		return literalSegment(data), nil
+
+	case "prefix":
+		return literalSegment(keyPrefix(config)), nil
+
+	case "run":
+		return literalSegment(config.RunID), nil
+
+	case "vector":
+		return vectorSegment(arg, config)
+	}
+	return nil, fmt.Errorf("unknown template expression {{%s}}", expr)
+}
+
+// templateCommand is a CustomCommand executing a rendered command template
+type templateCommand struct {
+	template *CommandTemplate
+	ctx      templateContext
+	next     []string
+	reply    interface{}
+}
+
+// newTemplateCommandFactory returns a factory creating template-backed custom commands
+func newTemplateCommandFactory(template *CommandTemplate) CustomCommandFactory {
+	return func() CustomCommand {
+		return &templateCommand{template: template}
+	}
+}
+
+func (c *templateCommand) Setup(client interface{}, workerID int, args string) error {
+	c.ctx = templateContext{
+		threadID: workerID,
+		rng:      rand.New(rand.NewSource(benchRand.Int63())),
+	}
+	return nil
+}
+
+// Prepare renders the arguments for the next request outside of the timed section
+func (c *templateCommand) Prepare() error {
+	c.next = c.template.Render(&c.ctx)
+	c.ctx.counter++
+	return nil
+}
+
+// Key returns the first argument of the prepared request, which is the key of most commands
+func (c *templateCommand) Key() string {
+	if len(c.next) < 2 {
+		return ""
+	}
+	return c.next[1]
+}
+
+// CommandName returns the command of the prepared request
+func (c *templateCommand) CommandName() string {
+	return commandName(c.next)
+}
+
+func (c *templateCommand) Execute(client interface{}) error {
+	var err error
+	c.reply, err = executeCommand(client, c.next)
+	return err
+}
+
+// TransferredBytes approximates the encoded size of the last request and its reply
+func (c *templateCommand) TransferredBytes() (sent, received int64) {
+	return respCommandSize(c.next...), respReplySize(c.reply)
+}
+
+func (c *templateCommand) Teardown(client interface{}) error {
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// IntervalStats holds the statistics of one reporting interval
+type IntervalStats struct {
+	Time     time.Time `json:"time"`      // End of the interval
+	Elapsed  float64   `json:"elapsed_s"` // Seconds since the start of the run
+	Requests int64     `json:"requests"`  // Successful requests in the interval
+	Errors   int64     `json:"errors"`    // Failed requests in the interval
+	RPS      float64   `json:"rps"`       // Successful requests per second in the interval
+	P50      float64   `json:"p50_ms"`    // Interval latency percentiles in ms (0 without requests)
+	P95      float64   `json:"p95_ms"`
+	P99      float64   `json:"p99_ms"`
+	Max      float64   `json:"max_ms"`
+	Stall    bool      `json:"stall,omitempty"`        // A stall was injected during the interval
+	ProbeP50 float64   `json:"probe_p50_ms,omitempty"` // RTT probe percentiles in ms (0 without --rtt-probe)
+	ProbeP99 float64   `json:"probe_p99_ms,omitempty"`
+	Keyspace int64     `json:"keyspace,omitempty"` // Random keyspace at the end of the interval with --keyspace-growth
+	Evicted  int64     `json:"evicted,omitempty"`  // Keys evicted during the interval with --eviction-pressure
+}
+
+// recordInterval appends the statistics of the interval ending now to the
+// timeline. Callers must hold s.mu.
+func (s *BenchmarkStats) recordInterval(now time.Time, completed int64, window *LatencyStats) {
+	errors := atomic.LoadInt64(&s.errors)
+	interval := IntervalStats{
+		Time:     now,
+		Elapsed:  now.Sub(s.startTime).Seconds(),
+		Requests: completed - s.lastRequests,
+		Errors:   errors - s.lastErrors,
+		Stall:    s.stalledBetween(s.lastPrint, now),
+	}
+	if seconds := now.Sub(s.lastPrint).Seconds(); seconds > 0 {
+		interval.RPS = float64(interval.Requests) / seconds
+	}
+	if window != nil {
+		interval.P50 = window.p50
+		interval.P95 = window.p95
+		interval.P99 = window.p99
+		interval.Max = window.max
+	}
+	if s.config.KeyspaceGrowth > 0 {
+		interval.Keyspace = growingKeyspace(s.config, now.Sub(s.startTime))
+	}
+	if s.eviction != nil {
+		interval.Evicted = s.eviction.intervalEvictions()
+	}
+	if s.probe != nil {
+		if probe := s.probe.drain(); probe != nil {
+			interval.ProbeP50 = probe.p50
+			interval.ProbeP99 = probe.p99
+		}
+	}
+	s.timeline = append(s.timeline, interval)
+	s.lastErrors = errors
+	if s.exporters != nil {
+		s.exporters.Publish(interval)
+	}
+}
+
+// Timeline returns a copy of the per-interval statistics recorded so far
+func (s *BenchmarkStats) Timeline() []IntervalStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	timeline := make([]IntervalStats, len(s.timeline))
+	copy(timeline, s.timeline)
+	return timeline
+}
+
+// printTimelineSummary prints how interval p99 varied over the run and the
+// intervals with the worst tail latency
+func (s *BenchmarkStats) printTimelineSummary() {
+	timeline := s.Timeline()
+	var withRequests []IntervalStats
+	for _, interval := range timeline {
+		if interval.Requests > 0 {
+			withRequests = append(withRequests, interval)
+		}
+	}
+	if len(withRequests) < 2 {
+		return
+	}
+
+	p99s := make([]float64, len(withRequests))
+	for i, interval := range withRequests {
+		p99s[i] = interval.P99
+	}
+	sort.Float64s(p99s)
+
+	fmt.Printf("\nInterval p99 Timeline (ms, %d intervals):\n", len(withRequests))
+	fmt.Printf("=====================\n")
+	fmt.Printf("Best: %.3f, Median: %.3f, Worst: %.3f\n", p99s[0], p99s[len(p99s)/2], p99s[len(p99s)-1])
+
+	worst := make([]IntervalStats, len(withRequests))
+	copy(worst, withRequests)
+	sort.SliceStable(worst, func(i, j int) bool { return worst[i].P99 > worst[j].P99 })
+	if len(worst) > 5 {
+		worst = worst[:5]
+	}
+	fmt.Printf("Worst intervals:\n")
+	for _, interval := range worst {
+		fmt.Printf("  t=%7.1fs p99: %.3f, max: %.3f, RPS: %.2f, Errors: %d\n",
+			interval.Elapsed, interval.P99, interval.Max, interval.RPS, interval.Errors)
+	}
+}
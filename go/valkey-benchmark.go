@@ -5,162 +5,507 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"log/slog"
 	"math"
 	"math/rand"
 	"os"
 	"os/signal"
 	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/valkey-io/valkey-glide/go/api"
+	glideconfig "github.com/valkey-io/valkey-glide/go/api/config"
 )
 
 // Configuration holds all benchmark settings
 type Config struct {
-	Host              string
-	Port              int
-	PoolSize          int
-	TotalRequests     int64
-	DataSize          int
-	Command           string
-	RandomKeyspace    int64
-	NumThreads        int
-	TestDuration      int
-	UseSequential     bool
-	SequentialKeyLen  int64
-	QPS               int
-	StartQPS          int
-	EndQPS            int
-	QPSChangeInterval int
-	QPSChange         int
-	QPSRampMode       string  // "linear" or "exponential"
-	QPSRampFactor     float64 // Explicit multiplier for exponential mode (0 = auto-calculate)
-	UseTLS            bool
-	IsCluster         bool
-	ReadFromReplica   bool
-	RequestTimeout    int // Request timeout in milliseconds
+	Host                 string
+	Port                 int
+	ConfigEndpoint       string // Cluster configuration endpoint of a managed service
+	PoolSize             int
+	NodePool             string // Connections per cluster node of --node-pool (count or host:port=count list)
+	WarmupOps            int    // PING and SET operations per client before the measured phase
+	Warmup               int    // Seconds of workload before the measured phase, excluded from the results
+	Populate             bool   // Load every key of the keyspace before the measured phase
+	TotalRequests        int64
+	DataSize             int
+	RandomizeData        bool   // Generate a fresh value for every SET instead of reusing one
+	DataTemplate         string // Generator of the values: json, json:<schema file>, csv or binary
+	Command              string
+	Ratio                string // SET:GET mix of -t set and get (empty for a single command)
+	Pipeline             int    // Concurrent in-flight requests per worker
+	RandomKeyspace       int64
+	KeyspaceOffset       int64   // First key number of the -r and -sequential keyspaces
+	KeyspaceGrowth       float64 // Keys per second added to the random keyspace during the run
+	KeyspaceMax          int64   // Upper bound of the growing keyspace (0 = unbounded)
+	ScanSample           int     // Existing keys sampled with SCAN for -t get (0 disables)
+	ScanMatch            string  // MATCH pattern of the sampled keys
+	ScanCount            int     // COUNT hint of the sampling SCAN calls
+	NumThreads           int
+	TestDuration         int
+	RampDownSeconds      int    // Final seconds of a timed run during which load decreases to zero
+	CheckpointFile       string // Periodically persist cumulative stats to this file
+	CheckpointInterval   int    // Seconds between checkpoints
+	Resume               bool   // Continue from the checkpoint file if it exists
+	WatchdogSeconds      int    // Deadline after which a request is reported as stalled
+	StallInterval        int    // Seconds between injected server stalls (0 disables)
+	StallDuration        int    // Length of each injected stall in milliseconds
+	StallMode            string // sleep (DEBUG SLEEP) or pause (CLIENT PAUSE)
+	PauseAt              int    // Seconds into the run at which CLIENT PAUSE is issued (0 disables)
+	PauseDuration        int    // Length of the --pause-at pause in milliseconds
+	PauseMode            string // Clients paused: write or all
+	WatchdogRecycle      bool   // Replace the client of a stalled request
+	DumpDir              string // Directory for diagnostic dumps written on SIGQUIT
+	UseSequential        bool
+	SequentialKeyLen     int64
+	Deterministic        bool  // Derive keys and values from the seed and request number
+	RequestOffset        int64 // Number of the first request of a --processes child
+	QPS                  int
+	StartQPS             int
+	EndQPS               int
+	QPSChangeInterval    int
+	QPSChange            int
+	QPSRampMode          string  // "linear" or "exponential"
+	QPSRampFactor        float64 // Explicit multiplier for exponential mode (0 = auto-calculate)
+	QPSProfile           string  // QPS waveform between start-qps and end-qps: "sine" ("" for none)
+	QPSPeriod            int     // Period of the QPS waveform in seconds
+	QPSSchedule          string  // CSV file of time,qps rows replayed as the QPS target
+	PacingJitter         float64 // Randomize each inter-request gap by ±P percent
+	Arrival              string  // Request arrival process: "uniform" or "poisson"
+	QPSBurst             int     // Requests that may be sent back to back to catch up on missed slots
+	BusyPoll             bool    // Spin instead of sleeping while pacing and lock workers to OS threads
+	TargetMbps           float64 // Bandwidth limit in megabits per second (alternative to QPS)
+	MaxInflight          int     // Global cap of requests in flight (closed-loop mode)
+	UseTLS               bool
+	Password             string // Password of -a
+	User                 string // ACL user of --user (default: the default user)
+	AuthProvider         string // Token provider of --auth-provider (iam or command)
+	AuthUser             string // User authenticated with provider tokens
+	AuthCommand          string // Shell command printing a token
+	AuthTokenTTL         int    // Lifetime in seconds of command tokens
+	AuthFile             string // File holding the password of the file provider
+	AuthEnv              string // Environment variable holding the password of the env provider
+	IAMCacheName         string // Replication group or cluster name signed into IAM tokens
+	IAMRegion            string // AWS region of IAM tokens
+	IsCluster            bool
+	ReadFromReplica      bool
+	ReplicaReadRatio     int         // Percentage of reads sent with PreferReplica (0 = disabled)
+	ClientNoEvict        bool        // Issue CLIENT NO-EVICT on for every connection
+	ClientNoTouch        bool        // Issue CLIENT NO-TOUCH on for every connection
+	RunID                string      // Identifier for this run, used to tag connections
+	NamespaceKeys        bool        // Include the run ID in all generated keys
+	KeyPrefix            string      // Prefix of all generated keys
+	PluginPath           string      // Go plugin (.so) providing the custom command
+	PluginArgs           string      // Free-form arguments passed to the plugin's Setup
+	WorkloadCommand      string      // Subprocess generating custom commands over NDJSON
+	CommandTemplate      string      // Command template rendered per request for -t custom
+	CommandLine          string      // Command line with redis-benchmark placeholders (-command)
+	CommandMixFile       string      // File of weighted command templates sampled per request
+	JSONDocument         string      // Document template of -t json.set
+	JSONPath             string      // JSONPath template of -t json.get and json.arrappend
+	JSONValue            string      // Value template of -t json.arrappend
+	SearchIndex          string      // FT index name of -t ft.search and ft.knn
+	SearchSchema         string      // FT.CREATE schema
+	SearchDocument       string      // Field/value template of the indexed hashes
+	SearchDocuments      int64       // Number of documents indexed before the queries
+	SearchQuery          string      // FT.SEARCH query template
+	SearchLimit          int         // Maximum results per query
+	BloomCapacity        int64       // Capacity of the reserved Bloom filters
+	BloomErrorRate       float64     // False positive rate of the reserved Bloom filters
+	BloomFilters         int         // Number of Bloom filters
+	BloomItem            string      // Item template of -t bf.add and bf.exists
+	VectorDim            int         // Dimensionality of the vectors of -t ft.knn
+	VectorK              int         // Neighbours returned per KNN query
+	VectorDocuments      int64       // Number of vectors indexed before the queries
+	VectorFile           string      // File with embedding vectors
+	VectorMetric         string      // Distance metric of the vector index
+	VectorAlgorithm      string      // Vector index algorithm
+	PageSize             int64       // Elements per window of -t lrange and zrange
+	PageStride           int64       // Offset advance between windows
+	CollectionSize       int64       // Elements per paged collection
+	Collections          int         // Number of paged collections
+	PageFill             bool        // Recreate the collections before paging
+	ChurnKeys            int64       // Keys held by -t churn
+	ExpiryTTL            int64       // TTL in ms of the keys of -t expiry
+	ExpiryWindow         int64       // Reads of -t expiry span this many ms around the expiry
+	ExpiryReads          int         // Reads per key of -t expiry
+	EvictionPressure     bool        // Write past maxmemory and report evictions
+	ServerBackoff        int         // Maximum backoff in ms after OOM or LOADING replies (0 disables)
+	StatsBatch           int         // Requests a worker accumulates before flushing them to the shared stats
+	StatsFlush           int         // Milliseconds after which a worker flushes its accumulated requests
+	ProgressWindow       int         // Seconds of latencies covered by the progress line percentiles
+	Processes            int         // Child processes running the benchmark (1 runs it in this process)
+	ProcessIndex         int         // Index of this child process of a --processes run
+	ProcessResult        string      // File receiving the final stats of a child process
+	ProcessReady         string      // File created by child 0 once it has populated and warmed up (empty if unused)
+	LatencyBuckets       Float64List // Upper bounds (ms) of latency SLO buckets
+	ApdexThreshold       float64     // Apdex target threshold T in ms (0 = disabled)
+	CurveQPS             IntList     // Offered QPS per stage in throughput-latency curve mode
+	CurveStageSeconds    int         // Duration of each curve stage in seconds
+	CurveCSV             string      // Optional CSV file for the curve results
+	SweepDataSizes       IntList     // Data sizes of the parameter sweep
+	SweepPoolSizes       IntList     // Client pool sizes of the parameter sweep
+	SweepPipelines       IntList     // Pipeline depths of the parameter sweep
+	SweepStageSeconds    int         // Duration of each sweep stage in seconds
+	SweepCSV             string      // Optional CSV file for the sweep results
+	ConfigSnapshotFile   string      // File the server CONFIG snapshot is written to
+	ConfigDiffFile       string      // Snapshot of an earlier run to diff the server CONFIG against
+	Seed                 int64       // Seed of the generated data and random choices
+	ManifestFile         string      // File the reproducibility manifest is written to
+	LogLevel             string      // Level of internal logs
+	LogFormat            string      // text or json
+	LogFile              string      // File internal logs are appended to (default stderr)
+	KneeSearch           bool        // Sweep thread counts to find the throughput knee
+	KneeMaxThreads       int         // Highest thread count of the knee search
+	ScenarioFile         string      // JSON scenario of phases with expectations
+	ScenarioVerdictFile  string      // Write the structured scenario verdict to this file
+	Workflow             string      // Comma-separated workflow stages
+	WorkflowStages       []string    // Parsed workflow stages
+	LatencyDumpFile      string      // Write raw latencies to this gzip-compressed binary file
+	LatencyDumpSample    int         // Reservoir sample size for the latency dump (0 = all)
+	CSV                  bool        // Print the results as redis-benchmark --csv instead of the report
+	MemtierStats         bool        // Add the memtier_benchmark ALL STATS table to the report
+	OutputJSON           string      // Write the final results as JSON to this file
+	IntervalCSV          string      // Append one CSV row per reporting interval to this file
+	InfluxFile           string      // Append interval metrics in InfluxDB line protocol to this file
+	InfluxURL            string      // InfluxDB write endpoint for interval metrics
+	InfluxToken          string      // InfluxDB API token
+	EventsNATS           string      // NATS server URL for interval stats and lifecycle events
+	EventsKafkaREST      string      // Kafka REST proxy URL for interval stats and lifecycle events
+	EventsSubject        string      // NATS subject or Kafka topic for events
+	CloudWatchNamespace  string      // CloudWatch namespace for interval metrics (enables the exporter)
+	CloudWatchRegion     string      // AWS region of the CloudWatch endpoint
+	CloudWatchDimensions string      // Extra "name=value,..." dimensions
+	StreamAddr           string      // Listen address of the live results stream (SSE and WebSocket)
+	StatsDAddr           string      // StatsD agent address for interval metrics (enables the exporter)
+	StatsDPrefix         string      // Prefix of the StatsD metric names
+	StatsDTags           bool        // Tag StatsD metrics in the DogStatsD format
+	OTelEndpoint         string      // OTLP/HTTP collector URL for metrics and spans (enables the exporter)
+	OTelHeaders          string      // Extra OTLP request headers as name=value,...
+	OTelTraceSample      float64     // Fraction of requests exported as spans (0 disables tracing)
+	RequestTimeout       int         // Request timeout in milliseconds
+	ReconnectEvery       int64       // Requests per connection between reconnects (0 disables)
+	ProbeInterval        int         // Milliseconds between RTT probe PINGs (0 disables)
+	WaitForServer        int         // Seconds to wait for the server to answer PING before starting
+	StartAt              string      // RFC3339 instant at which the measured phase begins
+	RequestDeadline      int         // Per-request deadline in milliseconds enforced by the benchmark
+	SkipCapabilityCheck  bool        // Don't verify that the server supports the workload's commands
+	SkipHealthCheck      bool        // Skip the pre-run health gate
+	SkipReconciliation   bool        // Don't compare the client counts with the server's commandstats
+	ProxyMode            bool        // Target is a Redis-protocol proxy
 }
 
 // BenchmarkStats tracks performance metrics
 type BenchmarkStats struct {
-	startTime         time.Time  // Test start timestamp
-	requestsCompleted int64      // Counter for completed requests
-	latencies         []float64  // All request latencies
-	errors            int64      // Error counter
-	lastPrint         time.Time  // Last progress print timestamp
-	lastRequests      int64      // Request count at last print
-	currentLatencies  []float64  // Recent request latencies
-	mu                sync.Mutex // Protects shared data
+	config            *Config                      // Benchmark configuration
+	startTime         time.Time                    // Test start timestamp
+	endTime           time.Time                    // Test end timestamp (zero while running)
+	requestsCompleted int64                        // Counter for completed requests
+	latencies         *LatencyHistogram            // All request latencies
+	errors            int64                        // Error counter
+	bytesSent         int64                        // Approximate request bytes of successful requests
+	bytesReceived     int64                        // Approximate reply bytes of successful requests
+	hits              int64                        // GET replies with a value
+	misses            int64                        // GET replies without a value
+	timeouts          int64                        // Errors reported as timeouts by the client
+	deadlineExceeded  int64                        // Requests abandoned at the --request-deadline
+	cancelled         int64                        // Requests abandoned because the run ended
+	ooms              int64                        // Requests rejected with OOM
+	loading           int64                        // Requests rejected with LOADING
+	backoffNanos      int64                        // Time workers backed off after OOM or LOADING
+	requestSizes      SizeHistogram                // Distribution of request sizes
+	responseSizes     SizeHistogram                // Distribution of reply sizes
+	payloadLatencies  []*LatencyHistogram          // Latencies by payload size bucket (allocated on first use)
+	lastPrint         time.Time                    // Last progress print timestamp
+	lastRequests      int64                        // Request count at last print
+	window            *SlidingDigest               // Sketches of the latencies of the last --progress-window intervals
+	pathLatencies     map[string]*LatencyHistogram // Latencies broken down by labelled path
+	pathErrors        map[string]int64             // Errors broken down by labelled path
+	pathOrder         []string                     // Labels in first-seen order for reporting
+	commandLatencies  map[string]*LatencyHistogram // Latencies broken down by command name
+	commandErrors     map[string]int64             // Errors broken down by command name
+	commandOrder      []string                     // Command names in first-seen order for reporting
+	sloBuckets        *LatencyBuckets              // Latency SLO bucket counters (nil if disabled)
+	apdex             *ApdexCounter                // Apdex counters (nil if disabled)
+	rampStages        []*RampStage                 // Per QPS level statistics of ramped runs
+	timeline          []IntervalStats              // Statistics of every reporting interval
+	stalls            []StallWindow                // Injected server stalls
+	pauseRecovery     *PauseRecovery               // Outcome of --pause-at (nil otherwise)
+	reconnects        *ReconnectStats              // Handshakes of --reconnect-every (nil otherwise)
+	probe             *RTTProbe                    // RTT probe of --rtt-probe (nil otherwise)
+	eviction          *EvictionMonitor             // Eviction counters of --eviction-pressure (nil otherwise)
+	expiry            *ExpiryStats                 // Read outcomes of -t expiry (nil otherwise)
+	dataset           *LatencyDataset              // Raw latencies for --latency-dump (nil if disabled)
+	exporters         *ExporterHub                 // Receives every interval (nil if no exporter is enabled)
+	workload          *SeededWorkload              // Request sequence of --deterministic, saved in checkpoints (nil otherwise)
+	skipped           []string                     // Labels of suite commands skipped as unsupported by the server
+	lastErrors        int64                        // Error count at last print
+	mu                sync.Mutex                   // Protects shared data
+}
+
+// StatsSummary holds the aggregate results of a benchmark phase
+type StatsSummary struct {
+	Duration float64       // Elapsed time in seconds
+	Requests int64         // Successful requests
+	Errors   int64         // Failed requests
+	RPS      float64       // Successful requests per second
+	Latency  *LatencyStats // Latency statistics (nil without successful requests)
+	Sent     int64         // Approximate bytes sent
+	Received int64         // Approximate bytes received
 }
 
 // LatencyStats holds calculated statistics about request latencies
 type LatencyStats struct {
-	min float64 // Minimum latency
-	max float64 // Maximum latency
-	avg float64 // Average latency
-	p50 float64 // 50th percentile (median)
-	p95 float64 // 95th percentile
-	p99 float64 // 99th percentile
+	min  float64 // Minimum latency
+	max  float64 // Maximum latency
+	avg  float64 // Average latency
+	p50  float64 // 50th percentile (median)
+	p95  float64 // 95th percentile
+	p99  float64 // 99th percentile
+	p999 float64 // 99.9th percentile
 }
 
 // QPSController manages rate limiting to maintain target QPS
-// Supports both linear and exponential ramp modes
+// Supports both linear and exponential ramp modes. It holds the target shared
+// by all workers; each worker paces its share with its own WorkerPacer.
 type QPSController struct {
 	config                *Config
-	currentQPS            int
+	currentQPS            int64 // Current target, read atomically by the worker pacers
 	lastUpdate            time.Time
-	requestsInSecond      int
-	secondStart           time.Time
+	nextStep              int64      // Unix nanoseconds of the next ramp step, read atomically
+	profile               QPSProfile // Target over time of --qps-profile or --qps-schedule (nil otherwise)
+	start                 time.Time  // Start of the phase, the origin of the profile
 	exponentialMultiplier float64
-	mu                    sync.Mutex
+	onUpdate              func(qps int) // Called with the new target after each ramp step
+	bytesPerSecond        float64       // Bandwidth target of --target-mbps (0 if disabled)
+	rampDown              *RampDown     // Scales the target down at the end of the run (nil if disabled)
+	nextTransfer          time.Time     // Time at which the bandwidth budget is available again
+	mu                    sync.Mutex    // Guards ramp steps and the bandwidth budget
 }
 
 func generateRandomData(size int) string {
 	const chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
 	result := make([]byte, size)
 	for i := 0; i < size; i++ {
-		result[i] = chars[rand.Intn(len(chars))]
+		result[i] = chars[benchRand.Intn(len(chars))]
 	}
 	return string(result)
 }
 
-func getRandomKey(keyspace int64) string {
-	return fmt.Sprintf("key:%d", rand.Int63n(keyspace))
+// keyPrefix returns the prefix of all generated keys, --key-prefix. With key
+// namespacing enabled the run ID is included so concurrent runs don't share keys.
+func keyPrefix(config *Config) string {
+	if config.NamespaceKeys {
+		return "vkbench:" + config.RunID + ":" + config.KeyPrefix
+	}
+	return config.KeyPrefix
+}
+
+func getRandomKey(prefix string, offset, keyspace int64) string {
+	return fmt.Sprintf("%s:%d", prefix, offset+benchRand.Int63n(keyspace))
 }
 
 // NewBenchmarkStats creates a new stats tracker
-func NewBenchmarkStats() *BenchmarkStats {
+func NewBenchmarkStats(config *Config) *BenchmarkStats {
+	var sloBuckets *LatencyBuckets
+	if len(config.LatencyBuckets) > 0 {
+		sloBuckets = NewLatencyBuckets(config.LatencyBuckets)
+	}
+	var apdex *ApdexCounter
+	if config.ApdexThreshold > 0 {
+		apdex = NewApdexCounter(config.ApdexThreshold)
+	}
+	var dataset *LatencyDataset
+	if config.LatencyDumpFile != "" {
+		dataset = NewLatencyDataset(config.LatencyDumpSample)
+	}
 	return &BenchmarkStats{
-		startTime: time.Now(),
-		lastPrint: time.Now(),
-		latencies: make([]float64, 0, 1000000),
+		dataset:          dataset,
+		sloBuckets:       sloBuckets,
+		apdex:            apdex,
+		config:           config,
+		startTime:        time.Now(),
+		lastPrint:        time.Now(),
+		latencies:        NewLatencyHistogram(),
+		window:           NewSlidingDigest(config.ProgressWindow),
+		pathLatencies:    make(map[string]*LatencyHistogram),
+		pathErrors:       make(map[string]int64),
+		commandLatencies: make(map[string]*LatencyHistogram),
+		commandErrors:    make(map[string]int64),
 	}
 }
 
-// AddLatency records a request latency
-func (s *BenchmarkStats) AddLatency(latency float64) {
-	atomic.AddInt64(&s.requestsCompleted, 1)
-	s.mu.Lock()
-	s.latencies = append(s.latencies, latency)
-	s.currentLatencies = append(s.currentLatencies, latency)
-	s.mu.Unlock()
-	s.PrintProgress()
+// registerPath remembers the order in which paths are first seen. Callers must hold s.mu.
+func (s *BenchmarkStats) registerPath(path string) {
+	if _, ok := s.pathLatencies[path]; ok {
+		return
+	}
+	if _, ok := s.pathErrors[path]; ok {
+		return
+	}
+	s.pathOrder = append(s.pathOrder, path)
+}
+
+// recordPath adds a latency to the histogram of a labelled path. Callers must hold s.mu.
+func (s *BenchmarkStats) recordPath(path string, latency float64) {
+	s.registerPath(path)
+	h, ok := s.pathLatencies[path]
+	if !ok {
+		h = NewLatencyHistogram()
+		s.pathLatencies[path] = h
+	}
+	h.Record(latency)
+}
+
+// pathRequests returns the successful requests of a labelled path. Callers must hold s.mu.
+func (s *BenchmarkStats) pathRequests(path string) int64 {
+	if h, ok := s.pathLatencies[path]; ok {
+		return h.Count()
+	}
+	return 0
 }
 
-// AddError increments the error counter
-func (s *BenchmarkStats) AddError() {
-	atomic.AddInt64(&s.errors, 1)
+// pathStats computes the latency statistics of a labelled path, or returns
+// nil without successful requests. Callers must hold s.mu.
+func (s *BenchmarkStats) pathStats(path string) *LatencyStats {
+	if h, ok := s.pathLatencies[path]; ok {
+		return h.Stats()
+	}
+	return nil
 }
 
-// PrintProgress displays real-time benchmark progress statistics
+// PrintProgress displays real-time benchmark progress statistics for the
+// interval since the last call. It is called by RunReporter, off the request path.
 func (s *BenchmarkStats) PrintProgress() {
 	now := time.Now()
-	if now.Sub(s.lastPrint) >= time.Second {
-		s.mu.Lock()
-		defer s.mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	completed := atomic.LoadInt64(&s.requestsCompleted)
+	intervalRequests := completed - s.lastRequests
+	currentRPS := float64(intervalRequests) / now.Sub(s.lastPrint).Seconds()
+	overallRPS := float64(completed) / now.Sub(s.startTime).Seconds()
+
+	// Estimate window statistics from the sketches instead of sorting every sample.
+	// The timeline keeps per-interval figures; the progress line covers the whole window.
+	stats := s.window.Interval().Stats()
+	live := s.window.Stats()
+
+	fmt.Printf("\r\x1b[K") // Clear line
+	fmt.Printf("Progress: %d requests, Current RPS: %.2f, Overall RPS: %.2f, Errors: %d",
+		completed, currentRPS, overallRPS, atomic.LoadInt64(&s.errors))
+	if live != nil {
+		window := ""
+		if s.config.ProgressWindow > 1 {
+			window = fmt.Sprintf(", last %ds", s.config.ProgressWindow)
+		}
+		fmt.Printf(" | Latencies (ms%s) - Avg: %.2f, p50: %.2f, p99: %.2f",
+			window, live.avg, live.p50, live.p99)
+	}
 
-		completed := atomic.LoadInt64(&s.requestsCompleted)
-		intervalRequests := completed - s.lastRequests
-		currentRPS := float64(intervalRequests)
-		overallRPS := float64(completed) / now.Sub(s.startTime).Seconds()
+	s.recordInterval(now, completed, stats)
+	interval := s.timeline[len(s.timeline)-1]
+	if interval.ProbeP99 > 0 {
+		fmt.Printf(" | RTT p99: %.2f", interval.ProbeP99)
+	}
+	if interval.Keyspace > 0 {
+		fmt.Printf(" | Keyspace: %d", interval.Keyspace)
+	}
 
-		// Calculate window statistics
-		stats := calculateLatencyStats(s.currentLatencies)
+	s.window.Rotate()
+	s.lastPrint = now
+	s.lastRequests = completed
+}
 
-		fmt.Printf("\r\x1b[K") // Clear line
-		fmt.Printf("Progress: %d requests, Current RPS: %.2f, Overall RPS: %.2f, Errors: %d",
-			completed, currentRPS, overallRPS, atomic.LoadInt64(&s.errors))
-		if stats != nil {
-			fmt.Printf(" | Latencies (ms) - Avg: %.2f, p50: %.2f, p99: %.2f",
-				stats.avg, stats.p50, stats.p99)
+// RunReporter prints the progress line and records the timeline every second
+// until ctx is done, so workers only add their samples
+func (s *BenchmarkStats) RunReporter(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.PrintProgress()
 		}
+	}
+}
+
+// Stop freezes the elapsed time of the stats at the current time
+func (s *BenchmarkStats) Stop() {
+	s.mu.Lock()
+	if s.endTime.IsZero() {
+		s.endTime = time.Now()
+	}
+	s.mu.Unlock()
+}
+
+// elapsed returns the run time in seconds, up to Stop if it was called
+func (s *BenchmarkStats) elapsed() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.endTime.IsZero() {
+		return time.Since(s.startTime).Seconds()
+	}
+	return s.endTime.Sub(s.startTime).Seconds()
+}
 
-		s.currentLatencies = s.currentLatencies[:0]
-		s.lastPrint = now
-		s.lastRequests = completed
+// Summary computes the aggregate results collected so far
+func (s *BenchmarkStats) Summary() StatsSummary {
+	duration := s.elapsed()
+	requests := atomic.LoadInt64(&s.requestsCompleted)
+	s.mu.Lock()
+	latency := s.latencies.Stats()
+	s.mu.Unlock()
+	return StatsSummary{
+		Duration: duration,
+		Requests: requests,
+		Errors:   atomic.LoadInt64(&s.errors),
+		RPS:      float64(requests) / duration,
+		Latency:  latency,
+		Sent:     atomic.LoadInt64(&s.bytesSent),
+		Received: atomic.LoadInt64(&s.bytesReceived),
 	}
 }
 
-// PrintFinalStats prints the final benchmark results
 // PrintFinalStats outputs the final benchmark results and statistics
 func (s *BenchmarkStats) PrintFinalStats() {
-	totalTime := time.Since(s.startTime).Seconds()
+	totalTime := s.elapsed()
 	finalRPS := float64(s.requestsCompleted) / totalTime
 
 	s.mu.Lock()
-	finalStats := calculateLatencyStats(s.latencies)
+	finalStats := s.latencies.Stats()
+	pathStats := make([]*LatencyStats, len(s.pathOrder))
+	pathRequests := make([]int64, len(s.pathOrder))
+	for i, path := range s.pathOrder {
+		pathStats[i] = s.pathStats(path)
+		pathRequests[i] = s.pathRequests(path)
+	}
 	s.mu.Unlock()
 
+	if redisCSV != nil {
+		s.printRedisCSV(totalTime, finalStats, pathStats)
+	}
+
 	fmt.Printf("\n\nFinal Results:\n")
 	fmt.Printf("=============\n")
+	fmt.Printf("Run ID: %s\n", s.config.RunID)
+	if s.config.NamespaceKeys || s.config.KeyPrefix != "key" {
+		fmt.Printf("Key namespace: %s\n", keyPrefix(s.config))
+	}
 	fmt.Printf("Total time: %.2f seconds\n", totalTime)
 	fmt.Printf("Requests completed: %d\n", s.requestsCompleted)
 	fmt.Printf("Requests per second: %.2f\n", finalRPS)
 	fmt.Printf("Total errors: %d\n", s.errors)
+	s.printCancellations()
+	s.printConditions()
 
 	if finalStats != nil {
 		fmt.Printf("\nLatency Statistics (ms):\n")
@@ -172,6 +517,50 @@ func (s *BenchmarkStats) PrintFinalStats() {
 		fmt.Printf("95th percentile: %.3f\n", finalStats.p95)
 		fmt.Printf("99th percentile: %.3f\n", finalStats.p99)
 	}
+
+	s.printBandwidth(totalTime)
+
+	if len(s.rampStages) > 0 {
+		s.printRampStages()
+	}
+	s.printTimelineSummary()
+	s.printStalls()
+	s.printPauseRecovery()
+	s.printReconnects()
+	s.printProbe()
+	s.printKeyspaceGrowth()
+	s.printEviction()
+	s.printExpiry()
+	if s.sloBuckets != nil {
+		s.sloBuckets.Print()
+	}
+	if s.apdex != nil {
+		s.apdex.Print()
+	}
+
+	for i, path := range s.pathOrder {
+		requests := pathRequests[i]
+		fmt.Printf("\nLatency Statistics for %s (ms, %d requests, %.2f req/s, %d errors):\n",
+			path, requests, float64(requests)/totalTime, s.pathErrors[path])
+		fmt.Printf("=====================\n")
+		ps := pathStats[i]
+		if ps == nil {
+			continue
+		}
+		fmt.Printf("Minimum: %.3f\n", ps.min)
+		fmt.Printf("Average: %.3f\n", ps.avg)
+		fmt.Printf("Maximum: %.3f\n", ps.max)
+		fmt.Printf("Median (p50): %.3f\n", ps.p50)
+		fmt.Printf("95th percentile: %.3f\n", ps.p95)
+		fmt.Printf("99th percentile: %.3f\n", ps.p99)
+	}
+	s.printCommandStats(totalTime)
+	for _, label := range s.skipped {
+		fmt.Printf("\n%s: skipped (unsupported)\n", label)
+	}
+	if s.config.MemtierStats {
+		s.printMemtierStats(totalTime, finalStats, pathStats)
+	}
 }
 
 // calculateLatencyStats computes statistics from a slice of latency measurements
@@ -186,12 +575,13 @@ func calculateLatencyStats(latencies []float64) *LatencyStats {
 	sort.Float64s(sorted)
 
 	return &LatencyStats{
-		min: sorted[0],
-		max: sorted[len(sorted)-1],
-		avg: average(latencies),
-		p50: sorted[len(sorted)*50/100],
-		p95: sorted[len(sorted)*95/100],
-		p99: sorted[len(sorted)*99/100],
+		min:  sorted[0],
+		max:  sorted[len(sorted)-1],
+		avg:  average(latencies),
+		p50:  sorted[len(sorted)*50/100],
+		p95:  sorted[len(sorted)*95/100],
+		p99:  sorted[len(sorted)*99/100],
+		p999: sorted[len(sorted)*999/1000],
 	}
 }
 
@@ -207,96 +597,90 @@ func average(values []float64) float64 {
 	return sum / float64(len(values))
 }
 
-// Throttle implements rate limiting to maintain target QPS
-// Supports both linear and exponential ramp modes
-func (qps *QPSController) Throttle() {
-	qps.mu.Lock()
-	defer qps.mu.Unlock()
-
-	if qps.currentQPS <= 0 {
-		return
-	}
-
-	now := time.Now()
-
-	isExponential := qps.config.QPSRampMode == "exponential"
+// isRamping reports whether the configuration changes the QPS target over time
+func (qps *QPSController) isRamping() bool {
 	hasDynamicQps := qps.config.StartQPS > 0 && qps.config.EndQPS > 0 && qps.config.QPSChangeInterval > 0
 
 	// For linear mode, also require QPSChange
-	if !isExponential {
+	if qps.config.QPSRampMode != "exponential" {
 		hasDynamicQps = hasDynamicQps && qps.config.QPSChange != 0
 	}
+	return hasDynamicQps
+}
 
-	// Handle dynamic QPS changes
-	if hasDynamicQps {
-		elapsedSeconds := int(now.Sub(qps.lastUpdate).Seconds())
-		if elapsedSeconds >= qps.config.QPSChangeInterval {
-			if isExponential {
-				// Exponential mode: multiply by the computed multiplier
-				newQPS := int(math.Round(float64(qps.currentQPS) * qps.exponentialMultiplier))
+// step advances a QPS ramp when its change interval has elapsed. Workers
+// call it before every request, so the due time is checked without locking.
+func (qps *QPSController) step(now time.Time) {
+	if now.UnixNano() < atomic.LoadInt64(&qps.nextStep) || !qps.isRamping() {
+		return
+	}
+	qps.mu.Lock()
+	defer qps.mu.Unlock()
 
-				// Clamp to EndQPS
-				if qps.config.EndQPS > qps.config.StartQPS {
-					// Increasing QPS
-					if newQPS > qps.config.EndQPS {
-						newQPS = qps.config.EndQPS
-					}
-				} else {
-					// Decreasing QPS
-					if newQPS < qps.config.EndQPS {
-						newQPS = qps.config.EndQPS
-					}
-				}
-				qps.currentQPS = newQPS
-			} else {
-				// Linear mode: add QPSChange
-				if qps.config.StartQPS < qps.config.EndQPS {
-					// Increasing QPS
-					qps.currentQPS += qps.config.QPSChange
-					if qps.currentQPS > qps.config.EndQPS {
-						qps.currentQPS = qps.config.EndQPS
-					}
-				} else {
-					// Decreasing QPS
-					qps.currentQPS -= qps.config.QPSChange
-					if qps.currentQPS < qps.config.EndQPS {
-						qps.currentQPS = qps.config.EndQPS
-					}
-				}
+	elapsedSeconds := int(now.Sub(qps.lastUpdate).Seconds())
+	if elapsedSeconds < qps.config.QPSChangeInterval {
+		return
+	}
+	previousQPS := int(atomic.LoadInt64(&qps.currentQPS))
+	currentQPS := previousQPS
+	if qps.config.QPSRampMode == "exponential" {
+		// Exponential mode: multiply by the computed multiplier
+		newQPS := int(math.Round(float64(currentQPS) * qps.exponentialMultiplier))
+
+		// Clamp to EndQPS
+		if qps.config.EndQPS > qps.config.StartQPS {
+			// Increasing QPS
+			if newQPS > qps.config.EndQPS {
+				newQPS = qps.config.EndQPS
+			}
+		} else {
+			// Decreasing QPS
+			if newQPS < qps.config.EndQPS {
+				newQPS = qps.config.EndQPS
+			}
+		}
+		currentQPS = newQPS
+	} else {
+		// Linear mode: add QPSChange
+		if qps.config.StartQPS < qps.config.EndQPS {
+			// Increasing QPS
+			currentQPS += qps.config.QPSChange
+			if currentQPS > qps.config.EndQPS {
+				currentQPS = qps.config.EndQPS
+			}
+		} else {
+			// Decreasing QPS
+			currentQPS -= qps.config.QPSChange
+			if currentQPS < qps.config.EndQPS {
+				currentQPS = qps.config.EndQPS
 			}
-			qps.lastUpdate = now
-			fmt.Printf("\nUpdated QPS target to: %d\n", qps.currentQPS)
 		}
 	}
-
-	// Reset counter and update second start if we've moved to a new second
-	if now.Sub(qps.secondStart) >= time.Second {
-		qps.requestsInSecond = 0
-		qps.secondStart = now.Truncate(time.Second)
+	atomic.StoreInt64(&qps.currentQPS, int64(currentQPS))
+	qps.lastUpdate = now
+	atomic.StoreInt64(&qps.nextStep, now.Add(time.Duration(qps.config.QPSChangeInterval)*time.Second).UnixNano())
+	if currentQPS == previousQPS {
+		// Clamped at the end of the ramp: no new stage
+		return
 	}
-
-	// Calculate the target interval between requests
-	interval := time.Second / time.Duration(qps.currentQPS)
-
-	// Calculate the expected time for this request
-	expectedTime := qps.secondStart.Add(time.Duration(qps.requestsInSecond) * interval)
-
-	// If we're ahead of schedule, sleep until the expected time
-	if now.Before(expectedTime) {
-		time.Sleep(expectedTime.Sub(now))
+	slog.Info("QPS target updated", "qps", currentQPS)
+	if qps.onUpdate != nil {
+		qps.onUpdate(currentQPS)
 	}
+}
 
-	// If we've hit the QPS limit for this second, wait for next second
-	if qps.requestsInSecond >= qps.currentQPS {
-		nextSecond := qps.secondStart.Add(time.Second)
-		if now.Before(nextSecond) {
-			time.Sleep(nextSecond.Sub(now))
-		}
-		qps.requestsInSecond = 0
-		qps.secondStart = nextSecond
-	}
+// Target returns the current QPS target (0 if unlimited)
+func (qps *QPSController) Target() int {
+	return int(math.Round(qps.target(time.Now())))
+}
 
-	qps.requestsInSecond++
+// target returns the QPS target at the given time, following the profile if
+// one is configured
+func (qps *QPSController) target(now time.Time) float64 {
+	if qps.profile != nil {
+		return qps.profile(now.Sub(qps.start))
+	}
+	return float64(atomic.LoadInt64(&qps.currentQPS))
 }
 
 // Update the client configuration and usage
@@ -326,13 +710,13 @@ func NewQPSController(config *Config) *QPSController {
 		// For ramp-up modes without StartQPS, use EndQPS as initial value
 		currentQPS = config.EndQPS
 		effectiveStartQPS = config.EndQPS
-		fmt.Fprintln(os.Stderr, "Warning: start-qps not set for ramp mode, using end-qps as initial QPS")
+		slog.Warn("start-qps not set for ramp mode, using end-qps as initial QPS")
 	}
 
 	// Validate StartQPS if ramp mode is configured
 	if config.QPSChangeInterval > 0 && config.EndQPS > 0 {
 		if config.StartQPS <= 0 {
-			fmt.Fprintln(os.Stderr, "Warning: start-qps must be positive for QPS ramping. Using end-qps as fallback.")
+			slog.Warn("start-qps must be positive for QPS ramping, using end-qps as fallback")
 			effectiveStartQPS = config.EndQPS
 		}
 	}
@@ -348,7 +732,7 @@ func NewQPSController(config *Config) *QPSController {
 			exponentialMultiplier = config.QPSRampFactor
 			// Warn if factor < 1 (causes ramp-down instead of ramp-up)
 			if config.QPSRampFactor < 1 {
-				fmt.Fprintln(os.Stderr, "Warning: qps-ramp-factor < 1 will cause QPS to decrease (ramp-down) each interval")
+				slog.Warn("qps-ramp-factor < 1 will cause QPS to decrease (ramp-down) each interval")
 			}
 		} else {
 			fmt.Fprintln(os.Stderr, "Error: exponential mode requires --qps-ramp-factor to be specified")
@@ -356,237 +740,1968 @@ func NewQPSController(config *Config) *QPSController {
 		}
 	}
 
+	profile, err := newQPSProfile(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	return &QPSController{
 		config:                config,
-		currentQPS:            currentQPS,
+		currentQPS:            int64(currentQPS),
 		lastUpdate:            now,
-		secondStart:           now,
-		requestsInSecond:      0,
+		nextStep:              now.Add(time.Duration(config.QPSChangeInterval) * time.Second).UnixNano(),
 		exponentialMultiplier: exponentialMultiplier,
+		bytesPerSecond:        config.TargetMbps * 1e6 / 8,
+		profile:               profile,
+		start:                 now,
 	}
 }
 
-// RunBenchmark executes the benchmark with the given configuration
-func RunBenchmark(ctx context.Context, config *Config) error {
-	stats := NewBenchmarkStats()
-	qpsController := NewQPSController(config)
-
-	// Print benchmark configuration
-	fmt.Println("Valkey Benchmark")
-	fmt.Printf("Host: %s\n", config.Host)
-	fmt.Printf("Port: %d\n", config.Port)
-	fmt.Printf("Threads: %d\n", config.NumThreads)
-	fmt.Printf("Total Requests: %d\n", config.TotalRequests)
-	fmt.Printf("Data Size: %d\n", config.DataSize)
-	fmt.Printf("Command: %s\n", config.Command)
-	fmt.Printf("Is Cluster: %v\n", config.IsCluster)
-	fmt.Printf("Read from Replica: %v\n", config.ReadFromReplica)
-	fmt.Printf("Use TLS: %v\n", config.UseTLS)
-	fmt.Println()
-	// Create client pool
-	clientPool := make([]interface{}, config.PoolSize)
-	for i := 0; i < config.PoolSize; i++ {
-		if config.IsCluster {
-			clusterConfig := api.NewGlideClusterClientConfiguration().
-				WithAddress(&api.NodeAddress{Host: config.Host, Port: config.Port})
-
-			// Set request timeout if configured
-			if config.RequestTimeout > 0 {
-				clusterConfig.WithRequestTimeout(config.RequestTimeout)
-			}
+// ThrottleBytes paces requests by payload size when --target-mbps is set. It is
+// called after a request with its transferred bytes and blocks until the
+// bandwidth budget consumed by all workers so far is available again. It
+// returns false if the context was done while waiting.
+func (qps *QPSController) ThrottleBytes(ctx context.Context, bytes int64) bool {
+	if qps.bytesPerSecond <= 0 {
+		return true
+	}
+	qps.mu.Lock()
+	now := time.Now()
+	if qps.nextTransfer.Before(now) {
+		qps.nextTransfer = now
+	}
+	qps.nextTransfer = qps.nextTransfer.Add(time.Duration(float64(bytes) / qps.bytesPerSecond * float64(time.Second)))
+	wait := qps.nextTransfer.Sub(now)
+	qps.mu.Unlock()
+	return qps.wait(ctx, wait)
+}
 
-			if config.UseTLS {
-				clusterConfig.WithUseTLS(true)
-			}
-			if config.ReadFromReplica {
-				clusterConfig.WithReadFrom(api.PreferReplica)
-			}
+// generateRunID returns a short random identifier for a benchmark run
+func generateRunID() string {
+	return fmt.Sprintf("%08x", rand.Uint32())
+}
 
-			client, err := api.NewGlideClusterClient(clusterConfig)
-			if err != nil {
-				return fmt.Errorf("failed to create cluster client: %v", err)
-			}
-			clientPool[i] = client
-		} else {
-			clientConfig := api.NewGlideClientConfiguration().
-				WithAddress(&api.NodeAddress{Host: config.Host, Port: config.Port})
+// connectionName builds the CLIENT SETNAME value for a pool slot, e.g. "vkbench:1a2b3c4d:w7".
+// Replica pool slots use the "r" tag instead of "w".
+func connectionName(config *Config, tag string, index int) string {
+	return fmt.Sprintf("vkbench:%s:%s%d", config.RunID, tag, index)
+}
 
-			// Set request timeout if configured
-			if config.RequestTimeout > 0 {
-				clientConfig.WithRequestTimeout(config.RequestTimeout)
-			}
+// createClient creates a single standalone or cluster client with the given read strategy.
+// The name is registered with the server via CLIENT SETNAME on every connection of the client.
+func createClient(config *Config, readFrom api.ReadFrom, name string) (interface{}, error) {
+	if config.IsCluster {
+		clusterConfig := api.NewGlideClusterClientConfiguration().
+			WithAddress(&api.NodeAddress{Host: config.Host, Port: config.Port}).
+			WithClientName(name)
+
+		// Set request timeout if configured
+		if config.RequestTimeout > 0 {
+			clusterConfig.WithRequestTimeout(config.RequestTimeout)
+		}
 
-			if config.UseTLS {
-				clientConfig.WithUseTLS(true)
-			}
-			if config.ReadFromReplica {
-				clientConfig.WithReadFrom(api.PreferReplica)
-			}
+		if config.UseTLS {
+			clusterConfig.WithUseTLS(true)
+		}
+		if credentials := serverCredentials(); credentials != nil {
+			clusterConfig.WithCredentials(credentials)
+		}
+		if readFrom != api.Primary {
+			clusterConfig.WithReadFrom(readFrom)
+		}
 
-			client, err := api.NewGlideClient(clientConfig)
-			if err != nil {
-				return fmt.Errorf("failed to create client: %v", err)
-			}
-			clientPool[i] = client
+		client, err := api.NewGlideClusterClient(clusterConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cluster client: %v", err)
+		}
+		if err := applyConnectionSettings(config, client); err != nil {
+			client.Close()
+			return nil, err
 		}
+		return client, nil
 	}
 
-	// Update worker goroutine
-	var wg sync.WaitGroup
-	for i := 0; i < config.NumThreads; i++ {
-		wg.Add(1)
-		go func(threadID int) {
-			defer wg.Done()
-			data := ""
-			if config.Command == "set" {
-				data = generateRandomData(config.DataSize)
-			}
+	clientConfig := api.NewGlideClientConfiguration().
+		WithAddress(&api.NodeAddress{Host: config.Host, Port: config.Port})
+	// Proxies typically reject CLIENT SETNAME
+	if !config.ProxyMode {
+		clientConfig.WithClientName(name)
+	}
 
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				default:
-					if config.TestDuration == 0 &&
-						atomic.LoadInt64(&stats.requestsCompleted) >= config.TotalRequests {
-						return
-					}
+	// Set request timeout if configured
+	if config.RequestTimeout > 0 {
+		clientConfig.WithRequestTimeout(config.RequestTimeout)
+	}
 
-					clientIndex := int(atomic.LoadInt64(&stats.requestsCompleted)) % config.PoolSize
-					client := clientPool[clientIndex]
+	if config.UseTLS {
+		clientConfig.WithUseTLS(true)
+	}
+	if credentials := serverCredentials(); credentials != nil {
+		clientConfig.WithCredentials(credentials)
+	}
+	if readFrom != api.Primary {
+		clientConfig.WithReadFrom(readFrom)
+	}
 
-					qpsController.Throttle()
+	client, err := api.NewGlideClient(clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %v", err)
+	}
+	if err := applyConnectionSettings(config, client); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
 
-					start := time.Now()
-					var err error
-
-					switch config.Command {
-					case "set":
-						key := fmt.Sprintf("key:%d:%d", threadID, stats.requestsCompleted)
-						if config.UseSequential {
-							key = fmt.Sprintf("key:%d",
-								atomic.LoadInt64(&stats.requestsCompleted)%config.SequentialKeyLen)
-						} else if config.RandomKeyspace > 0 {
-							key = getRandomKey(config.RandomKeyspace)
-						}
-						if c, ok := client.(*api.GlideClient); ok {
-							var result string
-							result, err = c.Set(key, data)
-							_ = result // Ignore the result value
-						} else if c, ok := client.(*api.GlideClusterClient); ok {
-							var result string
-							result, err = c.Set(key, data)
-							_ = result // Ignore the result value
-						}
+// applyConnectionSettings issues per-connection CLIENT commands on a freshly created client.
+// In cluster mode the commands are routed to all nodes so every node connection is covered.
+func applyConnectionSettings(config *Config, client interface{}) error {
+	var commands [][]string
+	if config.ClientNoEvict {
+		commands = append(commands, []string{"CLIENT", "NO-EVICT", "on"})
+	}
+	if config.ClientNoTouch {
+		commands = append(commands, []string{"CLIENT", "NO-TOUCH", "on"})
+	}
 
-					case "get":
-						key := "somekey"
-						if config.RandomKeyspace > 0 {
-							key = getRandomKey(config.RandomKeyspace)
-						}
-						if c, ok := client.(*api.GlideClient); ok {
-							_, err = c.Get(key)
-						} else if c, ok := client.(*api.GlideClusterClient); ok {
-							_, err = c.Get(key)
-						}
+	for _, args := range commands {
+		if _, err := executeOnAllNodes(client, args); err != nil {
+			return fmt.Errorf("failed to execute %s %s: %v", args[0], args[1], err)
+		}
+	}
+	return nil
+}
 
-					case "custom":
-						if config.IsCluster {
-							clusterCmd := &CustomCommandCluster{}
-							err = clusterCmd.execute(client.(*api.GlideClusterClient))
+// executeCommand runs an arbitrary command on a standalone or cluster client
+// using the default routing of the client
+func executeCommand(client interface{}, args []string) (interface{}, error) {
+	if c, ok := client.(*api.GlideClient); ok {
+		return c.CustomCommand(args)
+	} else if c, ok := client.(*api.GlideClusterClient); ok {
+		result, err := c.CustomCommand(args)
+		if err != nil {
+			return nil, err
+		}
+		if result.IsMultiValue() {
+			return result.MultiValue(), nil
+		}
+		return result.SingleValue(), nil
+	}
+	return nil, fmt.Errorf("unsupported client type %T", client)
+}
 
-						} else {
-							standaloneCmd := &CustomCommandStandalone{}
-							err = standaloneCmd.execute(client.(*api.GlideClient))
-						}
-					}
+// executeOnAllNodes runs a command on every node in cluster mode, or on the
+// single server in standalone mode. Cluster results are keyed by node address.
+func executeOnAllNodes(client interface{}, args []string) (interface{}, error) {
+	return executeOnRoute(client, args, glideconfig.AllNodes)
+}
 
-					if err != nil {
-						stats.AddError()
-						fmt.Printf("Error in thread %d: %v\n", threadID, err)
-					} else {
-						stats.AddLatency(float64(time.Since(start).Microseconds()) / 1000.0)
-					}
-				}
-			}
-		}(i)
-	}
+// executeOnAllPrimaries runs a command on every primary in cluster mode, or on
+// the single server in standalone mode
+func executeOnAllPrimaries(client interface{}, args []string) (interface{}, error) {
+	return executeOnRoute(client, args, glideconfig.AllPrimaries)
+}
 
-	// Wait for completion or duration
-	if config.TestDuration > 0 {
-		time.Sleep(time.Duration(config.TestDuration) * time.Second)
+// executeOnRoute runs a command on the routed cluster nodes, or on the single
+// server in standalone mode
+func executeOnRoute(client interface{}, args []string, route glideconfig.Route) (interface{}, error) {
+	if c, ok := client.(*api.GlideClusterClient); ok {
+		result, err := c.CustomCommandWithRoute(args, route)
+		if err != nil {
+			return nil, err
+		}
+		if result.IsMultiValue() {
+			return result.MultiValue(), nil
+		}
+		return result.SingleValue(), nil
 	}
-	wg.Wait()
+	return executeCommand(client, args)
+}
 
-	stats.PrintFinalStats()
+// createClientPool creates PoolSize clients sharing the same read strategy.
+// Each client is named after its pool slot using the given tag.
+func createClientPool(config *Config, readFrom api.ReadFrom, tag string) ([]interface{}, error) {
+	pool := make([]interface{}, config.PoolSize)
+	for i := 0; i < config.PoolSize; i++ {
+		client, err := createClient(config, readFrom, connectionName(config, tag, i))
+		if err != nil {
+			closeClients(pool[:i])
+			return nil, err
+		}
+		pool[i] = client
+	}
+	return pool, nil
+}
 
-	// Close all clients
-	for _, client := range clientPool {
+// closeClients closes every client in the pool
+func closeClients(pool []interface{}) {
+	for _, client := range pool {
 		if c, ok := client.(*api.GlideClient); ok {
 			c.Close()
 		} else if c, ok := client.(*api.GlideClusterClient); ok {
 			c.Close()
 		}
 	}
+}
 
-	return nil
+// Benchmark holds the resources shared by all phases of a benchmark run
+type Benchmark struct {
+	config             *Config
+	clientPool         []interface{}
+	replicaPool        []interface{} // Replica read pool for --replica-read-ratio (nil if disabled)
+	newCustomCommand   CustomCommandFactory
+	inflight           *ConcurrencyLimiter // Global in-flight cap (nil without --max-inflight)
+	poolMu             sync.RWMutex        // Guards pool slots replaced by the watchdog
+	phaseMu            sync.Mutex          // Guards the state of the running phase
+	phaseQPS           *QPSController      // Rate limiter of the running phase, for diagnostic dumps
+	phaseWatchdog      *Watchdog           // Watchdog of the running phase (nil if disabled)
+	stopDiagnostics    func()
+	stopTokenRefresh   func()
+	customCommandNames []string            // Commands issued by template and mix workloads
+	mix                *CommandMix         // Command mix of --command-mix (nil otherwise)
+	skippedCommands    []string            // Mix entries skipped as unsupported by the server
+	capabilities       *ServerCapabilities // Detected server capabilities (nil if not checked)
+	stallClient        interface{}         // Dedicated client of --stall-interval (nil if disabled)
+	probeClient        interface{}         // Dedicated client of --rtt-probe (nil if disabled)
+	metadata           *RunMetadata        // Environment of the run, embedded in result documents
+	expiry             *ExpiryStats        // Read outcomes of -t expiry (nil otherwise)
+	slots              *SlotCounter        // Operations per hash slot in cluster mode (nil otherwise)
+	sampledKeys        []string            // Existing keys read by -t get with --scan-sample
+	nodePools          *NodePools          // Per-node connections of --node-pool (nil otherwise)
+	workload           *SeededWorkload     // Keys and values of --deterministic (nil otherwise)
+	ratio              *CommandRatio       // SET:GET mix of -ratio (nil otherwise)
+	tracer             *OTelTracer         // Span exporter of --otel-trace-sample (nil if disabled)
 }
 
-// Global configuration
-var config Config
+// NewBenchmark resolves the custom command and creates the client pools
+func NewBenchmark(config *Config) (*Benchmark, error) {
+	b := &Benchmark{config: config}
+	if config.Deterministic {
+		b.workload = NewSeededWorkload(config)
+	}
+	if config.Ratio != "" {
+		var err error
+		if b.ratio, err = parseRatio(config.Ratio); err != nil {
+			return nil, err
+		}
+	}
+	if config.IsCluster {
+		b.slots = &SlotCounter{}
+	}
 
-// CustomCommandStandalone implements custom commands for standalone mode
-type CustomCommandStandalone struct{}
+	if build, ok := moduleWorkloads[config.Command]; ok {
+		template, err := build(config)
+		if err != nil {
+			return nil, err
+		}
+		b.newCustomCommand = newTemplateCommandFactory(template)
+		b.customCommandNames = []string{template.name}
+	} else if name, ok := paginationCommands[config.Command]; ok {
+		b.newCustomCommand = newPaginationFactory(config)
+		b.customCommandNames = []string{name}
+	} else if config.Command == "expiry" {
+		b.expiry = newExpiryStats(config)
+		b.newCustomCommand = newExpiryFactory(config, b.expiry)
+		b.customCommandNames = []string{"SET", "GET"}
+	} else if config.Command == "churn" {
+		b.newCustomCommand = newChurnFactory(config)
+		b.customCommandNames = []string{"SET", "DEL"}
+	} else if config.Command == "custom" {
+		if config.WorkloadCommand != "" {
+			b.newCustomCommand = newSubprocessCommandFactory(config.WorkloadCommand)
+		} else if config.CommandMixFile != "" {
+			mix, err := LoadCommandMix(config.CommandMixFile, config)
+			if err != nil {
+				return nil, err
+			}
+			b.newCustomCommand = newMixCommandFactory(mix)
+			b.mix = mix
+			for _, entry := range mix.Entries {
+				b.customCommandNames = append(b.customCommandNames, entry.Template.name)
+			}
+		} else if config.CommandTemplate != "" {
+			template, err := ParseCommandTemplate(config.CommandTemplate, config)
+			if err != nil {
+				return nil, err
+			}
+			b.newCustomCommand = newTemplateCommandFactory(template)
+			b.customCommandNames = []string{template.name}
+		} else {
+			factory, err := loadCustomCommandFactory(config.PluginPath)
+			if err != nil {
+				return nil, err
+			}
+			b.newCustomCommand = factory
+		}
+	}
 
-func (c *CustomCommandStandalone) execute(client *api.GlideClient) error {
+	// Create client pool
+	readFrom := api.Primary
+	if config.ReadFromReplica {
+		readFrom = api.PreferReplica
+	}
 	var err error
-	_, err = client.Set("custom key", "custom value")
+	b.clientPool, err = createClientPool(config, readFrom, "w")
+	if err != nil {
+		return nil, err
+	}
+	if config.NodePool != "" {
+		if b.nodePools, err = newNodePools(config, b.clientPool[0]); err != nil {
+			closeClients(b.clientPool)
+			return nil, err
+		}
+	}
 
-	return err
-}
+	b.stopDiagnostics = b.watchDiagnostics()
 
-// CustomCommandCluster implements custom commands for cluster mode
-type CustomCommandCluster struct{}
+	if config.MaxInflight > 0 {
+		b.inflight = NewConcurrencyLimiter(config.MaxInflight)
+		b.inflight.WatchSignals()
+	}
 
-func (c *CustomCommandCluster) execute(client *api.GlideClusterClient) error {
-	var err error
-	_, err = client.Set("custom key", "custom value")
+	if config.StallInterval > 0 || config.PauseAt > 0 {
+		b.stallClient, err = createStallClient(config)
+		if err != nil {
+			b.Close()
+			return nil, err
+		}
+	}
 
-	return err
-}
+	if config.ProbeInterval > 0 {
+		b.probeClient, err = createProbeClient(config)
+		if err != nil {
+			b.Close()
+			return nil, err
+		}
+	}
 
-// main is the entry point for the benchmark tool
-func main() {
-	// Parse command line flags
-	flag.StringVar(&config.Host, "H", "127.0.0.1", "Server hostname")
-	flag.IntVar(&config.Port, "p", 6379, "Server port")
-	flag.IntVar(&config.PoolSize, "c", 50, "Number of parallel connections")
-	flag.Int64Var(&config.TotalRequests, "n", 100000, "Total number of requests")
-	flag.IntVar(&config.DataSize, "d", 3, "Data size of value in bytes for SET")
-	flag.StringVar(&config.Command, "t", "set", "Command to benchmark set, get or custom")
-	flag.Int64Var(&config.RandomKeyspace, "r", 0, "Use random keys from 0 to keyspacelen-1")
-	flag.IntVar(&config.NumThreads, "threads", 1, "Number of worker threads")
-	flag.IntVar(&config.TestDuration, "test-duration", 0, "Test duration in seconds")
-	flag.Int64Var(&config.SequentialKeyLen, "sequential", 0, "Use sequential keys")
-	flag.IntVar(&config.QPS, "qps", 0, "Queries per second limit")
-	flag.IntVar(&config.StartQPS, "start-qps", 0, "Starting QPS for dynamic rate")
-	flag.IntVar(&config.EndQPS, "end-qps", 0, "Ending QPS for dynamic rate")
-	flag.IntVar(&config.QPSChangeInterval, "qps-change-interval", 0, "Interval for QPS changes in seconds")
-	flag.IntVar(&config.QPSChange, "qps-change", 0, "QPS change amount per interval (linear mode only)")
-	flag.StringVar(&config.QPSRampMode, "qps-ramp-mode", "linear", "QPS ramp mode: linear or exponential")
-	flag.Float64Var(&config.QPSRampFactor, "qps-ramp-factor", 0, "Explicit multiplier for exponential QPS ramp (e.g., 2.0 to double QPS each interval)")
-	flag.BoolVar(&config.UseTLS, "tls", false, "Use TLS connection")
-	flag.BoolVar(&config.IsCluster, "cluster", false, "Use cluster client")
-	flag.BoolVar(&config.ReadFromReplica, "read-from-replica", false, "Read from replica nodes")
-	flag.IntVar(&config.RequestTimeout, "request-timeout", 0, "Request timeout in milliseconds")
-	flag.Parse()
+	// Create a second pool for replica reads when mixing primary and replica reads
+	if config.ReplicaReadRatio > 0 {
+		b.replicaPool, err = createClientPool(config, api.PreferReplica, "r")
+		if err != nil {
+			b.Close()
+			return nil, err
+		}
+	}
 
-	config.UseSequential = config.SequentialKeyLen > 0
+	if tokenAuth != nil {
+		b.stopTokenRefresh = b.watchTokenRefresh()
+	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	if config.OTelTraceSample > 0 {
+		b.tracer, err = NewOTelTracer(config)
+		if err != nil {
+			b.Close()
+			return nil, err
+		}
+	}
 
-	// Handle Ctrl+C
+	return b, nil
+}
+
+// poolClient returns the client in a slot of the primary or replica pool
+func (b *Benchmark) poolClient(replica bool, index int) interface{} {
+	b.poolMu.RLock()
+	defer b.poolMu.RUnlock()
+	if replica {
+		return b.replicaPool[index]
+	}
+	return b.clientPool[index]
+}
+
+// recycleClient replaces the client in a pool slot with a new connection and
+// closes the old client, which fails any request stuck on it
+func (b *Benchmark) recycleClient(replica bool, index int) error {
+	old, err := b.replaceClient(replica, index)
+	if err != nil {
+		return err
+	}
+	go closeClients([]interface{}{old})
+	return nil
+}
+
+// replaceClient puts a new connection in a pool slot and returns the old client
+func (b *Benchmark) replaceClient(replica bool, index int) (interface{}, error) {
+	readFrom, tag := api.Primary, "w"
+	if b.config.ReadFromReplica {
+		readFrom = api.PreferReplica
+	}
+	if replica {
+		readFrom, tag = api.PreferReplica, "r"
+	}
+	client, err := createClient(b.config, readFrom, connectionName(b.config, tag, index))
+	if err != nil {
+		return nil, err
+	}
+
+	b.poolMu.Lock()
+	pool := b.clientPool
+	if replica {
+		pool = b.replicaPool
+	}
+	old := pool[index]
+	pool[index] = client
+	b.poolMu.Unlock()
+	return old, nil
+}
+
+// Close closes all clients of the benchmark
+func (b *Benchmark) Close() {
+	if b.stopDiagnostics != nil {
+		b.stopDiagnostics()
+	}
+	if b.stopTokenRefresh != nil {
+		b.stopTokenRefresh()
+	}
+	if b.inflight != nil {
+		b.inflight.StopSignals()
+	}
+	if b.tracer != nil {
+		b.tracer.Close()
+	}
+	closeClients(b.clientPool)
+	closeClients(b.replicaPool)
+	b.nodePools.Close()
+	if b.stallClient != nil {
+		closeClients([]interface{}{b.stallClient})
+	}
+	if b.probeClient != nil {
+		closeClients([]interface{}{b.probeClient})
+	}
+}
+
+// runPhase runs the worker goroutines until the configured request count or
+// duration is reached. The config may differ from the benchmark's config to
+// run stages with different load settings on the same clients.
+func (b *Benchmark) runPhase(ctx context.Context, config *Config, stats *BenchmarkStats, qpsController *QPSController) {
+	// The workers stop when the duration elapses or the request count is
+	// reached, whichever comes first
+	if config.TestDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(config.TestDuration)*time.Second)
+		defer cancel()
+	}
+	stats.skipped = b.skippedCommands
+	rampDown := newRampDown(config, time.Now())
+	qpsController.rampDown = rampDown
+
+	if b.inflight != nil {
+		stopWaking := context.AfterFunc(ctx, b.inflight.wake)
+		defer stopWaking()
+	}
+
+	watchdog := newWatchdog(b, config)
+	b.phaseMu.Lock()
+	b.phaseQPS, b.phaseWatchdog = qpsController, watchdog
+	b.phaseMu.Unlock()
+	if watchdog != nil {
+		watchdogCtx, stopWatchdog := context.WithCancel(ctx)
+		go watchdog.Run(watchdogCtx)
+		defer func() {
+			stopWatchdog()
+			watchdog.PrintSummary()
+		}()
+	}
+
+	if config.StallInterval > 0 {
+		stallCtx, stopStalls := context.WithCancel(ctx)
+		go b.injectStalls(stallCtx, config, stats)
+		defer stopStalls()
+	}
+	if config.PauseAt > 0 {
+		pauseCtx, stopPause := context.WithCancel(ctx)
+		measured := make(chan struct{})
+		go func() {
+			defer close(measured)
+			b.measurePause(pauseCtx, config, stats)
+		}()
+		defer func() {
+			stopPause()
+			<-measured
+		}()
+	}
+
+	reconnects := newReconnectStats(config)
+	probe := newRTTProbe(config)
+	stats.mu.Lock()
+	stats.reconnects = reconnects
+	stats.probe = probe
+	stats.expiry = b.expiry
+	stats.mu.Unlock()
+	if probe != nil {
+		probeCtx, stopProbe := context.WithCancel(ctx)
+		go probe.Run(probeCtx, b.probeClient)
+		defer stopProbe()
+	}
+	if config.EvictionPressure {
+		if monitor, err := newEvictionMonitor(config, b.poolClient(false, 0)); err != nil {
+			slog.Warn("eviction monitoring disabled", "error", err)
+		} else {
+			stats.mu.Lock()
+			stats.eviction = monitor
+			stats.mu.Unlock()
+			monitorCtx, stopMonitor := context.WithCancel(ctx)
+			go monitor.Run(monitorCtx)
+			defer func() {
+				stopMonitor()
+				monitor.sample()
+			}()
+		}
+	}
+
+	// Update worker goroutine
+	reporterCtx, stopReporter := context.WithCancel(ctx)
+	reported := make(chan struct{})
+	go func() {
+		defer close(reported)
+		stats.RunReporter(reporterCtx)
+	}()
+	defer func() {
+		stopReporter()
+		<-reported
+	}()
+
+	// Next index of the -sequential keyspace, shared by all workers. A resumed
+	// run continues where the checkpointed one stopped.
+	sequence := atomic.LoadInt64(&stats.requestsCompleted)
+
+	var wg sync.WaitGroup
+	for i := 0; i < config.NumThreads; i++ {
+		wg.Add(1)
+		go func(threadID int) {
+			defer wg.Done()
+			defer pinWorker(config)()
+			prefix := keyPrefix(config)
+			keyspace := config.RandomKeyspace
+			backoff := newConditionBackoff(config)
+			batch := newStatsBatch(stats, config)
+			defer batch.Flush()
+			var requests int64 // Requests sent by this worker
+			pacer := qpsController.Pacer(threadID)
+			var payload *PayloadSource
+			if config.Command == "set" || b.ratio != nil {
+				payload = newPayloadSource(config, config.DataSize)
+			}
+
+			var customCommand CustomCommand
+			if isCustomWorkload(config.Command) {
+				customCommand = b.newCustomCommand()
+				client := b.poolClient(false, threadID%config.PoolSize)
+				if err := customCommand.Setup(client, threadID, config.PluginArgs); err != nil {
+					slog.Error("custom command setup failed", "thread", threadID, "error", err)
+					return
+				}
+				defer func() {
+					if err := customCommand.Teardown(client); err != nil {
+						slog.Error("custom command teardown failed", "thread", threadID, "error", err)
+					}
+				}()
+			}
+
+			// prepare chooses the key and value of a set or get request before
+			// it is sent, so the requests of a pipeline can be sent concurrently
+			prepare := func(r *workerRequest) {
+				switch r.command {
+				case "set":
+					r.key = fmt.Sprintf("%s:%d:%d", prefix, threadID, r.number)
+					if config.UseSequential {
+						r.key = fmt.Sprintf("%s:%d", prefix,
+							config.KeyspaceOffset+(atomic.AddInt64(&sequence, 1)-1)%config.SequentialKeyLen)
+					} else if config.RandomKeyspace > 0 {
+						r.key = getRandomKey(prefix, config.KeyspaceOffset, keyspace)
+					}
+					if b.workload != nil {
+						index := b.workload.Next()
+						r.key = b.workload.Key(prefix, index, keyspace)
+						r.data = b.workload.Value(index, config.DataSize)
+					} else {
+						r.data = payload.Next()
+					}
+
+				case "get":
+					r.key = "somekey"
+					if config.NamespaceKeys {
+						r.key = prefix + ":somekey"
+					}
+					if config.RandomKeyspace > 0 {
+						r.key = getRandomKey(prefix, config.KeyspaceOffset, keyspace)
+					} else if b.sampledKeys != nil {
+						r.key = b.sampledKeys[benchRand.Intn(len(b.sampledKeys))]
+					}
+					if b.workload != nil && (config.RandomKeyspace > 0 || config.UseSequential) {
+						r.key = b.workload.Key(prefix, b.workload.Next(), keyspace)
+					}
+				}
+			}
+
+			// send executes a prepared request
+			send := func(client interface{}, r *workerRequest) requestResult {
+				var result requestResult
+				switch r.command {
+				case "set":
+					b.slots.Record(r.key)
+					client, release := b.nodePools.Acquire(client, r.key)
+					if c, ok := client.(*api.GlideClient); ok {
+						_, result.err = c.Set(r.key, r.data)
+					} else if c, ok := client.(*api.GlideClusterClient); ok {
+						_, result.err = c.Set(r.key, r.data)
+					}
+					release()
+					result.sent, result.received = respCommandSize("SET", r.key, r.data), respOKSize
+
+				case "get":
+					b.slots.Record(r.key)
+					client, release := b.nodePools.Acquire(client, r.key)
+					var value api.Result[string]
+					if c, ok := client.(*api.GlideClient); ok {
+						value, result.err = c.Get(r.key)
+					} else if c, ok := client.(*api.GlideClusterClient); ok {
+						value, result.err = c.Get(r.key)
+					}
+					release()
+					result.sent, result.received = respCommandSize("GET", r.key), respNilSize
+					if value.IsNil() {
+						result.miss = true
+					} else {
+						result.received = respBulkSize(len(value.Value()))
+					}
+
+				default:
+					result.err = customCommand.Execute(client)
+					if sizer, ok := customCommand.(CustomCommandSizer); ok && result.err == nil {
+						result.sent, result.received = sizer.TransferredBytes()
+					}
+				}
+				return result
+			}
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+					if config.TotalRequests > 0 &&
+						atomic.LoadInt64(&stats.requestsCompleted)+batch.Pending() >= config.TotalRequests {
+						return
+					}
+					if rampDown != nil && rampDown.WorkerRetired(threadID, config.NumThreads, time.Now()) {
+						return
+					}
+
+					// Workers start at different slots and rotate through the pool
+					clientIndex := (threadID + int(requests)) % config.PoolSize
+					request := requests
+					requests++
+					path := ""
+					replica := false
+					if config.Command == "get" && b.replicaPool != nil {
+						path = "read:primary"
+						if benchRand.Intn(100) < config.ReplicaReadRatio {
+							path = "read:replica"
+							replica = true
+						}
+					}
+					command := config.Command
+					if b.ratio != nil {
+						command = b.ratio.Command(request)
+						path = strings.ToUpper(command)
+					}
+					client := b.poolClient(replica, clientIndex)
+
+					if preparer, ok := customCommand.(CustomCommandPreparer); ok {
+						if err := preparer.Prepare(); err != nil {
+							if errors.Is(err, errCustomCommandDone) {
+								return
+							}
+							batch.AddError("", "")
+							logRequestError(threadID, err)
+							continue
+						}
+					}
+
+					if keyer, ok := customCommand.(CustomCommandKeyer); ok {
+						b.slots.Record(keyer.Key())
+					}
+
+					if !pacer.Throttle(ctx) {
+						return
+					}
+
+					if b.inflight != nil && !b.inflight.Acquire(ctx) {
+						return
+					}
+					if labeler, ok := customCommand.(CustomCommandLabeler); ok {
+						path = labeler.Label()
+					}
+					name := ""
+					if customCommand == nil {
+						name = strings.ToUpper(command)
+					} else if namer, ok := customCommand.(CustomCommandNamer); ok {
+						name = namer.CommandName()
+					}
+
+					if config.KeyspaceGrowth > 0 {
+						keyspace = growingKeyspace(config, time.Since(stats.startTime))
+					}
+
+					pending := []*workerRequest{{number: request, command: command, path: path, name: name}}
+					for len(pending) < config.Pipeline && (config.TotalRequests == 0 ||
+						atomic.LoadInt64(&stats.requestsCompleted)+batch.Pending()+int64(len(pending)) < config.TotalRequests) {
+						next := &workerRequest{number: requests, command: config.Command, path: path}
+						if b.ratio != nil {
+							next.command = b.ratio.Command(next.number)
+							next.path = strings.ToUpper(next.command)
+						}
+						next.name = strings.ToUpper(next.command)
+						// The batch only grows into free in-flight slots
+						if b.inflight != nil && !b.inflight.TryAcquire() {
+							break
+						}
+						if !pacer.Throttle(ctx) {
+							if b.inflight != nil {
+								b.inflight.Release()
+							}
+							break
+						}
+						requests++
+						pending = append(pending, next)
+					}
+					for _, r := range pending {
+						prepare(r)
+					}
+
+					watchdog.Begin(threadID, replica, clientIndex)
+					start := time.Now()
+					var results []requestResult
+					switch {
+					case len(pending) > 1:
+						results = runPipeline(client, pending, send)
+					case config.RequestDeadline > 0:
+						// Custom commands are not safe for concurrent use, so an
+						// abandoned execution is awaited before the next request
+						results = []requestResult{runWithDeadline(ctx, time.Duration(config.RequestDeadline)*time.Millisecond,
+							func() requestResult { return send(client, pending[0]) }, customCommand != nil)}
+					default:
+						results = []requestResult{send(client, pending[0])}
+					}
+					if len(pending) == 1 {
+						pending[0].finish(start)
+					}
+					watchdog.End(threadID)
+					if b.inflight != nil {
+						for range pending {
+							b.inflight.Release()
+						}
+					}
+
+					if reconnects.Due(replica, clientIndex) {
+						b.reconnectClient(replica, clientIndex, reconnects)
+					}
+
+					for i, result := range results {
+						path, command, name := pending[i].path, pending[i].command, pending[i].name
+						latency := pending[i].latency
+						err := result.err
+						if errors.Is(err, errCustomCommandDone) {
+							return
+						}
+						if errors.Is(err, errRequestCancelled) {
+							// Interrupted by the end of the run, not a failure of the server
+							stats.AddCancelled()
+							return
+						}
+						if b.tracer != nil {
+							b.tracer.Record(name, pending[i].start, latency, err)
+						}
+
+						if err == nil {
+							stats.AddTransfer(result.sent, result.received)
+							if command == "get" {
+								stats.AddLookup(result.miss)
+							}
+							if !qpsController.ThrottleBytes(ctx, result.sent+result.received) {
+								return
+							}
+						}
+						if err != nil {
+							if condition := serverCondition(err); condition != "" {
+								stats.AddCondition(condition)
+								if !backoff.Wait(ctx, stats) {
+									return
+								}
+								continue
+							}
+							stats.classifyError(err)
+							batch.AddError(path, name)
+							logRequestError(threadID, err)
+							continue
+						}
+						backoff.Reset()
+						if result.sent+result.received > 0 {
+							stats.AddPayloadLatency(result.sent+result.received, latency)
+						}
+						batch.AddLatency(path, name, latency)
+					}
+				}
+			}
+		}(i)
+	}
+
+	// Wait for the request count, the duration or an interrupt
+	wg.Wait()
+}
+
+// runStage runs one phase with its own statistics and QPS controller
+func (b *Benchmark) runStage(ctx context.Context, config *Config) *BenchmarkStats {
+	stats := NewBenchmarkStats(config)
+	b.runPhase(ctx, config, stats, NewQPSController(config))
+	stats.Stop()
+	return stats
+}
+
+// printConfig prints the benchmark configuration header
+func printConfig(config *Config) {
+	fmt.Println("Valkey Benchmark")
+	fmt.Printf("Run ID: %s\n", config.RunID)
+	fmt.Printf("Seed: %d\n", config.Seed)
+	if config.Deterministic {
+		fmt.Printf("Deterministic workload: yes\n")
+	}
+	if config.NamespaceKeys || config.KeyPrefix != "key" {
+		fmt.Printf("Key namespace: %s\n", keyPrefix(config))
+	}
+	if config.ConfigEndpoint != "" {
+		service := managedService(config.Host)
+		if service == "" {
+			service = "cluster"
+		}
+		fmt.Printf("Configuration Endpoint: %s (%s)\n", config.ConfigEndpoint, service)
+	}
+	fmt.Printf("Host: %s\n", config.Host)
+	fmt.Printf("Port: %d\n", config.Port)
+	fmt.Printf("Threads: %d\n", config.NumThreads)
+	if config.TotalRequests > 0 {
+		fmt.Printf("Total Requests: %d\n", config.TotalRequests)
+	}
+	if config.TestDuration > 0 {
+		fmt.Printf("Test Duration: %d seconds\n", config.TestDuration)
+	}
+	fmt.Printf("Data Size: %d\n", config.DataSize)
+	fmt.Printf("Command: %s\n", config.Command)
+	if config.Ratio != "" {
+		fmt.Printf("Ratio (SET:GET): %s\n", config.Ratio)
+	}
+	if config.Pipeline > 1 {
+		fmt.Printf("Pipeline: %d\n", config.Pipeline)
+	}
+	if config.PluginPath != "" {
+		fmt.Printf("Plugin: %s\n", config.PluginPath)
+	}
+	if config.WorkloadCommand != "" {
+		fmt.Printf("Workload Command: %s\n", config.WorkloadCommand)
+	}
+	if config.CommandTemplate != "" {
+		fmt.Printf("Command Template: %s\n", config.CommandTemplate)
+	}
+	if config.CommandMixFile != "" {
+		fmt.Printf("Command Mix: %s\n", config.CommandMixFile)
+	}
+	if config.Populate {
+		fmt.Printf("Populate: %d keys\n", workflowKeyspace(config))
+	}
+	if config.Warmup > 0 {
+		fmt.Printf("Warm-up: %d seconds\n", config.Warmup)
+	}
+	fmt.Printf("Is Cluster: %v\n", config.IsCluster)
+	if config.NodePool != "" {
+		fmt.Printf("Node Pool: %s\n", config.NodePool)
+	}
+	if config.ProxyMode {
+		fmt.Printf("Proxy Mode: true\n")
+	}
+	fmt.Printf("Read from Replica: %v\n", config.ReadFromReplica)
+	if config.ReplicaReadRatio > 0 {
+		fmt.Printf("Replica Read Ratio: %d%%\n", config.ReplicaReadRatio)
+	}
+	if config.RequestTimeout > 0 {
+		fmt.Printf("Request Timeout: %d ms\n", config.RequestTimeout)
+	}
+	fmt.Printf("Use TLS: %v\n", config.UseTLS)
+	if config.Password != "" {
+		user := config.User
+		if user == "" {
+			user = "default"
+		}
+		fmt.Printf("Auth User: %s\n", user)
+	}
+	if config.AuthProvider != "" {
+		fmt.Printf("Auth Provider: %s\n", config.AuthProvider)
+	}
+	if config.EvictionPressure {
+		fmt.Printf("Eviction Pressure: true\n")
+	}
+	if config.KeyspaceGrowth > 0 {
+		fmt.Printf("Keyspace Growth: %.0f keys/s\n", config.KeyspaceGrowth)
+	}
+	if config.ProbeInterval > 0 {
+		fmt.Printf("RTT Probe: PING every %d ms\n", config.ProbeInterval)
+	}
+	if config.ReconnectEvery > 0 {
+		fmt.Printf("Reconnect Every: %d requests\n", config.ReconnectEvery)
+	}
+	if config.RampDownSeconds > 0 {
+		fmt.Printf("Ramp-Down: %d seconds\n", config.RampDownSeconds)
+	}
+	if config.BusyPoll {
+		fmt.Printf("Busy Poll: true\n")
+	}
+	if config.Arrival == "poisson" {
+		fmt.Printf("Arrival: poisson (open-loop)\n")
+	}
+	if config.QPSProfile == "sine" {
+		fmt.Printf("QPS Profile: sine from %d to %d QPS, period %d seconds\n", config.StartQPS, config.EndQPS, config.QPSPeriod)
+	}
+	if config.QPSSchedule != "" {
+		fmt.Printf("QPS Schedule: %s\n", config.QPSSchedule)
+	}
+	if config.StartAt != "" {
+		fmt.Printf("Start At: %s\n", config.StartAt)
+	}
+	if config.MaxInflight > 0 {
+		fmt.Printf("Max In-Flight: %d\n", config.MaxInflight)
+	}
+	if config.PauseAt > 0 {
+		fmt.Printf("Pause: CLIENT PAUSE %s for %d ms at %d seconds\n", strings.ToUpper(config.PauseMode), config.PauseDuration, config.PauseAt)
+	}
+	if config.StallInterval > 0 {
+		fmt.Printf("Stall Injection: %s for %d ms every %d seconds\n", strings.Join(stallCommand(config)[:2], " "), config.StallDuration, config.StallInterval)
+	}
+	if config.TargetMbps > 0 {
+		fmt.Printf("Target Bandwidth: %g Mbit/s\n", config.TargetMbps)
+	}
+	if config.ScenarioFile != "" {
+		fmt.Printf("Scenario: %s\n", config.ScenarioFile)
+	}
+	if config.KneeSearch {
+		fmt.Printf("Knee Search: 1 to %d threads, %d seconds per stage\n", config.KneeMaxThreads, config.SweepStageSeconds)
+	}
+	if sweeping(config) {
+		fmt.Printf("Sweep: data sizes %v, pool sizes %v, pipelines %v, %d seconds per stage\n",
+			config.SweepDataSizes, config.SweepPoolSizes, config.SweepPipelines, config.SweepStageSeconds)
+	}
+	if len(config.WorkflowStages) > 0 {
+		fmt.Printf("Workflow: %s (%d keys)\n", strings.Join(config.WorkflowStages, " -> "), workflowKeyspace(config))
+	}
+	fmt.Println()
+}
+
+// RunBenchmark executes the benchmark with the given configuration
+func RunBenchmark(ctx context.Context, config *Config) error {
+	var scenario *Scenario
+	if config.ScenarioFile != "" {
+		var err error
+		if scenario, err = LoadScenario(config.ScenarioFile); err != nil {
+			return err
+		}
+	}
+
+	var resumed *checkpointState
+	if config.Resume {
+		var err error
+		if resumed, err = resumeCheckpoint(config); err != nil {
+			return err
+		}
+	}
+
+	printConfig(config)
+	if config.ManifestFile != "" {
+		if err := writeManifest(config.ManifestFile, config); err != nil {
+			return err
+		}
+		fmt.Printf("Manifest written to %s\n", config.ManifestFile)
+	}
+	if resumed != nil {
+		fmt.Printf("Resuming from checkpoint saved at %s (%.0f seconds, %d requests completed)\n\n",
+			resumed.Saved.Format(time.RFC3339), resumed.Elapsed, resumed.Requests)
+	}
+
+	if config.WaitForServer > 0 {
+		if err := waitForServer(ctx, config, time.Duration(config.WaitForServer)*time.Second); err != nil {
+			return err
+		}
+	}
+
+	benchmark, err := NewBenchmark(config)
+	if err != nil {
+		return err
+	}
+	defer benchmark.Close()
+
+	if !config.SkipHealthCheck {
+		if err := benchmark.checkHealth(); err != nil {
+			return err
+		}
+	}
+
+	if !config.SkipCapabilityCheck && !config.ProxyMode {
+		if err := benchmark.checkCapabilities(); err != nil {
+			return err
+		}
+	}
+
+	if err := benchmark.prepareWorkload(ctx); err != nil {
+		return err
+	}
+	if config.WarmupOps > 0 {
+		if err := benchmark.warmUp(ctx); err != nil {
+			return err
+		}
+	}
+	if config.Populate && resumed == nil {
+		if err := benchmark.populate(ctx); err != nil {
+			return err
+		}
+	}
+
+	benchmark.metadata = benchmark.collectMetadata()
+	if config.ConfigEndpoint != "" {
+		fmt.Printf("Discovered %d primaries and %d replicas through %s\n",
+			benchmark.metadata.ClusterPrimaries, benchmark.metadata.ClusterReplicas, config.ConfigEndpoint)
+	}
+	if config.ConfigSnapshotFile != "" || config.ConfigDiffFile != "" {
+		snapshot, err := benchmark.snapshotServerConfig()
+		if err != nil {
+			return err
+		}
+		benchmark.metadata.ServerConfig = snapshot.Config
+		if config.ConfigDiffFile != "" {
+			previous, err := loadConfigSnapshot(config.ConfigDiffFile)
+			if err != nil {
+				return err
+			}
+			printConfigDiff(previous, snapshot)
+		}
+		if config.ConfigSnapshotFile != "" {
+			if err := writeConfigSnapshot(config.ConfigSnapshotFile, snapshot); err != nil {
+				return err
+			}
+			fmt.Printf("Server config snapshot written to %s\n", config.ConfigSnapshotFile)
+		}
+	}
+
+	if !config.ProxyMode {
+		keysBefore, err := benchmark.countKeys()
+		if err != nil {
+			slog.Warn("key count failed", "error", err)
+		} else {
+			defer benchmark.printKeyCountDelta(keysBefore)
+		}
+	}
+	if benchmark.slots != nil {
+		defer benchmark.printSlotBalance()
+	}
+	if benchmark.nodePools != nil {
+		defer benchmark.printNodePools()
+	}
+
+	if config.StartAt != "" {
+		if err := waitForStartTime(ctx, config); err != nil {
+			return err
+		}
+	}
+	if config.Warmup > 0 {
+		if err := benchmark.warmUpWorkload(ctx); err != nil {
+			return err
+		}
+	}
+	if config.ProcessReady != "" {
+		if err := syncProcesses(ctx, config); err != nil {
+			return err
+		}
+	}
+
+	if len(config.CurveQPS) > 0 {
+		return benchmark.RunCurve(ctx)
+	}
+	if sweeping(config) {
+		return benchmark.RunSweep(ctx)
+	}
+	if config.KneeSearch {
+		return benchmark.RunKneeSearch(ctx)
+	}
+	if scenario != nil {
+		return benchmark.RunScenario(ctx, scenario)
+	}
+	if len(config.WorkflowStages) > 0 {
+		return benchmark.RunWorkflow(ctx, config.WorkflowStages)
+	}
+
+	exporters, err := createExporters(config)
+	if err != nil {
+		return err
+	}
+
+	stats := NewBenchmarkStats(config)
+	stats.workload = benchmark.workload
+	if len(exporters) > 0 {
+		stats.exporters = NewExporterHub(config.RunID, exporters)
+		defer stats.exporters.Close()
+		stats.exporters.PublishLifecycleEvent("started", map[string]interface{}{
+			"command":  config.Command,
+			"target":   fmt.Sprintf("%s:%d", config.Host, config.Port),
+			"threads":  config.NumThreads,
+			"clients":  config.PoolSize,
+			"requests": config.TotalRequests,
+			"duration": config.TestDuration,
+			"metadata": benchmark.metadata,
+		})
+	}
+	if resumed != nil {
+		stats.restore(resumed)
+	}
+	var stopCheckpointing func()
+	if config.CheckpointFile != "" {
+		stopCheckpointing = startCheckpointing(stats, config.CheckpointFile, time.Duration(config.CheckpointInterval)*time.Second)
+	}
+	var serverBefore *serverCalls
+	if !config.SkipReconciliation && !config.ProxyMode && resumed == nil && config.ProcessResult == "" {
+		if serverBefore, err = benchmark.readServerCalls(); err != nil {
+			slog.Warn("reconciliation failed", "error", err)
+		}
+	}
+	qpsController := NewQPSController(config)
+	if qpsController.isRamping() {
+		// Keep separate statistics for every QPS level of the ramp
+		stats.StartRampStage(qpsController.Target())
+		qpsController.onUpdate = stats.StartRampStage
+	}
+	benchmark.runPhase(ctx, config, stats, qpsController)
+	stats.Stop()
+	if stopCheckpointing != nil {
+		stopCheckpointing()
+		if ctx.Err() != nil {
+			fmt.Printf("\nCheckpoint saved to %s, rerun with --resume to continue\n", config.CheckpointFile)
+		} else {
+			if err := os.Remove(config.CheckpointFile); err != nil && !os.IsNotExist(err) {
+				slog.Warn("failed to remove checkpoint", "file", config.CheckpointFile, "error", err)
+			}
+		}
+	}
+	stats.PrintFinalStats()
+	if serverBefore != nil {
+		benchmark.printReconciliation(serverBefore, stats)
+	}
+	if config.OutputJSON != "" {
+		if err := writeResults(config.OutputJSON, stats.Results(benchmark.metadata)); err != nil {
+			return err
+		}
+		fmt.Printf("\nResults written to %s\n", config.OutputJSON)
+	}
+	if config.ProcessResult != "" {
+		if err := writeCheckpoint(config.ProcessResult, stats.checkpoint()); err != nil {
+			return err
+		}
+	}
+	if stats.exporters != nil {
+		summary := stats.Summary()
+		data := map[string]interface{}{
+			"duration_s":     summary.Duration,
+			"requests":       summary.Requests,
+			"errors":         summary.Errors,
+			"rps":            summary.RPS,
+			"sent_bytes":     summary.Sent,
+			"recv_bytes":     summary.Received,
+			"request_sizes":  stats.requestSizes.Buckets(),
+			"response_sizes": stats.responseSizes.Buckets(),
+			"metadata":       benchmark.metadata,
+		}
+		if summary.Latency != nil {
+			data["p50_ms"] = summary.Latency.p50
+			data["p99_ms"] = summary.Latency.p99
+		}
+		stats.exporters.PublishLifecycleEvent("finished", data)
+	}
+
+	if stats.dataset != nil {
+		if err := stats.dataset.WriteFile(config.LatencyDumpFile); err != nil {
+			return err
+		}
+		kept, seen := stats.dataset.Len()
+		fmt.Printf("\nWrote %d of %d latencies to %s\n", kept, seen, config.LatencyDumpFile)
+	}
+
+	return nil
+}
+
+// Global configuration
+var config Config
+
+// main is the entry point for the benchmark tool
+func main() {
+	// Parse command line flags
+	flag.StringVar(&config.Host, "H", "127.0.0.1", "Server hostname")
+	flag.IntVar(&config.Port, "p", 6379, "Server port")
+	flag.IntVar(&config.PoolSize, "c", 50, "Number of parallel connections")
+	flag.StringVar(&config.NodePool, "node-pool", "", "Cluster mode: connections per node for -t set and get, as a count or host:port=count list (*=count for unlisted nodes)")
+	flag.BoolVar(&config.Populate, "populate", false, "Load every key of the -r or -sequential keyspace with a -d byte value before the measured phase")
+	flag.IntVar(&config.Warmup, "warmup", 0, "Run the workload for N seconds before the measured phase and discard its statistics")
+	flag.IntVar(&config.WarmupOps, "warmup-ops", 0, "Send this many PING and SET operations on every client before the measured phase to exclude first-use costs")
+	flag.Int64Var(&config.TotalRequests, "n", 100000, "Total number of requests")
+	flag.IntVar(&config.DataSize, "d", 3, "Data size of value in bytes for SET")
+	flag.StringVar(&config.DataTemplate, "data-template", "", "Generate values as json (or json:<schema file>), csv rows or protobuf-like binary blobs instead of random letters")
+	flag.BoolVar(&config.RandomizeData, "randomize-data-per-request", false, "Generate a fresh value for every SET and {{data}} template expression instead of reusing one per thread")
+	flag.StringVar(&config.Command, "t", "set", "Command to benchmark: set, get, custom, json.set, json.get, json.arrappend, ft.search, ft.knn, bf.add, bf.exists, lrange, zrange, churn or expiry")
+	flag.StringVar(&config.SearchIndex, "ft-index", "", "Index name of -t ft.search and ft.knn (default: <key prefix>:idx, or <key prefix>:vidx for ft.knn)")
+	flag.StringVar(&config.SearchSchema, "ft-schema", "", "FT.CREATE schema of -t ft.search (default: \"title TEXT tag TAG score NUMERIC\")")
+	flag.StringVar(&config.SearchDocument, "ft-doc", "", "Field/value template of the hashes indexed before -t ft.search")
+	flag.Int64Var(&config.SearchDocuments, "ft-docs", 10000, "Number of documents indexed before -t ft.search (0 to skip)")
+	flag.StringVar(&config.SearchQuery, "ft-query", "", "Query template of -t ft.search")
+	flag.IntVar(&config.SearchLimit, "ft-limit", 10, "Maximum results returned per FT.SEARCH query")
+	flag.Int64Var(&config.PageSize, "page-size", 100, "Elements per window of -t lrange and zrange")
+	flag.Int64Var(&config.PageStride, "page-stride", 0, "Offset advance between windows of -t lrange and zrange (default: --page-size)")
+	flag.Int64Var(&config.CollectionSize, "collection-size", 100000, "Elements per collection paged by -t lrange and zrange")
+	flag.IntVar(&config.Collections, "collections", 1, "Number of collections paged by -t lrange and zrange")
+	flag.BoolVar(&config.PageFill, "page-fill", true, "Recreate the collections before -t lrange and zrange (false to page through existing data)")
+	flag.IntVar(&config.StatsBatch, "stats-batch", 16, "Requests each worker accumulates before adding them to the shared statistics")
+	flag.IntVar(&config.StatsFlush, "stats-flush", 10, "Milliseconds after which a worker adds its accumulated requests to the shared statistics")
+	flag.IntVar(&config.ProgressWindow, "progress-window", 1, "Seconds of latencies covered by the percentiles of the progress line")
+	flag.IntVar(&config.Processes, "processes", 1, "Run the benchmark in N child processes, each with its own client runtime and the full thread and client settings, and merge their results")
+	flag.IntVar(&config.ProcessIndex, "process-index", 0, "Internal: index of a --processes child")
+	flag.StringVar(&config.ProcessResult, "process-result", "", "Internal: file receiving the results of a --processes child")
+	flag.StringVar(&config.ProcessReady, "process-ready", "", "Internal: file signalling that child 0 of --processes has populated and warmed up")
+	flag.IntVar(&config.ServerBackoff, "server-backoff", 0, "After OOM or LOADING replies, back off exponentially up to N milliseconds until the condition clears")
+	flag.BoolVar(&config.EvictionPressure, "eviction-pressure", false, "Write new keys past maxmemory and report evicted keys (from INFO) next to the latency impact")
+	flag.Int64Var(&config.ExpiryTTL, "expiry-ttl", 1000, "TTL in milliseconds of the keys written by -t expiry")
+	flag.Int64Var(&config.ExpiryWindow, "expiry-window", 200, "-t expiry reads each key from this many milliseconds before to after its expiry")
+	flag.IntVar(&config.ExpiryReads, "expiry-reads", 10, "Reads per key of -t expiry, spread evenly over the expiry window")
+	flag.Int64Var(&config.ChurnKeys, "churn-keys", 100000, "Number of keys -t churn holds while inserting new keys and deleting the oldest")
+	flag.IntVar(&config.VectorDim, "vector-dim", 128, "Dimensionality of the vectors of -t ft.knn")
+	flag.IntVar(&config.VectorK, "vector-k", 10, "Number of nearest neighbours returned per -t ft.knn query")
+	flag.Int64Var(&config.VectorDocuments, "vector-docs", 10000, "Number of vectors indexed before -t ft.knn (0 to skip)")
+	flag.StringVar(&config.VectorFile, "vector-file", "", "File with one embedding vector per line, used instead of random vectors")
+	flag.StringVar(&config.VectorMetric, "vector-metric", "COSINE", "Distance metric of the vector index: L2, IP or COSINE")
+	flag.StringVar(&config.VectorAlgorithm, "vector-algorithm", "HNSW", "Vector index algorithm: HNSW or FLAT")
+	flag.Int64Var(&config.BloomCapacity, "bf-capacity", 100000, "Capacity of the filters reserved for -t bf.add and bf.exists")
+	flag.Float64Var(&config.BloomErrorRate, "bf-error-rate", 0.01, "False positive rate of the reserved filters")
+	flag.IntVar(&config.BloomFilters, "bf-filters", 1, "Number of Bloom filters the requests are spread over")
+	flag.StringVar(&config.BloomItem, "bf-item", "", "Item template of -t bf.add and bf.exists (default: item:{{rand:0:<capacity-1>}})")
+	flag.StringVar(&config.JSONDocument, "json-doc", "", "Document template written by -t json.set (default: a small document with nested fields)")
+	flag.StringVar(&config.JSONPath, "json-path", "", "JSONPath template read by -t json.get (default $) or appended to by -t json.arrappend (default $.tags)")
+	flag.StringVar(&config.JSONValue, "json-value", "", "JSON value template appended by -t json.arrappend (default a --datasize string)")
+	flag.Int64Var(&config.RandomKeyspace, "r", 0, "Use random keys from 0 to keyspacelen-1")
+	flag.Int64Var(&config.KeyspaceOffset, "keyspace-offset", 0, "Add N to the key numbers of -r and -sequential, e.g. -r 100000 -keyspace-offset 200000 uses keys 200000-299999")
+	flag.IntVar(&config.Pipeline, "pipeline", 1, "Keep N concurrent in-flight -t set or get requests per worker")
+	flag.StringVar(&config.Ratio, "ratio", "", "Mix SET and GET requests in this SETS:GETS ratio (e.g. 1:10), reported per command; requires -r")
+	flag.IntVar(&config.ScanSample, "scan-sample", 0, "Sample up to N existing keys with SCAN before the run and read them with -t get")
+	flag.StringVar(&config.ScanMatch, "scan-match", "*", "MATCH pattern of the keys sampled with --scan-sample")
+	flag.IntVar(&config.ScanCount, "scan-count", 1000, "COUNT hint of the SCAN calls of --scan-sample")
+	flag.Float64Var(&config.KeyspaceGrowth, "keyspace-growth", 0, "Grow the -r keyspace by this many keys per second during the run")
+	flag.Int64Var(&config.KeyspaceMax, "keyspace-max", 0, "Stop growing the keyspace at this many keys (0 = unbounded)")
+	flag.IntVar(&config.NumThreads, "threads", 1, "Number of worker threads")
+	flag.IntVar(&config.TestDuration, "test-duration", 0, "Test duration in seconds; with an explicit -n the run stops at whichever is reached first")
+	flag.Int64Var(&config.SequentialKeyLen, "sequential", 0, "Use sequential keys")
+	flag.IntVar(&config.QPS, "qps", 0, "Queries per second limit")
+	flag.IntVar(&config.StartQPS, "start-qps", 0, "Starting QPS for dynamic rate")
+	flag.IntVar(&config.EndQPS, "end-qps", 0, "Ending QPS for dynamic rate")
+	flag.IntVar(&config.QPSChangeInterval, "qps-change-interval", 0, "Interval for QPS changes in seconds")
+	flag.IntVar(&config.QPSChange, "qps-change", 0, "QPS change amount per interval (linear mode only)")
+	flag.BoolVar(&config.SkipCapabilityCheck, "skip-capability-check", false, "Don't verify on startup that the server supports the workload's commands")
+	flag.BoolVar(&config.SkipHealthCheck, "skip-health-check", false, "Don't verify PING, cluster state and connected replicas before the run")
+	flag.BoolVar(&config.SkipReconciliation, "skip-reconciliation", false, "Don't compare the client's request counts with the server's commandstats after the run")
+	flag.BoolVar(&config.ProxyMode, "proxy-mode", false, "Benchmark through a Redis-protocol proxy (twemproxy, Envoy): no cluster discovery, CLIENT or server inspection commands")
+	flag.IntVar(&config.RequestDeadline, "request-deadline", 0, "Abandon a request after N milliseconds regardless of the client timeout, counted separately from client timeouts")
+	flag.StringVar(&config.DumpDir, "dump-dir", ".", "Directory for diagnostic dumps written on SIGQUIT")
+	flag.IntVar(&config.StallInterval, "stall-interval", 0, "Stall the server every N seconds to measure recovery (0 to disable)")
+	flag.IntVar(&config.StallDuration, "stall-duration", 100, "Length of each injected stall in milliseconds")
+	flag.StringVar(&config.StallMode, "stall-mode", "sleep", "How stalls are injected: sleep (DEBUG SLEEP) or pause (CLIENT PAUSE)")
+	flag.IntVar(&config.PauseAt, "pause-at", 0, "Issue CLIENT PAUSE N seconds into the run and measure the recovery (0 to disable)")
+	flag.IntVar(&config.PauseDuration, "pause-duration", 1000, "Length of the --pause-at pause in milliseconds")
+	flag.StringVar(&config.PauseMode, "pause-mode", "write", "Clients paused by --pause-at: write or all")
+	flag.IntVar(&config.WatchdogSeconds, "watchdog", 0, "Report workers whose request hasn't completed within N seconds")
+	flag.BoolVar(&config.WatchdogRecycle, "watchdog-recycle", false, "Replace the client a stalled worker is stuck on with a new connection")
+	flag.StringVar(&config.CheckpointFile, "checkpoint", "", "Periodically save cumulative stats to this file so an interrupted run can be resumed")
+	flag.IntVar(&config.CheckpointInterval, "checkpoint-interval", 60, "Seconds between checkpoints")
+	flag.BoolVar(&config.Resume, "resume", false, "Continue the run saved in the --checkpoint file if it exists")
+	flag.IntVar(&config.RampDownSeconds, "ramp-down", 0, "Decrease the offered load to zero over the last N seconds of a timed run and drain in-flight requests")
+	flag.IntVar(&config.MaxInflight, "max-inflight", 0, "Closed-loop mode: cap the requests in flight across all threads, without rate limiting (SIGUSR1 doubles, SIGUSR2 halves the cap)")
+	flag.Float64Var(&config.TargetMbps, "target-mbps", 0, "Limit the payload bandwidth (sent + received) to this many megabits per second instead of limiting QPS")
+	flag.Float64Var(&config.PacingJitter, "pacing-jitter", 0, "Randomize each inter-request gap by up to ±P percent (0-100)")
+	flag.IntVar(&config.QPSBurst, "qps-burst", 1, "Requests (across all threads) that may be sent back to back when the workers fell behind the QPS target")
+	flag.StringVar(&config.Arrival, "arrival", "uniform", "Request arrival process at the QPS target: uniform (evenly spaced) or poisson (open-loop, exponential gaps)")
+	flag.BoolVar(&config.BusyPoll, "busy-poll", false, "Spin-wait instead of sleeping while pacing and lock every worker to an OS thread, for sub-100µs latencies")
+	flag.StringVar(&config.QPSRampMode, "qps-ramp-mode", "linear", "QPS ramp mode: linear or exponential")
+	flag.Float64Var(&config.QPSRampFactor, "qps-ramp-factor", 0, "Explicit multiplier for exponential QPS ramp (e.g., 2.0 to double QPS each interval)")
+	flag.StringVar(&config.QPSProfile, "qps-profile", "", "QPS waveform between start-qps and end-qps: sine")
+	flag.IntVar(&config.QPSPeriod, "qps-period", 0, "Period of the --qps-profile waveform in seconds")
+	flag.StringVar(&config.QPSSchedule, "qps-schedule", "", "Replay the QPS targets of a CSV file of time,qps rows (seconds since the start, interpolated linearly)")
+	flag.BoolVar(&config.UseTLS, "tls", false, "Use TLS connection")
+	flag.StringVar(&config.Password, "a", "", "Password to authenticate with")
+	flag.StringVar(&config.User, "user", "", "ACL user to authenticate with -a (default: the default user)")
+	flag.BoolVar(&config.IsCluster, "cluster", false, "Use cluster client")
+	flag.StringVar(&config.ConfigEndpoint, "config-endpoint", "", "Cluster configuration endpoint (host[:port]) of a managed service; enables cluster mode, node discovery and TLS")
+	flag.StringVar(&config.AuthProvider, "auth-provider", "", "Authenticate with tokens or rotating credentials: iam, command, file or env (re-authenticates on SIGHUP)")
+	flag.StringVar(&config.AuthUser, "auth-user", "", "User authenticated with --auth-provider tokens (default: the default user)")
+	flag.StringVar(&config.AuthCommand, "auth-command", "", "Shell command printing a token on stdout for --auth-provider command")
+	flag.StringVar(&config.AuthFile, "auth-file", "", "File holding \"<password>\" or \"<user> <password>\" for --auth-provider file, reloaded on SIGHUP")
+	flag.StringVar(&config.AuthEnv, "auth-env", "", "Environment variable holding \"<password>\" or \"<user> <password>\" for --auth-provider env")
+	flag.IntVar(&config.AuthTokenTTL, "auth-token-ttl", 900, "Lifetime in seconds of the tokens printed by --auth-command")
+	flag.StringVar(&config.IAMCacheName, "iam-cache-name", "", "ElastiCache replication group or MemoryDB cluster name for --auth-provider iam")
+	flag.StringVar(&config.IAMRegion, "iam-region", awsRegionFromEnv(), "AWS region for --auth-provider iam (default: $AWS_REGION)")
+	flag.BoolVar(&config.ReadFromReplica, "read-from-replica", false, "Read from replica nodes")
+	flag.IntVar(&config.ReplicaReadRatio, "replica-read-ratio", 0, "Percentage of reads (0-100) sent to replicas, the rest go to the primary")
+	flag.BoolVar(&config.ClientNoEvict, "client-no-evict", false, "Set CLIENT NO-EVICT on for benchmark connections")
+	flag.BoolVar(&config.ClientNoTouch, "client-no-touch", false, "Set CLIENT NO-TOUCH on for benchmark connections")
+	flag.StringVar(&config.PluginPath, "plugin", "", "Go plugin (.so) implementing the custom command for -t custom")
+	flag.StringVar(&config.PluginArgs, "plugin-args", "", "Arguments passed to the custom command plugin's Setup")
+	flag.StringVar(&config.WorkloadCommand, "workload-cmd", "", "Shell command of a subprocess generating custom commands as newline-delimited JSON")
+	flag.StringVar(&config.CommandLine, "command", "", "Full command line to benchmark with -t custom, e.g. \"HSET myhash:__rand_int__ field __data__\" (__rand_int__ needs -r)")
+	flag.StringVar(&config.CommandTemplate, "command-template", "", "Command template for -t custom, e.g. \"HSET user:{{rand:1:1000}} visits {{counter}}\"")
+	flag.StringVar(&config.CommandMixFile, "command-mix", "", "File listing weighted command templates for -t custom (one \"<weight> <template>\" per line)")
+	flag.Var(&config.LatencyBuckets, "latency-buckets", "Comma-separated latency SLO bucket bounds in ms, e.g. 1,5,10,50")
+	flag.Float64Var(&config.ApdexThreshold, "apdex-threshold", 0, "Apdex target threshold T in ms (enables Apdex score in results)")
+	flag.Var(&config.CurveQPS, "curve-qps", "Comma-separated offered QPS levels for throughput-latency curve mode, e.g. 1000,5000,10000")
+	flag.IntVar(&config.CurveStageSeconds, "curve-stage-duration", 10, "Duration of each curve stage in seconds")
+	flag.StringVar(&config.CurveCSV, "curve-csv", "", "Write the throughput-latency curve to this CSV file")
+	flag.Var(&config.SweepDataSizes, "sweep-datasize", "Comma-separated data sizes to sweep, e.g. 64,1024,16384")
+	flag.Var(&config.SweepPoolSizes, "sweep-pool", "Comma-separated client pool sizes to sweep, e.g. 1,4,16")
+	flag.Var(&config.SweepPipelines, "sweep-pipeline", "Comma-separated -pipeline depths to sweep, e.g. 1,8,32")
+	flag.IntVar(&config.SweepStageSeconds, "sweep-stage-duration", 10, "Duration of each sweep stage in seconds")
+	flag.StringVar(&config.LogLevel, "log-level", "info", "Level of internal logs: debug, info, warn or error")
+	flag.StringVar(&config.LogFormat, "log-format", "text", "Format of internal logs: text or json")
+	flag.StringVar(&config.LogFile, "log-file", "", "Append internal logs to this file instead of stderr")
+	flag.Int64Var(&config.Seed, "seed", 0, "Seed of the generated data and random choices (default: random, printed at start)")
+	flag.BoolVar(&config.Deterministic, "deterministic", false, "Derive the keys and values of -t set and get from --seed and the request number with the algorithm shared by all languages of the project")
+	flag.StringVar(&config.ManifestFile, "manifest", "", "Write a reproducibility manifest (effective config, seed, input file hashes, version) to this file")
+	flag.StringVar(&config.ConfigSnapshotFile, "config-snapshot", "", "Write the server's CONFIG GET * at run start to this JSON file")
+	flag.StringVar(&config.ConfigDiffFile, "config-diff", "", "Print the server config differences to a snapshot of an earlier run")
+	flag.StringVar(&config.SweepCSV, "sweep-csv", "", "Write the sweep comparison to this CSV file")
+	flag.BoolVar(&config.KneeSearch, "find-knee", false, "Sweep thread counts to find where more threads stop improving throughput")
+	flag.IntVar(&config.KneeMaxThreads, "knee-max-threads", 256, "Highest thread count tried by --find-knee")
+	flag.StringVar(&config.LatencyDumpFile, "latency-dump", "", "Write all recorded latencies to this gzip-compressed binary file")
+	flag.IntVar(&config.LatencyDumpSample, "latency-dump-sample", 0, "Keep a uniform reservoir sample of this many latencies for the dump (0 = all)")
+	flag.BoolVar(&config.CSV, "csv", false, "Print only the results, in the CSV format of redis-benchmark --csv")
+	flag.BoolVar(&config.MemtierStats, "memtier-stats", false, "Also print the results as the ALL STATS table of memtier_benchmark")
+	flag.StringVar(&config.OutputJSON, "output-json", "", "Write the final results (throughput, errors, latency percentiles, configuration) as JSON to this file")
+	flag.StringVar(&config.IntervalCSV, "interval-csv", "", "Append one CSV row per second (timestamp, RPS, errors, p50/p95/p99 latency) to this file")
+	flag.StringVar(&config.InfluxFile, "influx-file", "", "Append interval metrics in InfluxDB line protocol to this file")
+	flag.StringVar(&config.InfluxURL, "influx-url", "", "InfluxDB write URL for interval metrics, e.g. http://localhost:8086/api/v2/write?org=o&bucket=b&precision=ns")
+	flag.StringVar(&config.InfluxToken, "influx-token", os.Getenv("INFLUX_TOKEN"), "InfluxDB API token (default: $INFLUX_TOKEN)")
+	flag.StringVar(&config.EventsNATS, "events-nats", "", "Publish interval stats and lifecycle events to this NATS server, e.g. nats://localhost:4222")
+	flag.StringVar(&config.EventsKafkaREST, "events-kafka-rest", "", "Publish interval stats and lifecycle events through this Kafka REST proxy, e.g. http://localhost:8082")
+	flag.StringVar(&config.EventsSubject, "events-subject", "valkey-benchmark", "NATS subject or Kafka topic for published events")
+	flag.StringVar(&config.CloudWatchNamespace, "cloudwatch-namespace", "", "Publish interval metrics to this CloudWatch namespace")
+	flag.StringVar(&config.CloudWatchRegion, "cloudwatch-region", "", "AWS region for CloudWatch (default: $AWS_REGION)")
+	flag.StringVar(&config.CloudWatchDimensions, "cloudwatch-dimensions", "", "Additional CloudWatch dimensions as name=value,name=value")
+	flag.StringVar(&config.StatsDAddr, "statsd-addr", "", "Send interval metrics to this StatsD agent over UDP, e.g. localhost:8125")
+	flag.StringVar(&config.StatsDPrefix, "statsd-prefix", "valkey_benchmark", "Prefix of the StatsD metric names")
+	flag.BoolVar(&config.StatsDTags, "statsd-tags", false, "Tag StatsD metrics with run_id, command and target in the DogStatsD format")
+	flag.StringVar(&config.OTelEndpoint, "otel-endpoint", "", "Send interval metrics to this OTLP/HTTP collector, e.g. http://localhost:4318")
+	flag.StringVar(&config.OTelHeaders, "otel-headers", "", "Extra OTLP request headers as name=value,... (e.g. an API key)")
+	flag.Float64Var(&config.OTelTraceSample, "otel-trace-sample", 0, "Fraction of requests (0-1) exported as spans to the OTLP collector")
+	flag.StringVar(&config.StreamAddr, "stream-addr", "", "Serve live interval stats and events on this address (e.g. :8080) as server-sent events on /events and WebSocket frames on /ws")
+	flag.StringVar(&config.ScenarioFile, "scenario", "", "JSON scenario file with ordered phases and their expected outcomes")
+	flag.StringVar(&config.ScenarioVerdictFile, "scenario-verdict", "", "Write the per-phase pass/fail verdict as JSON to this file")
+	flag.StringVar(&config.Workflow, "workflow", "", "Comma-separated stages to chain in one run: prefill,benchmark,verify,cleanup")
+	flag.StringVar(&config.RunID, "run-id", "", "Run identifier used to tag connections (default: randomly generated)")
+	flag.StringVar(&config.KeyPrefix, "key-prefix", "key", "Prefix of all generated keys (<prefix>:<n>)")
+	flag.BoolVar(&config.NamespaceKeys, "namespace-keys", false, "Include the run ID in all generated keys (vkbench:<run-id>:<prefix>:<n>)")
+	flag.IntVar(&config.WaitForServer, "wait-for-server", 0, "Retry connecting and PING for up to N seconds until the server is available")
+	flag.StringVar(&config.StartAt, "start-at", "", "Begin the load at this RFC3339 time (e.g. 2024-05-01T12:00:00Z) after setup, to start several instances together")
+	flag.IntVar(&config.ProbeInterval, "rtt-probe", 0, "Send PING every N milliseconds on a dedicated connection and report its latency next to the workload's")
+	flag.Int64Var(&config.ReconnectEvery, "reconnect-every", 0, "Tear down and re-establish each connection every N requests and report the handshake overhead")
+	flag.IntVar(&config.RequestTimeout, "request-timeout", 0, "Client request timeout in milliseconds; slower requests fail and are counted as timeouts (default: the client library default)")
+	flag.Parse()
+
+	setFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+	if config.ConfigEndpoint != "" {
+		if setFlags["H"] || setFlags["p"] {
+			fmt.Fprintln(os.Stderr, "Error: --config-endpoint cannot be combined with -H or -p")
+			os.Exit(1)
+		}
+		if err := applyConfigEndpoint(&config, setFlags["tls"]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// A duration only ends early at -n requests if -n was given explicitly
+	if config.TestDuration > 0 && !setFlags["n"] {
+		config.TotalRequests = 0
+	}
+	if config.TestDuration < 0 || config.TotalRequests < 0 {
+		fmt.Fprintln(os.Stderr, "Error: test-duration and -n must not be negative")
+		os.Exit(1)
+	}
+	if config.TestDuration == 0 && config.TotalRequests == 0 {
+		fmt.Fprintln(os.Stderr, "Error: -n must be positive without --test-duration")
+		os.Exit(1)
+	}
+
+	config.UseSequential = config.SequentialKeyLen > 0
+	config.Command = strings.ToLower(config.Command)
+	if config.Command != "set" && config.Command != "get" && !isCustomWorkload(config.Command) {
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q for -t\n", config.Command)
+		os.Exit(1)
+	}
+	if config.CommandLine != "" {
+		// -command selects -t custom unless another workload was asked for explicitly
+		if !setFlags["t"] {
+			config.Command = "custom"
+		}
+		if config.Command != "custom" || config.CommandTemplate != "" {
+			fmt.Fprintln(os.Stderr, "Error: command requires -t custom and cannot be combined with command-template")
+			os.Exit(1)
+		}
+		config.CommandTemplate = config.CommandLine
+	}
+	if config.Workflow != "" {
+		stages, err := parseWorkflow(config.Workflow)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if workflowKeyspace(&config) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: --workflow requires a keyspace (-r or -sequential)")
+			os.Exit(1)
+		}
+		if config.ScenarioFile != "" || len(config.CurveQPS) > 0 {
+			fmt.Fprintln(os.Stderr, "Error: --workflow cannot be combined with --scenario or --curve-qps")
+			os.Exit(1)
+		}
+		config.WorkflowStages = stages
+	}
+	for _, bound := range config.LatencyBuckets {
+		if bound <= 0 {
+			fmt.Fprintln(os.Stderr, "Error: latency-buckets bounds must be positive")
+			os.Exit(1)
+		}
+	}
+
+	if config.TargetMbps < 0 {
+		fmt.Fprintln(os.Stderr, "Error: target-mbps must not be negative")
+		os.Exit(1)
+	}
+	if config.TargetMbps > 0 && (config.QPS > 0 || config.StartQPS > 0 || config.EndQPS > 0 || len(config.CurveQPS) > 0) {
+		fmt.Fprintln(os.Stderr, "Error: --target-mbps cannot be combined with QPS limits")
+		os.Exit(1)
+	}
+
+	if config.RequestTimeout < 0 {
+		fmt.Fprintln(os.Stderr, "Error: request-timeout must not be negative")
+		os.Exit(1)
+	}
+
+	if config.RequestDeadline < 0 {
+		fmt.Fprintln(os.Stderr, "Error: request-deadline must not be negative")
+		os.Exit(1)
+	}
+
+	if config.Pipeline < 1 {
+		fmt.Fprintln(os.Stderr, "Error: pipeline must be at least 1")
+		os.Exit(1)
+	}
+	if (config.Pipeline > 1 || len(config.SweepPipelines) > 0) &&
+		((config.Command != "set" && config.Command != "get") || config.RequestDeadline > 0) {
+		fmt.Fprintln(os.Stderr, "Error: pipeline and sweep-pipeline require -t set or get and cannot be combined with request-deadline")
+		os.Exit(1)
+	}
+
+	if config.WatchdogSeconds < 0 {
+		fmt.Fprintln(os.Stderr, "Error: watchdog must not be negative")
+		os.Exit(1)
+	}
+	if config.WatchdogRecycle && config.WatchdogSeconds == 0 {
+		fmt.Fprintln(os.Stderr, "Error: --watchdog-recycle requires --watchdog")
+		os.Exit(1)
+	}
+
+	if config.Resume && config.CheckpointFile == "" {
+		fmt.Fprintln(os.Stderr, "Error: --resume requires --checkpoint")
+		os.Exit(1)
+	}
+	if config.CheckpointFile != "" {
+		if config.CheckpointInterval <= 0 {
+			fmt.Fprintln(os.Stderr, "Error: checkpoint-interval must be positive")
+			os.Exit(1)
+		}
+		if len(config.CurveQPS) > 0 || config.ScenarioFile != "" || config.Workflow != "" {
+			fmt.Fprintln(os.Stderr, "Error: --checkpoint cannot be combined with --curve-qps, --scenario or --workflow")
+			os.Exit(1)
+		}
+	}
+
+	if config.RampDownSeconds < 0 {
+		fmt.Fprintln(os.Stderr, "Error: ramp-down must not be negative")
+		os.Exit(1)
+	}
+	if config.RampDownSeconds > 0 && (config.TestDuration <= 0 || config.RampDownSeconds > config.TestDuration) {
+		fmt.Fprintln(os.Stderr, "Error: ramp-down requires a test-duration at least as long")
+		os.Exit(1)
+	}
+
+	if config.MaxInflight < 0 {
+		fmt.Fprintln(os.Stderr, "Error: max-inflight must not be negative")
+		os.Exit(1)
+	}
+	if config.MaxInflight > 0 {
+		if config.QPS > 0 || config.StartQPS > 0 || config.EndQPS > 0 || len(config.CurveQPS) > 0 || config.TargetMbps > 0 {
+			fmt.Fprintln(os.Stderr, "Error: --max-inflight cannot be combined with rate limits")
+			os.Exit(1)
+		}
+		if config.MaxInflight > config.NumThreads*config.Pipeline {
+			fmt.Fprintf(os.Stderr, "Warning: max-inflight %d exceeds the %d requests the threads can keep in flight\n", config.MaxInflight, config.NumThreads*config.Pipeline)
+		}
+	}
+
+	if config.ProxyMode {
+		if config.IsCluster || config.ReadFromReplica || config.ReplicaReadRatio > 0 {
+			fmt.Fprintln(os.Stderr, "Error: --proxy-mode cannot be combined with --cluster or replica reads")
+			os.Exit(1)
+		}
+		if config.ClientNoEvict || config.ClientNoTouch || config.StallInterval > 0 || config.PauseAt > 0 ||
+			config.ConfigSnapshotFile != "" || config.ConfigDiffFile != "" || config.EvictionPressure {
+			fmt.Fprintln(os.Stderr, "Error: --proxy-mode cannot be combined with options issuing CLIENT, DEBUG, CONFIG or INFO commands")
+			os.Exit(1)
+		}
+	}
+
+	if config.StatsBatch < 1 || config.StatsFlush < 1 {
+		fmt.Fprintln(os.Stderr, "Error: stats-batch and stats-flush must be at least 1")
+		os.Exit(1)
+	}
+
+	if config.ProgressWindow < 1 {
+		fmt.Fprintln(os.Stderr, "Error: progress-window must be at least 1")
+		os.Exit(1)
+	}
+
+	if config.Processes < 1 {
+		fmt.Fprintln(os.Stderr, "Error: processes must be at least 1")
+		os.Exit(1)
+	}
+	if config.Processes > 1 && (sweeping(&config) || config.KneeSearch || len(config.CurveQPS) > 0 ||
+		config.ScenarioFile != "" || config.Workflow != "" || config.CheckpointFile != "") {
+		fmt.Fprintln(os.Stderr, "Error: --processes cannot be combined with a parameter sweep, --find-knee, --curve-qps, --scenario, --workflow or --checkpoint")
+		os.Exit(1)
+	}
+	// The children report histograms, not the samples a latency dump needs
+	if config.Processes > 1 && config.LatencyDumpFile != "" {
+		fmt.Fprintln(os.Stderr, "Error: --processes cannot be combined with --latency-dump")
+		os.Exit(1)
+	}
+	// A child without a share of -n and without a duration would never stop
+	if config.TestDuration == 0 && config.TotalRequests < int64(config.Processes) {
+		fmt.Fprintln(os.Stderr, "Error: --processes must not exceed -n without --test-duration")
+		os.Exit(1)
+	}
+
+	if config.ServerBackoff < 0 {
+		fmt.Fprintln(os.Stderr, "Error: server-backoff must be non-negative")
+		os.Exit(1)
+	}
+
+	if config.EvictionPressure && (config.Command != "set" || config.RandomKeyspace > 0 || config.UseSequential) {
+		fmt.Fprintln(os.Stderr, "Error: eviction-pressure requires -t set without -r or --sequential, so every write adds a key")
+		os.Exit(1)
+	}
+
+	if config.ExpiryTTL <= 0 || config.ExpiryWindow <= 0 || config.ExpiryWindow >= config.ExpiryTTL || config.ExpiryReads < 1 {
+		fmt.Fprintln(os.Stderr, "Error: expiry-window must be positive and below expiry-ttl, and expiry-reads at least 1")
+		os.Exit(1)
+	}
+
+	if config.ChurnKeys <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: churn-keys must be positive")
+		os.Exit(1)
+	}
+
+	if config.ScanSample < 0 || config.ScanCount <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: scan-sample must be non-negative and scan-count positive")
+		os.Exit(1)
+	}
+	if config.Deterministic {
+		if config.Command != "set" && config.Command != "get" {
+			fmt.Fprintln(os.Stderr, "Error: deterministic requires -t set or get")
+			os.Exit(1)
+		}
+		if config.DataTemplate != "" || config.ScanSample > 0 {
+			fmt.Fprintln(os.Stderr, "Error: deterministic cannot be combined with --data-template or --scan-sample")
+			os.Exit(1)
+		}
+	}
+
+	if config.ScanSample > 0 && (config.Command != "get" || config.RandomKeyspace > 0) {
+		fmt.Fprintln(os.Stderr, "Error: scan-sample requires -t get without -r")
+		os.Exit(1)
+	}
+
+	if config.Ratio != "" {
+		if _, err := parseRatio(config.Ratio); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if (config.Command != "set" && config.Command != "get") || config.RandomKeyspace <= 0 {
+			fmt.Fprintln(os.Stderr, "Error: ratio requires -t set or get with -r, so SETs and GETs share a keyspace")
+			os.Exit(1)
+		}
+		if config.ReplicaReadRatio > 0 {
+			fmt.Fprintln(os.Stderr, "Error: ratio cannot be combined with replica-read-ratio")
+			os.Exit(1)
+		}
+	}
+
+	if config.KeyspaceGrowth < 0 || config.KeyspaceMax < 0 {
+		fmt.Fprintln(os.Stderr, "Error: keyspace-growth and keyspace-max must be non-negative")
+		os.Exit(1)
+	}
+	if config.KeyspaceGrowth > 0 && (config.RandomKeyspace <= 0 || (config.Command != "set" && config.Command != "get")) {
+		fmt.Fprintln(os.Stderr, "Error: keyspace-growth requires -r with -t set or get")
+		os.Exit(1)
+	}
+	if config.KeyspaceMax > 0 && config.KeyspaceMax < config.RandomKeyspace {
+		fmt.Fprintln(os.Stderr, "Error: keyspace-max must not be smaller than -r")
+		os.Exit(1)
+	}
+
+	if config.ProbeInterval < 0 {
+		fmt.Fprintln(os.Stderr, "Error: rtt-probe must be non-negative")
+		os.Exit(1)
+	}
+
+	if config.ReconnectEvery < 0 {
+		fmt.Fprintln(os.Stderr, "Error: reconnect-every must be non-negative")
+		os.Exit(1)
+	}
+
+	if config.WaitForServer < 0 {
+		fmt.Fprintln(os.Stderr, "Error: wait-for-server must be non-negative")
+		os.Exit(1)
+	}
+
+	if config.StartAt != "" {
+		if _, err := time.Parse(time.RFC3339Nano, config.StartAt); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: start-at must be an RFC3339 time such as 2024-05-01T12:00:00Z")
+			os.Exit(1)
+		}
+	}
+
+	if config.StallInterval < 0 || config.StallDuration <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: stall-interval must be non-negative and stall-duration positive")
+		os.Exit(1)
+	}
+	config.StallMode = strings.ToLower(config.StallMode)
+	if config.StallMode != "sleep" && config.StallMode != "pause" {
+		fmt.Fprintln(os.Stderr, "Error: stall-mode must be sleep or pause")
+		os.Exit(1)
+	}
+	if config.StallInterval > 0 && config.StallDuration >= config.StallInterval*1000 {
+		fmt.Fprintln(os.Stderr, "Error: stall-duration must be shorter than stall-interval")
+		os.Exit(1)
+	}
+
+	if config.PauseAt < 0 || config.PauseDuration <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: pause-at must be non-negative and pause-duration positive")
+		os.Exit(1)
+	}
+	config.PauseMode = strings.ToLower(config.PauseMode)
+	if config.PauseMode != "write" && config.PauseMode != "all" {
+		fmt.Fprintln(os.Stderr, "Error: pause-mode must be write or all")
+		os.Exit(1)
+	}
+	if config.PauseAt > 0 && config.PauseAt < 2 {
+		fmt.Fprintln(os.Stderr, "Error: pause-at must be at least 2 seconds to measure the baseline throughput")
+		os.Exit(1)
+	}
+	if config.PauseAt > 0 && config.TestDuration > 0 && config.PauseAt >= config.TestDuration {
+		fmt.Fprintln(os.Stderr, "Error: pause-at must be within test-duration")
+		os.Exit(1)
+	}
+
+	if config.PacingJitter < 0 || config.PacingJitter > 100 {
+		fmt.Fprintln(os.Stderr, "Error: pacing-jitter must be between 0 and 100")
+		os.Exit(1)
+	}
+	switch config.QPSProfile {
+	case "":
+	case "sine":
+		if config.StartQPS <= 0 || config.EndQPS <= 0 || config.QPSPeriod <= 0 {
+			fmt.Fprintln(os.Stderr, "Error: --qps-profile sine requires --start-qps, --end-qps and --qps-period")
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, "Error: qps-profile must be sine")
+		os.Exit(1)
+	}
+	if config.QPSProfile != "" || config.QPSSchedule != "" {
+		if config.QPSProfile != "" && config.QPSSchedule != "" {
+			fmt.Fprintln(os.Stderr, "Error: --qps-profile cannot be combined with --qps-schedule")
+			os.Exit(1)
+		}
+		if config.QPS > 0 || config.QPSChangeInterval > 0 || config.TargetMbps > 0 || (config.QPSSchedule != "" && (config.StartQPS > 0 || config.EndQPS > 0)) {
+			fmt.Fprintln(os.Stderr, "Error: --qps-profile and --qps-schedule cannot be combined with other QPS limits or ramps")
+			os.Exit(1)
+		}
+		if len(config.CurveQPS) > 0 || config.ScenarioFile != "" || (config.QPSSchedule != "" && config.Processes > 1) {
+			fmt.Fprintln(os.Stderr, "Error: --qps-profile and --qps-schedule cannot be combined with --curve-qps or --scenario, nor --qps-schedule with --processes")
+			os.Exit(1)
+		}
+		if config.QPSSchedule != "" {
+			if _, err := LoadQPSSchedule(config.QPSSchedule); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	}
+	if config.QPSBurst < 1 {
+		fmt.Fprintln(os.Stderr, "Error: qps-burst must be at least 1")
+		os.Exit(1)
+	}
+	switch config.Arrival {
+	case "uniform":
+	case "poisson":
+		if config.QPS <= 0 && config.StartQPS <= 0 && config.EndQPS <= 0 && len(config.CurveQPS) == 0 && config.QPSSchedule == "" {
+			fmt.Fprintln(os.Stderr, "Error: -arrival poisson requires a QPS target")
+			os.Exit(1)
+		}
+		if config.PacingJitter > 0 {
+			fmt.Fprintln(os.Stderr, "Error: --pacing-jitter cannot be combined with -arrival poisson")
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, "Error: arrival must be uniform or poisson")
+		os.Exit(1)
+	}
+
+	if config.SearchLimit < 0 || config.SearchDocuments < 0 {
+		fmt.Fprintln(os.Stderr, "Error: ft-limit and ft-docs must be non-negative")
+		os.Exit(1)
+	}
+
+	if config.PageSize <= 0 || config.PageStride < 0 || config.CollectionSize <= 0 || config.Collections <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: page-size, collection-size and collections must be positive and page-stride non-negative")
+		os.Exit(1)
+	}
+
+	if config.VectorDim <= 0 || config.VectorK <= 0 || config.VectorDocuments < 0 {
+		fmt.Fprintln(os.Stderr, "Error: vector-dim and vector-k must be positive and vector-docs non-negative")
+		os.Exit(1)
+	}
+	switch strings.ToUpper(config.VectorMetric) {
+	case "L2", "IP", "COSINE":
+	default:
+		fmt.Fprintln(os.Stderr, "Error: vector-metric must be L2, IP or COSINE")
+		os.Exit(1)
+	}
+	switch strings.ToUpper(config.VectorAlgorithm) {
+	case "HNSW", "FLAT":
+	default:
+		fmt.Fprintln(os.Stderr, "Error: vector-algorithm must be HNSW or FLAT")
+		os.Exit(1)
+	}
+
+	if config.BloomCapacity <= 0 || config.BloomFilters <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: bf-capacity and bf-filters must be positive")
+		os.Exit(1)
+	}
+	if config.BloomErrorRate <= 0 || config.BloomErrorRate >= 1 {
+		fmt.Fprintln(os.Stderr, "Error: bf-error-rate must be between 0 and 1")
+		os.Exit(1)
+	}
+
+	if config.OTelTraceSample < 0 || config.OTelTraceSample > 1 {
+		fmt.Fprintln(os.Stderr, "Error: otel-trace-sample must be between 0 and 1")
+		os.Exit(1)
+	}
+	if config.OTelTraceSample > 0 && config.OTelEndpoint == "" {
+		fmt.Fprintln(os.Stderr, "Error: --otel-trace-sample requires --otel-endpoint")
+		os.Exit(1)
+	}
+
+	if config.ApdexThreshold < 0 {
+		fmt.Fprintln(os.Stderr, "Error: apdex-threshold must be positive")
+		os.Exit(1)
+	}
+
+	if len(config.CurveQPS) > 0 {
+		if config.CurveStageSeconds <= 0 {
+			fmt.Fprintln(os.Stderr, "Error: curve-stage-duration must be positive")
+			os.Exit(1)
+		}
+		for _, qps := range config.CurveQPS {
+			if qps <= 0 {
+				fmt.Fprintln(os.Stderr, "Error: curve-qps levels must be positive")
+				os.Exit(1)
+			}
+		}
+	}
+
+	if config.KneeSearch {
+		if config.KneeMaxThreads <= 0 || config.SweepStageSeconds <= 0 {
+			fmt.Fprintln(os.Stderr, "Error: knee-max-threads and sweep-stage-duration must be positive")
+			os.Exit(1)
+		}
+		if sweeping(&config) || len(config.CurveQPS) > 0 || config.ScenarioFile != "" || config.Workflow != "" || config.CheckpointFile != "" {
+			fmt.Fprintln(os.Stderr, "Error: --find-knee cannot be combined with a parameter sweep, --curve-qps, --scenario, --workflow or --checkpoint")
+			os.Exit(1)
+		}
+	}
+
+	if sweeping(&config) {
+		if config.SweepStageSeconds <= 0 {
+			fmt.Fprintln(os.Stderr, "Error: sweep-stage-duration must be positive")
+			os.Exit(1)
+		}
+		for _, v := range append(append(append([]int(nil), config.SweepDataSizes...), config.SweepPoolSizes...), config.SweepPipelines...) {
+			if v <= 0 {
+				fmt.Fprintln(os.Stderr, "Error: sweep-datasize, sweep-pool and sweep-pipeline values must be positive")
+				os.Exit(1)
+			}
+		}
+		if len(config.CurveQPS) > 0 || config.ScenarioFile != "" || config.Workflow != "" || config.CheckpointFile != "" {
+			fmt.Fprintln(os.Stderr, "Error: sweep mode cannot be combined with --curve-qps, --scenario, --workflow or --checkpoint")
+			os.Exit(1)
+		}
+	}
+
+	if config.ScenarioFile != "" && len(config.CurveQPS) > 0 {
+		fmt.Fprintln(os.Stderr, "Error: --scenario and --curve-qps cannot be combined")
+		os.Exit(1)
+	}
+	if config.ScenarioVerdictFile != "" && config.ScenarioFile == "" {
+		fmt.Fprintln(os.Stderr, "Error: --scenario-verdict requires --scenario")
+		os.Exit(1)
+	}
+	// The multi-stage modes report their stages themselves and never reach the
+	// results, latency dump and exporters of a single run
+	if (sweeping(&config) || config.KneeSearch || len(config.CurveQPS) > 0 || config.ScenarioFile != "" || config.Workflow != "") &&
+		(config.OutputJSON != "" || config.LatencyDumpFile != "" || exporting(&config)) {
+		fmt.Fprintln(os.Stderr, "Error: --output-json, --latency-dump and metrics exporters cannot be combined with a parameter sweep, --find-knee, --curve-qps, --scenario or --workflow")
+		os.Exit(1)
+	}
+
+	if config.LatencyDumpSample < 0 {
+		fmt.Fprintln(os.Stderr, "Error: latency-dump-sample must not be negative")
+		os.Exit(1)
+	}
+
+	customSources := 0
+	for _, source := range []string{config.PluginPath, config.WorkloadCommand, config.CommandTemplate, config.CommandMixFile} {
+		if source != "" {
+			customSources++
+		}
+	}
+	if customSources > 1 {
+		fmt.Fprintln(os.Stderr, "Error: plugin, workload-cmd, command-template and command-mix are mutually exclusive")
+		os.Exit(1)
+	}
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+	seedRandom(config.Seed)
+
+	if config.RunID == "" {
+		config.RunID = generateRunID()
+	} else if strings.ContainsAny(config.RunID, " \t\n") {
+		fmt.Fprintln(os.Stderr, "Error: run-id must not contain whitespace")
+		os.Exit(1)
+	}
+
+	closeLog, err := setupLogging(&config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer closeLog()
+	checkBusyPoll(&config)
+
+	if config.CSV {
+		if err := startRedisCSV(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if config.DataTemplate != "" {
+		if dataGenerator, err = loadDataTemplate(&config); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if config.User != "" && config.Password == "" {
+		fmt.Fprintln(os.Stderr, "Error: --user requires -a")
+		os.Exit(1)
+	}
+	if config.Password != "" && config.AuthProvider != "" {
+		fmt.Fprintln(os.Stderr, "Error: -a cannot be combined with --auth-provider")
+		os.Exit(1)
+	}
+	if config.Password != "" {
+		startPasswordAuth(&config)
+	}
+	if config.AuthProvider != "" {
+		if err := startTokenAuth(&config); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if config.ReplicaReadRatio < 0 || config.ReplicaReadRatio > 100 {
+		fmt.Fprintln(os.Stderr, "Error: replica-read-ratio must be between 0 and 100")
+		os.Exit(1)
+	}
+	if config.ReplicaReadRatio > 0 && config.ReadFromReplica {
+		fmt.Fprintln(os.Stderr, "Error: replica-read-ratio cannot be combined with read-from-replica")
+		os.Exit(1)
+	}
+
+	if config.WarmupOps < 0 {
+		fmt.Fprintln(os.Stderr, "Error: warmup-ops must be non-negative")
+		os.Exit(1)
+	}
+	if config.Warmup < 0 {
+		fmt.Fprintln(os.Stderr, "Error: warmup must be non-negative")
+		os.Exit(1)
+	}
+	if config.KeyPrefix == "" {
+		fmt.Fprintln(os.Stderr, "Error: key-prefix must not be empty")
+		os.Exit(1)
+	}
+	if config.KeyspaceOffset < 0 {
+		fmt.Fprintln(os.Stderr, "Error: keyspace-offset must not be negative")
+		os.Exit(1)
+	}
+	if config.Populate && workflowKeyspace(&config) <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --populate requires -r or -sequential")
+		os.Exit(1)
+	}
+	if config.Populate && config.Workflow != "" {
+		fmt.Fprintln(os.Stderr, "Error: --populate cannot be combined with --workflow, use its prefill stage")
+		os.Exit(1)
+	}
+
+	if config.NodePool != "" {
+		if !config.IsCluster || (config.Command != "set" && config.Command != "get") {
+			fmt.Fprintln(os.Stderr, "Error: node-pool requires --cluster with -t set or get")
+			os.Exit(1)
+		}
+		if config.ReadFromReplica || config.ReplicaReadRatio > 0 {
+			fmt.Fprintln(os.Stderr, "Error: node-pool connects to primaries and cannot be combined with replica reads")
+			os.Exit(1)
+		}
+		if _, _, err := parseNodePoolSpec(config.NodePool, config.PoolSize); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Handle Ctrl+C
 	// Handle Ctrl+C gracefully
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt)
@@ -595,8 +2710,21 @@ func main() {
 		cancel()
 	}()
 
+	if config.Processes > 1 && config.ProcessResult == "" {
+		if err := runProcesses(ctx, &config); err != nil {
+			slog.Error("benchmark failed", "error", err)
+			closeLog()
+			os.Exit(1)
+		}
+		return
+	}
+	if config.ProcessResult != "" {
+		scaleForProcess(&config)
+	}
+
 	if err := RunBenchmark(ctx, &config); err != nil {
-		fmt.Printf("Benchmark failed: %v\n", err)
+		slog.Error("benchmark failed", "error", err)
+		closeLog()
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// vectorFiles caches the encoded vectors of --vector-file, which is read by
+// both the index load and the query templates
+var vectorFiles struct {
+	sync.Mutex
+	blobs map[string][]string
+}
+
+// encodeVector encodes a vector as the little-endian FLOAT32 blob expected by vector fields
+func encodeVector(values []float32) string {
+	blob := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(blob[4*i:], math.Float32bits(v))
+	}
+	return string(blob)
+}
+
+// loadVectorFile reads one vector per line, with components separated by
+// commas or whitespace. Empty lines and lines starting with # are skipped.
+func loadVectorFile(path string, dim int) ([]string, error) {
+	vectorFiles.Lock()
+	defer vectorFiles.Unlock()
+	if blobs, ok := vectorFiles.blobs[path]; ok {
+		return blobs, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vector file: %v", err)
+	}
+	defer file.Close()
+
+	var blobs []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.FieldsFunc(text, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		if len(fields) != dim {
+			return nil, fmt.Errorf("vector file %s line %d has %d components, expected --vector-dim %d", path, line, len(fields), dim)
+		}
+		values := make([]float32, dim)
+		for i, field := range fields {
+			v, err := strconv.ParseFloat(field, 32)
+			if err != nil {
+				return nil, fmt.Errorf("vector file %s line %d: invalid component %q", path, line, field)
+			}
+			values[i] = float32(v)
+		}
+		blobs = append(blobs, encodeVector(values))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read vector file: %v", err)
+	}
+	if len(blobs) == 0 {
+		return nil, fmt.Errorf("vector file %s contains no vectors", path)
+	}
+
+	if vectorFiles.blobs == nil {
+		vectorFiles.blobs = make(map[string][]string)
+	}
+	vectorFiles.blobs[path] = blobs
+	return blobs, nil
+}
+
+// vectorSegment compiles {{vector}} and {{vector:next}}. Without --vector-file
+// both render a random vector with components in [-1, 1); with a file,
+// {{vector}} picks one of its vectors at random and {{vector:next}} walks
+// through them in order, shared by all workers.
+func vectorSegment(arg string, config *Config) (templateSegment, error) {
+	if arg != "" && arg != "next" {
+		return nil, fmt.Errorf("vector expects {{vector}} or {{vector:next}}, got {{vector:%s}}", arg)
+	}
+	dim := config.VectorDim
+
+	if config.VectorFile == "" {
+		return func(ctx *templateContext, sb *strings.Builder) {
+			values := make([]float32, dim)
+			for i := range values {
+				values[i] = ctx.rng.Float32()*2 - 1
+			}
+			sb.WriteString(encodeVector(values))
+		}, nil
+	}
+
+	blobs, err := loadVectorFile(config.VectorFile, dim)
+	if err != nil {
+		return nil, err
+	}
+	if arg == "next" {
+		next := new(int64)
+		return func(ctx *templateContext, sb *strings.Builder) {
+			index := atomic.AddInt64(next, 1) - 1
+			sb.WriteString(blobs[index%int64(len(blobs))])
+		}, nil
+	}
+	return func(ctx *templateContext, sb *strings.Builder) {
+		sb.WriteString(blobs[ctx.rng.Intn(len(blobs))])
+	}, nil
+}
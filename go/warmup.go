@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// warmUp sends --warmup-ops PINGs (to every node in cluster mode) and SETs on
+// every pooled client before the measured phase, so first-use costs such as
+// TLS handshakes, route discovery and cold code paths don't land in the first
+// seconds of the recorded latencies. The warm-up keys are deleted afterwards.
+// Per-node connections only get PINGs, since a key may belong to another node.
+func (b *Benchmark) warmUp(ctx context.Context) error {
+	var clients []interface{}
+	b.poolMu.RLock()
+	clients = append(clients, b.clientPool...)
+	clients = append(clients, b.replicaPool...)
+	b.poolMu.RUnlock()
+	keyed := len(clients)
+	if b.nodePools != nil {
+		for _, pool := range b.nodePools.pools {
+			clients = append(clients, pool...)
+		}
+	}
+
+	start := time.Now()
+	key := keyPrefix(b.config) + ":warmup"
+	data := generateRandomData(b.config.DataSize)
+	errs := make(chan error, len(clients))
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		wg.Add(1)
+		go func(i int, client interface{}) {
+			defer wg.Done()
+			clientKey := fmt.Sprintf("%s:%d", key, i)
+			for op := 0; op < b.config.WarmupOps && ctx.Err() == nil; op++ {
+				if _, err := executeOnAllNodes(client, []string{"PING"}); err != nil {
+					errs <- fmt.Errorf("warm-up PING failed: %v", err)
+					return
+				}
+				if i >= keyed {
+					continue
+				}
+				if _, err := executeCommand(client, []string{"SET", clientKey, data}); err != nil {
+					errs <- fmt.Errorf("warm-up SET failed: %v", err)
+					return
+				}
+			}
+			if i >= keyed {
+				return
+			}
+			if _, err := executeCommand(client, []string{"DEL", clientKey}); err != nil {
+				errs <- fmt.Errorf("warm-up DEL failed: %v", err)
+			}
+		}(i, client)
+	}
+	wg.Wait()
+	close(errs)
+	if err := <-errs; err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	fmt.Printf("Warmed up %d clients with %d PING and SET operations each in %.0f ms\n",
+		len(clients), b.config.WarmupOps, float64(time.Since(start).Microseconds())/1000)
+	return nil
+}
+
+// warmUpWorkload runs the workload for --warmup seconds before the measured
+// phase and discards its statistics, so connection establishment, topology
+// discovery and cold server caches are excluded from the results. Stalls,
+// pauses, QPS ramps and the ramp-down only apply to the measured phase, so
+// a ramp is warmed up at its starting rate.
+func (b *Benchmark) warmUpWorkload(ctx context.Context) error {
+	config := *b.config
+	config.TestDuration = b.config.Warmup
+	config.TotalRequests = 0
+	config.StallInterval = 0
+	config.PauseAt = 0
+	config.QPSChangeInterval = 0
+	config.RampDownSeconds = 0
+	config.EvictionPressure = false
+
+	fmt.Printf("Warming up for %d seconds...\n", config.Warmup)
+	stats := b.runStage(ctx, &config)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	fmt.Printf("\nWarm-up: %d requests, %d errors in %.1f seconds (discarded)\n\n",
+		stats.requestsCompleted, stats.errors, stats.elapsed())
+
+	// Start the deterministic sequence over so the measured phase is reproducible
+	if b.workload != nil {
+		b.workload = NewSeededWorkload(b.config)
+	}
+	return nil
+}
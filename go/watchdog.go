@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// watchdogSlot tracks the request a worker is currently executing
+type watchdogSlot struct {
+	mu       sync.Mutex
+	start    time.Time // Zero while the worker is not executing a request
+	replica  bool
+	client   int
+	reported bool // Whether the current request was already reported
+}
+
+// Watchdog detects workers whose request hasn't completed within a deadline,
+// so hung connections don't silently reduce the offered load
+type Watchdog struct {
+	benchmark *Benchmark
+	deadline  time.Duration
+	recycle   bool
+	slots     []watchdogSlot
+	stalls    int64
+}
+
+// newWatchdog creates a watchdog for the workers of a phase, or returns nil if
+// the watchdog is disabled
+func newWatchdog(b *Benchmark, config *Config) *Watchdog {
+	if config.WatchdogSeconds <= 0 {
+		return nil
+	}
+	return &Watchdog{
+		benchmark: b,
+		deadline:  time.Duration(config.WatchdogSeconds) * time.Second,
+		recycle:   config.WatchdogRecycle,
+		slots:     make([]watchdogSlot, config.NumThreads),
+	}
+}
+
+// Begin records that a worker started a request on a pool slot
+func (w *Watchdog) Begin(threadID int, replica bool, client int) {
+	if w == nil {
+		return
+	}
+	slot := &w.slots[threadID]
+	slot.mu.Lock()
+	slot.start = time.Now()
+	slot.replica = replica
+	slot.client = client
+	slot.reported = false
+	slot.mu.Unlock()
+}
+
+// End records that a worker completed its request
+func (w *Watchdog) End(threadID int) {
+	if w == nil {
+		return
+	}
+	slot := &w.slots[threadID]
+	slot.mu.Lock()
+	if slot.reported {
+		fmt.Printf("\nWatchdog: thread %d recovered after %s\n", threadID, time.Since(slot.start).Round(time.Millisecond))
+	}
+	slot.start = time.Time{}
+	slot.mu.Unlock()
+}
+
+// Run checks the workers until the context is done
+func (w *Watchdog) Run(ctx context.Context) {
+	interval := w.deadline / 4
+	if interval > time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.check(now)
+		}
+	}
+}
+
+// check reports every request that exceeded the deadline once
+func (w *Watchdog) check(now time.Time) {
+	for threadID := range w.slots {
+		slot := &w.slots[threadID]
+		slot.mu.Lock()
+		stalled := !slot.start.IsZero() && !slot.reported && now.Sub(slot.start) > w.deadline
+		if stalled {
+			slot.reported = true
+		}
+		started, replica, client := slot.start, slot.replica, slot.client
+		slot.mu.Unlock()
+		if !stalled {
+			continue
+		}
+
+		atomic.AddInt64(&w.stalls, 1)
+		tag := "w"
+		if replica {
+			tag = "r"
+		}
+		name := connectionName(w.benchmark.config, tag, client)
+		slog.Warn("watchdog: request stalled", "thread", threadID, "client", name,
+			"waiting", now.Sub(started).Round(time.Millisecond))
+		if w.recycle {
+			if err := w.benchmark.recycleClient(replica, client); err != nil {
+				slog.Error("watchdog: failed to recycle client", "client", name, "error", err)
+			} else {
+				slog.Info("watchdog: recycled client", "client", name)
+			}
+		}
+	}
+}
+
+// PrintSummary prints the number of stalled requests if there were any
+func (w *Watchdog) PrintSummary() {
+	if stalls := atomic.LoadInt64(&w.stalls); stalls > 0 {
+		fmt.Printf("\nWatchdog: %d requests exceeded the %s deadline\n", stalls, w.deadline)
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+
+	"github.com/valkey-io/valkey-glide/go/api"
+)
+
+// Workflow stages in the order they can be chained
+const (
+	stagePrefill   = "prefill"
+	stageBenchmark = "benchmark"
+	stageVerify    = "verify"
+	stageCleanup   = "cleanup"
+)
+
+var workflowStages = []string{stagePrefill, stageBenchmark, stageVerify, stageCleanup}
+
+// parseWorkflow validates a comma-separated list of workflow stages
+func parseWorkflow(value string) ([]string, error) {
+	var stages []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ",") {
+		stage := strings.ToLower(strings.TrimSpace(part))
+		valid := false
+		for _, name := range workflowStages {
+			valid = valid || name == stage
+		}
+		if !valid {
+			return nil, fmt.Errorf("unknown workflow stage %q, expected %s", part, strings.Join(workflowStages, ", "))
+		}
+		if seen[stage] {
+			return nil, fmt.Errorf("workflow stage %q is listed twice", stage)
+		}
+		seen[stage] = true
+		stages = append(stages, stage)
+	}
+	return stages, nil
+}
+
+// workflowKeyspace returns the number of keys the workflow loads, verifies and removes
+func workflowKeyspace(config *Config) int64 {
+	if config.RandomKeyspace > 0 {
+		return config.RandomKeyspace
+	}
+	return config.SequentialKeyLen
+}
+
+// keyStageCommand visits every key of the keyspace exactly once across all
+// workers and applies one operation to it
+type keyStageCommand struct {
+	next    *int64 // Shared index of the next key
+	total   int64
+	offset  int64 // Number of the first key
+	prefix  string
+	data    string
+	operate func(client interface{}, key, data string) error
+	key     string
+}
+
+// newKeyStageFactory returns a factory for commands sharing one key cursor
+func newKeyStageFactory(config *Config, operate func(client interface{}, key, data string) error) CustomCommandFactory {
+	next := new(int64)
+	prefix := keyPrefix(config)
+	data := generateRandomData(config.DataSize)
+	total := workflowKeyspace(config)
+	return func() CustomCommand {
+		return &keyStageCommand{next: next, total: total, offset: config.KeyspaceOffset, prefix: prefix, data: data, operate: operate}
+	}
+}
+
+func (c *keyStageCommand) Setup(client interface{}, workerID int, args string) error {
+	return nil
+}
+
+// Prepare claims the next key, or reports that the keyspace is exhausted
+func (c *keyStageCommand) Prepare() error {
+	index := atomic.AddInt64(c.next, 1) - 1
+	if index >= c.total {
+		return errCustomCommandDone
+	}
+	c.key = fmt.Sprintf("%s:%d", c.prefix, c.offset+index)
+	return nil
+}
+
+// Key returns the claimed key
+func (c *keyStageCommand) Key() string {
+	return c.key
+}
+
+func (c *keyStageCommand) Execute(client interface{}) error {
+	return c.operate(client, c.key, c.data)
+}
+
+func (c *keyStageCommand) Teardown(client interface{}) error {
+	return nil
+}
+
+// prefillKey writes the payload to a key
+func prefillKey(client interface{}, key, data string) error {
+	var err error
+	if c, ok := client.(*api.GlideClient); ok {
+		_, err = c.Set(key, data)
+	} else if c, ok := client.(*api.GlideClusterClient); ok {
+		_, err = c.Set(key, data)
+	}
+	return err
+}
+
+// verifyKey checks that a key exists and holds a value of the configured size.
+// The content is not compared because the benchmark may overwrite it.
+func verifyKey(client interface{}, key, data string) error {
+	var result api.Result[string]
+	var err error
+	if c, ok := client.(*api.GlideClient); ok {
+		result, err = c.Get(key)
+	} else if c, ok := client.(*api.GlideClusterClient); ok {
+		result, err = c.Get(key)
+	}
+	if err != nil {
+		return err
+	}
+	if result.IsNil() {
+		return fmt.Errorf("verification failed: key %s is missing", key)
+	}
+	if len(result.Value()) != len(data) {
+		return fmt.Errorf("verification failed: key %s holds %d bytes, expected %d", key, len(result.Value()), len(data))
+	}
+	return nil
+}
+
+// cleanupKey deletes a key
+func cleanupKey(client interface{}, key, data string) error {
+	var err error
+	if c, ok := client.(*api.GlideClient); ok {
+		_, err = c.Del([]string{key})
+	} else if c, ok := client.(*api.GlideClusterClient); ok {
+		_, err = c.Del([]string{key})
+	}
+	return err
+}
+
+// runKeyStage runs one operation over the whole keyspace as fast as possible
+func (b *Benchmark) runKeyStage(ctx context.Context, operate func(client interface{}, key, data string) error) *BenchmarkStats {
+	return b.runCommandStage(ctx, workflowKeyspace(b.config), func(config *Config) CustomCommandFactory {
+		return newKeyStageFactory(config, operate)
+	})
+}
+
+// runCommandStage runs the given number of requests of a custom command
+// unthrottled, e.g. to load data before the measured workload
+func (b *Benchmark) runCommandStage(ctx context.Context, requests int64, newFactory func(config *Config) CustomCommandFactory) *BenchmarkStats {
+	config := *b.config
+	config.Command = "custom"
+	config.TestDuration = 0
+	config.TotalRequests = requests
+	config.QPS = 0
+	config.StartQPS = 0
+	config.EndQPS = 0
+	config.QPSChangeInterval = 0
+	config.TargetMbps = 0
+	config.StallInterval = 0
+	config.PauseAt = 0
+
+	factory := b.newCustomCommand
+	b.newCustomCommand = newFactory(&config)
+	defer func() { b.newCustomCommand = factory }()
+
+	return b.runStage(ctx, &config)
+}
+
+// populate loads every key of the keyspace with a -d byte value before the
+// measured phase of --populate, so GET benchmarks measure hits rather than
+// misses. It runs unthrottled and is not part of the results.
+func (b *Benchmark) populate(ctx context.Context) error {
+	keys := workflowKeyspace(b.config)
+	fmt.Printf("Populating %d keys with %d byte values...\n", keys, b.config.DataSize)
+	stats := b.runKeyStage(ctx, prefillKey)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	summary := stats.Summary()
+	fmt.Printf("\nPopulated %d keys in %.1f seconds (%.0f keys/s)\n\n", summary.Requests, summary.Duration, summary.RPS)
+	if summary.Errors > 0 {
+		slog.Warn("some keys could not be populated", "errors", summary.Errors)
+	}
+	return nil
+}
+
+// workflowResult holds the outcome of one workflow stage
+type workflowResult struct {
+	stage   string
+	summary StatsSummary
+}
+
+// RunWorkflow chains the given stages in one invocation. Prefill, verify and
+// cleanup each visit every key of the keyspace once; the benchmark stage runs
+// the configured workload. Each stage is reported with its own statistics.
+func (b *Benchmark) RunWorkflow(ctx context.Context, stages []string) error {
+	var results []workflowResult
+	for i, stage := range stages {
+		if ctx.Err() != nil {
+			break
+		}
+		fmt.Printf("\nWorkflow stage %d/%d: %s\n", i+1, len(stages), stage)
+
+		var stats *BenchmarkStats
+		switch stage {
+		case stagePrefill:
+			stats = b.runKeyStage(ctx, prefillKey)
+		case stageBenchmark:
+			stats = b.runStage(ctx, b.config)
+		case stageVerify:
+			stats = b.runKeyStage(ctx, verifyKey)
+		case stageCleanup:
+			stats = b.runKeyStage(ctx, cleanupKey)
+		}
+		stats.PrintFinalStats()
+		results = append(results, workflowResult{stage: stage, summary: stats.Summary()})
+	}
+
+	printWorkflow(results)
+	for _, result := range results {
+		if result.stage == stageVerify && result.summary.Errors > 0 {
+			return fmt.Errorf("verification found %d bad keys", result.summary.Errors)
+		}
+	}
+	return nil
+}
+
+// printWorkflow prints one line per workflow stage
+func printWorkflow(results []workflowResult) {
+	fmt.Printf("\n\nWorkflow Summary:\n")
+	fmt.Printf("=================\n")
+	fmt.Printf("%-10s %12s %10s %12s %12s\n", "Stage", "Requests", "Errors", "Seconds", "RPS")
+	for _, result := range results {
+		fmt.Printf("%-10s %12d %10d %12.2f %12.2f\n",
+			result.stage, result.summary.Requests, result.summary.Errors,
+			result.summary.Duration, result.summary.RPS)
+	}
+}